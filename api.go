@@ -1,35 +1,65 @@
 package api2go
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
+	"mime"
 	"net/http"
 	"net/url"
+	"path"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/manyminds/api2go/httputil"
+	"github.com/manyminds/api2go/httputil/header"
 	"github.com/manyminds/api2go/jsonapi"
+	"github.com/manyminds/api2go/routing"
 )
 
 const (
 	codeInvalidQueryFields   = "API2GO_INVALID_FIELD_QUERY_PARAM"
+	codeUnknownQueryParam    = "UNKNOWN_QUERY_PARAM"
 	defaultContentTypeHeader = "application/vnd.api+json; charset=utf-8"
-	api_info                 = "API:INFO"
-	api_relation             = "API:RELATION"
-	api_linked               = "API:LINKED"
-	api_prefix               = "API:PREFIX"
-	api_api                  = "API"
-	API_ERROR                = "API:ERROR"
+	headerResourceExists     = "X-Resource-Exists"
+	headerTotalCount         = "X-Total-Count"
+	// API_ERROR is kept as untyped string for compatibility, it is not used as context key by api2go
+	API_ERROR = "API:ERROR"
+)
+
+// contextKey is the type of all context keys of api2go, so that they can not
+// collide with the keys of other packages, even if these use the same strings
+type contextKey string
+
+const (
+	api_info        contextKey = "API:INFO"
+	api_relation    contextKey = "API:RELATION"
+	api_linked      contextKey = "API:LINKED"
+	api_prefix      contextKey = "API:PREFIX"
+	api_path_params contextKey = "API:PATH_PARAMS"
+	api_schema      contextKey = "API:SCHEMA"
+	api_api         contextKey = "API"
 )
 
 var queryFieldsRegex = regexp.MustCompile(`^fields\[(\w+)\]$`)
 
+// knownQueryParams are the query parameter families defined by jsonapi
+var knownQueryParams = map[string]bool{
+	"filter":  true,
+	"sort":    true,
+	"page":    true,
+	"include": true,
+	"fields":  true,
+}
+
 type response struct {
 	Meta   map[string]interface{}
 	Data   interface{}
@@ -53,6 +83,12 @@ type Information struct {
 	resolver URLResolver
 }
 
+// NewInformation returns the Information used to generate
+// urls for the given prefix and resolver
+func NewInformation(prefix string, resolver URLResolver) Information {
+	return Information{prefix: prefix, resolver: resolver}
+}
+
 func (i Information) GetBaseURL() string {
 	return i.resolver.GetBaseURL()
 }
@@ -61,39 +97,113 @@ func (i Information) GetPrefix() string {
 	return i.prefix
 }
 
+// paginationAliases maps the alternative page parameters some clients use to the jsonapi names
+var paginationAliases = map[string]string{
+	"page[page]":     "page[number]",
+	"page[per_page]": "page[size]",
+}
+
 type PaginationQueryParams struct {
 	number, size, offset, limit string
+	// numberAlias and sizeAlias are true if page[page] and page[per_page] have been used
+	numberAlias, sizeAlias bool
 }
 
+// NewPaginationQueryParams reads the pagination query parameters of the request.
+// page[page] and page[per_page] are accepted as aliases for page[number] and page[size]
+// if these are not set.
 func NewPaginationQueryParams(r *http.Request) PaginationQueryParams {
 	var result PaginationQueryParams
 
 	queryParams := r.URL.Query()
 	result.number = queryParams.Get("page[number]")
+	if result.number == "" {
+		result.number = queryParams.Get("page[page]")
+		result.numberAlias = result.number != ""
+	}
 	result.size = queryParams.Get("page[size]")
+	if result.size == "" {
+		result.size = queryParams.Get("page[per_page]")
+		result.sizeAlias = result.size != ""
+	}
 	result.offset = queryParams.Get("page[offset]")
 	result.limit = queryParams.Get("page[limit]")
 
 	return result
 }
 
-func (p PaginationQueryParams) IsValid() bool {
-	if p.number == "" && p.size == "" && p.offset == "" && p.limit == "" {
-		return false
+// numberParam returns the name of the query parameter used for the page number
+func (p PaginationQueryParams) numberParam() string {
+	if p.numberAlias {
+		return "page[page]"
 	}
 
-	if p.number != "" && p.size != "" && p.offset == "" && p.limit == "" {
-		return true
+	return "page[number]"
+}
+
+// sizeParam returns the name of the query parameter used for the page size
+func (p PaginationQueryParams) sizeParam() string {
+	if p.sizeAlias {
+		return "page[per_page]"
 	}
 
-	if p.number == "" && p.size == "" && p.offset != "" && p.limit != "" {
-		return true
+	return "page[size]"
+}
+
+func (p PaginationQueryParams) IsValid() bool {
+	valid, _ := p.IsValidWithError()
+	return valid
+}
+
+// IsValidWithError returns true if either page[number] and page[size], page[page] and
+// page[per_page] or page[offset] and page[limit] are set. If the pagination params are set but can not be used,
+// an error explaining why is returned. No pagination params at all is not an error.
+func (p PaginationQueryParams) IsValidWithError() (bool, error) {
+	numberSize := p.number != "" || p.size != ""
+	offsetLimit := p.offset != "" || p.limit != ""
+
+	// the names of the page number and size pair, page[page] and page[per_page] if any alias is used
+	numberParam, sizeParam := "page[number]", "page[size]"
+	if p.numberAlias || p.sizeAlias {
+		numberParam, sizeParam = "page[page]", "page[per_page]"
+	}
+
+	switch {
+	case !numberSize && !offsetLimit:
+		return false, nil
+	case numberSize && offsetLimit:
+		return false, fmt.Errorf("%s and %s can not be combined with page[offset] and page[limit]", numberParam, sizeParam)
+	case p.number != "" && p.size == "":
+		return false, fmt.Errorf("%s requires %s", numberParam, sizeParam)
+	case p.size != "" && p.number == "":
+		return false, fmt.Errorf("%s requires %s", sizeParam, numberParam)
+	case p.numberAlias != p.sizeAlias:
+		return false, fmt.Errorf("%s can not be combined with %s", p.numberParam(), p.sizeParam())
+	case p.offset != "" && p.limit == "":
+		return false, errors.New("page[offset] requires page[limit]")
+	case p.limit != "" && p.offset == "":
+		return false, errors.New("page[limit] requires page[offset]")
+	}
+
+	return true, nil
+}
+
+// errNegativeCount is returned for negative total counts of paginated collections
+var errNegativeCount = errors.New("the total count of a paginated collection must not be negative")
+
+// GetLinks returns the pagination links for a collection with `count` entries, like
+// the total count of PaginatedFindAll. A negative count is an error.
+func (p PaginationQueryParams) GetLinks(r *http.Request, count int64, info Information) (map[string]string, error) {
+	if count < 0 {
+		return nil, errNegativeCount
 	}
 
-	return false
+	return p.GetLinks64(r, uint64(count), info)
 }
 
-func (p PaginationQueryParams) GetLinks(r *http.Request, count uint, info Information) (result map[string]string, err error) {
+// GetLinks64 returns the pagination links (current, first, prev, next and last) for a collection
+// with `count` entries. The current link is the url of the requested page with normalized page parameters.
+func (p PaginationQueryParams) GetLinks64(r *http.Request, count uint64, info Information) (result map[string]string, err error) {
 	result = make(map[string]string)
 
 	params := r.URL.Query()
@@ -102,44 +212,49 @@ func (p PaginationQueryParams) GetLinks(r *http.Request, count uint, info Inform
 	if baseURL != "" {
 		prefix = baseURL
 	}
-	requestURL := fmt.Sprintf("%s%s", prefix, r.URL.Path)
+	// a path like //evil.com/v1/posts would result in a protocol-relative url to another host
+	requestURL := fmt.Sprintf("%s/%s", prefix, strings.TrimLeft(r.URL.Path, "/\\"))
 
 	if p.number != "" {
 		// we have number & size params
-		var number uint64
+		var number, size uint64
 		number, err = strconv.ParseUint(p.number, 10, 64)
 		if err != nil {
 			return
 		}
+		size, err = strconv.ParseUint(p.size, 10, 64)
+		if err != nil {
+			return
+		}
+
+		params.Set(p.numberParam(), strconv.FormatUint(number, 10))
+		params.Set(p.sizeParam(), strconv.FormatUint(size, 10))
+		query, _ := url.QueryUnescape(params.Encode())
+		result["current"] = fmt.Sprintf("%s?%s", requestURL, query)
 
-		if p.number != "1" {
-			params.Set("page[number]", "1")
+		if number != 1 {
+			params.Set(p.numberParam(), "1")
 			query, _ := url.QueryUnescape(params.Encode())
 			result["first"] = fmt.Sprintf("%s?%s", requestURL, query)
 
-			params.Set("page[number]", strconv.FormatUint(number-1, 10))
+			params.Set(p.numberParam(), strconv.FormatUint(number-1, 10))
 			query, _ = url.QueryUnescape(params.Encode())
 			result["prev"] = fmt.Sprintf("%s?%s", requestURL, query)
 		}
 
 		// calculate last page number
-		var size uint64
-		size, err = strconv.ParseUint(p.size, 10, 64)
-		if err != nil {
-			return
-		}
-		totalPages := (uint64(count) / size)
-		if (uint64(count) % size) != 0 {
+		totalPages := count / size
+		if (count % size) != 0 {
 			// there is one more page with some len(items) < size
 			totalPages++
 		}
 
-		if number != totalPages {
-			params.Set("page[number]", strconv.FormatUint(number+1, 10))
+		if number < totalPages {
+			params.Set(p.numberParam(), strconv.FormatUint(number+1, 10))
 			query, _ := url.QueryUnescape(params.Encode())
 			result["next"] = fmt.Sprintf("%s?%s", requestURL, query)
 
-			params.Set("page[number]", strconv.FormatUint(totalPages, 10))
+			params.Set(p.numberParam(), strconv.FormatUint(totalPages, 10))
 			query, _ = url.QueryUnescape(params.Encode())
 			result["last"] = fmt.Sprintf("%s?%s", requestURL, query)
 		}
@@ -155,7 +270,12 @@ func (p PaginationQueryParams) GetLinks(r *http.Request, count uint, info Inform
 			return
 		}
 
-		if p.offset != "0" {
+		params.Set("page[offset]", strconv.FormatUint(offset, 10))
+		params.Set("page[limit]", strconv.FormatUint(limit, 10))
+		query, _ := url.QueryUnescape(params.Encode())
+		result["current"] = fmt.Sprintf("%s?%s", requestURL, query)
+
+		if offset != 0 {
 			params.Set("page[offset]", "0")
 			query, _ := url.QueryUnescape(params.Encode())
 			result["first"] = fmt.Sprintf("%s?%s", requestURL, query)
@@ -172,12 +292,12 @@ func (p PaginationQueryParams) GetLinks(r *http.Request, count uint, info Inform
 		}
 
 		// check if there are more entries to be loaded
-		if (offset + limit) < uint64(count) {
+		if (offset + limit) < count {
 			params.Set("page[offset]", strconv.FormatUint(offset+limit, 10))
 			query, _ := url.QueryUnescape(params.Encode())
 			result["next"] = fmt.Sprintf("%s?%s", requestURL, query)
 
-			params.Set("page[offset]", strconv.FormatUint(uint64(count)-limit, 10))
+			params.Set("page[offset]", strconv.FormatUint(count-limit, 10))
 			query, _ = url.QueryUnescape(params.Encode())
 			result["last"] = fmt.Sprintf("%s?%s", requestURL, query)
 		}
@@ -186,13 +306,28 @@ func (p PaginationQueryParams) GetLinks(r *http.Request, count uint, info Inform
 	return
 }
 
+// NotAllowedHandler answers requests with a method that is not allowed
+// for the requested route with a jsonapi 405 error
 type NotAllowedHandler struct {
 	marshalers map[string]ContentMarshaler
 }
 
+// ServeHTTP implements http.Handler, the status code is written by HandleError
+// so that the Content-Type header is sent as well
 func (n NotAllowedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	err := NewHTTPError(nil, "Method Not Allowed", http.StatusMethodNotAllowed)
-	w.WriteHeader(http.StatusMethodNotAllowed)
+	HandleError(err, w, r, n.marshalers)
+}
+
+// NotFoundHandler answers requests to routes that are not registered,
+// e.g. unknown resource types, with a jsonapi 404 error
+type NotFoundHandler struct {
+	marshalers map[string]ContentMarshaler
+}
+
+// ServeHTTP implements http.Handler
+func (n NotFoundHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := NewHTTPError(nil, "Not Found", http.StatusNotFound)
 	HandleError(err, w, r, n.marshalers)
 }
 
@@ -201,79 +336,415 @@ type resource struct {
 	source       CRUD
 	name         string
 	marshalers   map[string]ContentMarshaler
+	deprecation  http.Header
+	types        *TypeRegistry
+	middlewares  routing.Chain
+	cacheTTL     time.Duration
+	authorizers  []Authorizer
+	// timeout limits the duration of reading requests, if it is set with WithTimeout
+	timeout time.Duration
+	// parent is the name of the resource this resource is nested in
+	parent      string
+	linkMethods bool
+	// readOnly resources answer all requests that would change data with 405
+	readOnly bool
+	// methods, relationships and routes are collected while registering the routes
+	methods       map[string]bool
+	relationships []string
+	references    []jsonapi.Reference
+	routes        []resourceRoute
+	// validFields contains the attribute names that can be requested with sparse fieldsets
+	validFields map[string]struct{}
+	// prefixes contains all api prefixes the routes are registered below
+	prefixes map[string]bool
+	// removal is the error all requests are answered with after DeprecateAndRemoveResource
+	removal *HTTPError
+	// version is added to the api prefix for resources added with AddVersionedResource
+	version string
+	// counters are the runtime statistics returned by api.Stats
+	counters *resourceCounters
+}
+
+// resourceRoute is a route of a resource without the api prefix
+type resourceRoute struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+	// prefixes is set for routes that do not belong to a resource, but are registered
+	// below the api prefix, their path is relative to it
+	prefixes map[string]bool
+}
+
+// register adds all routes of the resource below `prefix` to the router
+func (res *resource) register(router routing.Routeable, prefix string) {
+	prefix = strings.Trim(prefix, "/")
+	if res.prefixes[prefix] {
+		return
+	}
+	res.prefixes[prefix] = true
+
+	if prefix != "" {
+		prefix = "/" + prefix
+	}
+
+	for _, route := range res.routes {
+		router.Handle(route.method, prefix+route.path, route.handler)
+	}
+}
+
+// handle registers a route that does not belong to a resource
+func (api *API) handle(method, path string, handler http.HandlerFunc) {
+	route := resourceRoute{method: method, path: path, handler: handler}
+	api.routes = append(api.routes, route)
+	api.registerRoute(route, api.info.prefix)
+}
+
+// handlePrefixed registers a route that does not belong to a resource below the api prefix,
+// SetBasePath registers it below the new prefix again
+func (api *API) handlePrefixed(method, path string, handler http.HandlerFunc) {
+	route := resourceRoute{method: method, path: path, handler: handler, prefixes: map[string]bool{}}
+	api.routes = append(api.routes, route)
+	api.registerRoute(route, api.info.prefix)
+}
+
+// registerRoute adds a route that does not belong to a resource to the router, routes of
+// handlePrefixed are added below `prefix`
+func (api *API) registerRoute(route resourceRoute, prefix string) {
+	if route.prefixes == nil {
+		api.router.Handle(route.method, route.path, route.handler)
+		return
+	}
+
+	prefix = strings.Trim(prefix, "/")
+	if route.prefixes[prefix] {
+		return
+	}
+	route.prefixes[prefix] = true
+
+	if prefix != "" {
+		prefix = "/" + prefix
+	}
+
+	api.router.Handle(route.method, prefix+route.path, route.handler)
+}
+
+// key identifies a resource, it is unique within an api
+func (res *resource) key() string {
+	key := res.name
+	if res.parent != "" {
+		key = res.parent + "/" + key
+	}
+	if res.version != "" {
+		key = res.version + "/" + key
+	}
+
+	return key
+}
+
+// versionContext adds the version of the resource to the prefix of the api,
+// so that generated links and the Location header point to the versioned routes
+func (res *resource) versionContext(c context.Context) context.Context {
+	prefix, _ := c.Value(api_prefix).(string)
+	prefix = strings.Trim(strings.Trim(prefix, "/")+"/"+res.version, "/")
+	c = context.WithValue(c, api_prefix, prefix)
+
+	if info, ok := c.Value(api_info).(Information); ok {
+		c = context.WithValue(c, api_info, Information{prefix: prefix, resolver: info.resolver})
+	}
+
+	return c
 }
 
-func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source CRUD, marshalers map[string]ContentMarshaler) *resource {
+// withTimeout derives the context of the request with the timeout of the resource.
+// The returned function releases the context and must be called after the request.
+func (res *resource) withTimeout(r *http.Request) (*http.Request, context.CancelFunc) {
+	if res.timeout <= 0 {
+		return r, func() {}
+	}
+
+	c, cancel := context.WithTimeout(r.Context(), res.timeout)
+	return r.WithContext(c), cancel
+}
+
+// timeoutError answers errors that are caused by the expired context of the request with 504
+func timeoutError(r *http.Request, err error) error {
+	if r.Context().Err() != context.DeadlineExceeded || !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	return NewHTTPError(err, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+}
+
+// serve wraps all handlers of a resource to apply resource wide settings
+func (res *resource) serve(handler http.HandlerFunc) http.HandlerFunc {
+	authorized := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, authorizer := range res.authorizers {
+			if err := authorizer.Authorize(r); err != nil {
+				if _, ok := err.(HTTPError); !ok {
+					err = NewHTTPError(err, err.Error(), http.StatusForbidden)
+				}
+				HandleError(err, w, r, res.marshalers)
+				return
+			}
+		}
+
+		if res.cacheTTL > 0 && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+			w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(res.cacheTTL.Seconds())))
+		}
+
+		handler(w, r)
+	})
+	chain := res.middlewares.Handler(authorized)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder := res.counters.begin(w)
+		defer res.counters.end(recorder)
+		w = recorder
+
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				res.recoverPanic(recovered, recorder, r)
+			}
+		}()
+
+		for key, values := range res.deprecation {
+			w.Header()[key] = values
+		}
+
+		if res.removal != nil {
+			HandleError(*res.removal, w, r, res.marshalers)
+			return
+		}
+
+		chain.ServeHTTP(w, r)
+	}
+}
+
+var marshalIdentifierType = reflect.TypeOf((*jsonapi.MarshalIdentifier)(nil)).Elem()
+
+// attributeNames returns the names of all attributes that jsonapi marshals for a struct type
+func attributeNames(structType reflect.Type) map[string]struct{} {
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	names := map[string]struct{}{}
+	if structType.Kind() != reflect.Struct {
+		return names
+	}
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.Tag.Get("jsonapi") == "-" || field.PkgPath != "" {
+			continue
+		}
+
+		// the fields of embedded structs are marshaled as attributes of the struct itself
+		if field.Type.Implements(marshalIdentifierType) {
+			for name := range attributeNames(field.Type) {
+				names[name] = struct{}{}
+			}
+			continue
+		}
+
+		name := jsonapi.Jsonify(field.Name)
+		if tagName := jsonapi.GetTagValueByName(field, "name"); tagName != "" {
+			name = tagName
+		}
+		names[name] = struct{}{}
+	}
+
+	return names
+}
+
+// resourceName returns the jsonapi type name of a resource struct or struct pointer
+func resourceName(prototype jsonapi.MarshalIdentifier) string {
+	// check if EntityNamer interface is implemented and use that as name
+	entityName, ok := prototype.(jsonapi.EntityNamer)
+	if ok {
+		return entityName.GetName()
+	}
+
+	resourceType := reflect.TypeOf(prototype)
+	if resourceType.Kind() == reflect.Ptr {
+		resourceType = resourceType.Elem()
+	}
+
+	return jsonapi.Jsonify(jsonapi.Pluralize(resourceType.Name()))
+}
+
+// newResource creates a resource for the prototype and applies the options to it.
+// It panics if a resource with the same key is already registered.
+func (api *API) newResource(prototype jsonapi.MarshalIdentifier, marshalers map[string]ContentMarshaler, options []ResourceOption) *resource {
+	resourceType := reflect.TypeOf(prototype)
+	res := &resource{
+		resourceType: resourceType,
+		name:         resourceName(prototype),
+		marshalers:   marshalers,
+		methods:      map[string]bool{},
+		prefixes:     map[string]bool{},
+		validFields:  attributeNames(resourceType),
+		counters:     &resourceCounters{},
+	}
+
+	for _, option := range options {
+		option(res)
+	}
+
+	for _, registered := range api.resources {
+		if registered.key() == res.key() {
+			panic(fmt.Sprintf("resource '%s' already registered", res.key()))
+		}
+	}
+
+	return res
+}
+
+func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source CRUD, marshalers map[string]ContentMarshaler, options ...ResourceOption) *resource {
 	resourceType := reflect.TypeOf(prototype)
 	if resourceType.Kind() != reflect.Struct && resourceType.Kind() != reflect.Ptr {
 		panic("pass an empty resource struct or a struct pointer to AddResource!")
 	}
 
 	var ptrPrototype interface{}
-	var name string
 
 	if resourceType.Kind() == reflect.Struct {
 		ptrPrototype = reflect.New(resourceType).Interface()
-		name = resourceType.Name()
 	} else {
 		ptrPrototype = reflect.ValueOf(prototype).Interface()
-		name = resourceType.Elem().Name()
 	}
 
-	// check if EntityNamer interface is implemented and use that as name
-	entityName, ok := prototype.(jsonapi.EntityNamer)
-	if ok {
-		name = entityName.GetName()
-	} else {
-		name = jsonapi.Jsonify(jsonapi.Pluralize(name))
-	}
+	res := api.newResource(prototype, marshalers, options)
+	res.source = source
 
-	res := resource{
-		resourceType: resourceType,
-		name:         name,
-		source:       source,
-		marshalers:   marshalers,
+	if mw, ok := source.(ResourceMiddleware); ok {
+		res.middlewares = append(res.middlewares, mw.Middleware())
 	}
 
-	prefix := strings.Trim(api.info.prefix, "/")
+	name := res.name
+
+	// all routes are collected without prefix and registered at the end
 	baseURL := "/" + name
-	if prefix != "" {
-		baseURL = "/" + prefix + baseURL
+	if res.parent != "" {
+		baseURL = "/" + res.parent + "/:id" + baseURL
+	}
+	if res.version != "" {
+		baseURL = "/" + res.version + baseURL
 	}
 
-	api.router.Handle("OPTIONS", baseURL, func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Allow", "GET,POST,PATCH,OPTIONS")
+	// the id of nested resources is stored in :childID, because :id is the id of the parent
+	idParam := "id"
+	params := api.router.Param
+	if res.parent != "" {
+		idParam = "childID"
+		params = func(c context.Context, key string) string {
+			if key == "id" {
+				return api.router.Param(c, idParam)
+			}
+
+			return api.router.Param(c, key)
+		}
+	}
+
+	idURL := baseURL + "/:" + idParam
+
+	handle := func(protocol, route string, handler http.HandlerFunc) {
+		if res.readOnly && !readMethods[protocol] {
+			handler = readOnlyHandler(route, baseURL, idURL, marshalers)
+		} else {
+			res.methods[protocol] = true
+		}
+
+		res.routes = append(res.routes, resourceRoute{method: protocol, path: route, handler: res.serve(func(w http.ResponseWriter, r *http.Request) {
+			if res.parent != "" {
+				pathParams := map[string]string{"parentID": api.router.Param(r.Context(), "id")}
+				r = r.WithContext(context.WithValue(r.Context(), api_path_params, pathParams))
+			}
+
+			if res.version != "" {
+				r = r.WithContext(res.versionContext(r.Context()))
+			}
+
+			if provider, ok := source.(SchemaURLProvider); ok {
+				r = r.WithContext(context.WithValue(r.Context(), api_schema, provider.SchemaURL()))
+			}
+
+			handler(w, r)
+		})})
+	}
+
+	_, filterDeleter := source.(FilterDeleter)
+
+	baseAllow := "GET,POST,PATCH,OPTIONS"
+	if filterDeleter {
+		baseAllow = "GET,POST,PATCH,DELETE,OPTIONS"
+	}
+	if res.readOnly {
+		baseAllow = "GET,OPTIONS"
+	}
+
+	handle("OPTIONS", baseURL, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", baseAllow)
 		w.WriteHeader(http.StatusNoContent)
 	})
 
-	api.router.Handle("OPTIONS", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Allow", "GET,PATCH,DELETE,OPTIONS")
+	_, editToMany := ptrPrototype.(jsonapi.EditToManyRelations)
+	linkMethods := res.linkMethods && editToMany && !res.readOnly
+
+	_, copier := source.(Copier)
+
+	allow := "GET,HEAD,PATCH,DELETE,OPTIONS"
+	if linkMethods {
+		allow += ",LINK,UNLINK"
+	}
+	if copier {
+		allow += ",COPY"
+	}
+	if res.readOnly {
+		allow = "GET,HEAD,OPTIONS"
+	}
+
+	handle("OPTIONS", idURL, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
 		w.WriteHeader(http.StatusNoContent)
 	})
 
-	api.router.Handle("GET", baseURL, func(w http.ResponseWriter, r *http.Request) {
+	handle("GET", baseURL, func(w http.ResponseWriter, r *http.Request) {
+		r, cancel := res.withTimeout(r)
+		defer cancel()
+
 		err := res.handleIndex(r.Context(), w, r)
 		if err != nil {
-			HandleError(err, w, r, marshalers)
+			HandleError(timeoutError(r, err), w, r, marshalers)
 		}
 	})
 
-	api.router.Handle("GET", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request) {
-		err := res.handleRead(r.Context(), w, r, api.router.Param)
+	handle("GET", idURL, func(w http.ResponseWriter, r *http.Request) {
+		r, cancel := res.withTimeout(r)
+		defer cancel()
+
+		err := res.handleRead(r.Context(), w, r, params)
 		if err != nil {
-			HandleError(err, w, r, marshalers)
+			HandleError(timeoutError(r, err), w, r, marshalers)
 		}
 	})
 
+	handle("HEAD", idURL, func(w http.ResponseWriter, r *http.Request) {
+		res.handleHead(r.Context(), w, r, params)
+	})
+
 	// generate all routes for linked relations if there are relations
 	casted, ok := prototype.(jsonapi.MarshalReferences)
 	if ok {
 		relations := casted.GetReferences()
+		res.references = relations
 		for _, relation := range relations {
-			api.router.Handle("GET", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) http.HandlerFunc {
+			res.relationships = append(res.relationships, relation.Name)
+
+			handle("GET", idURL+"/relationships/"+relation.Name, func(relation jsonapi.Reference) http.HandlerFunc {
 				return func(w http.ResponseWriter, r *http.Request) {
 					ctx := context.WithValue(r.Context(), api_relation, relation.Name)
-					err := res.handleReadRelation(ctx, w, r, api.router.Param)
+					err := res.handleReadRelation(ctx, w, r, params)
 					if err != nil {
 						HandleError(err, w, r, marshalers)
 					}
@@ -291,32 +762,44 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source CRUD, ma
 			// 	}
 			// }(relation))
 
-			api.router.Handle("PATCH", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) http.HandlerFunc {
+			handle("PATCH", idURL+"/relationships/"+relation.Name, func(relation jsonapi.Reference) http.HandlerFunc {
 				return func(w http.ResponseWriter, r *http.Request) {
 					ctx := context.WithValue(r.Context(), api_relation, relation.Name)
-					err := res.handleReplaceRelation(ctx, w, r, api.router.Param)
+					err := res.handleReplaceRelation(ctx, w, r, params)
 					if err != nil {
 						HandleError(err, w, r, marshalers)
 					}
 				}
 			}(relation))
 
+			if _, ok := source.(BulkRelationshipPatcher); ok {
+				handle("PATCH", baseURL+"/relationships/"+relation.Name, func(relation jsonapi.Reference) http.HandlerFunc {
+					return func(w http.ResponseWriter, r *http.Request) {
+						ctx := context.WithValue(r.Context(), api_relation, relation.Name)
+						err := res.handleBulkReplaceRelation(ctx, w, r)
+						if err != nil {
+							HandleError(err, w, r, marshalers)
+						}
+					}
+				}(relation))
+			}
+
 			if _, ok := ptrPrototype.(jsonapi.EditToManyRelations); ok && relation.Name == jsonapi.Pluralize(relation.Name) {
 				// generate additional routes to manipulate to-many relationships
-				api.router.Handle("POST", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) http.HandlerFunc {
+				handle("POST", idURL+"/relationships/"+relation.Name, func(relation jsonapi.Reference) http.HandlerFunc {
 					return func(w http.ResponseWriter, r *http.Request) {
 						ctx := context.WithValue(r.Context(), api_relation, relation.Name)
-						err := res.handleAddToManyRelation(ctx, w, r, api.router.Param)
+						err := res.handleAddToManyRelation(ctx, w, r, params)
 						if err != nil {
 							HandleError(err, w, r, marshalers)
 						}
 					}
 				}(relation))
 
-				api.router.Handle("DELETE", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) http.HandlerFunc {
+				handle("DELETE", idURL+"/relationships/"+relation.Name, func(relation jsonapi.Reference) http.HandlerFunc {
 					return func(w http.ResponseWriter, r *http.Request) {
 						ctx := context.WithValue(r.Context(), api_relation, relation.Name)
-						err := res.handleDeleteToManyRelation(ctx, w, r, api.router.Param)
+						err := res.handleDeleteToManyRelation(ctx, w, r, params)
 						if err != nil {
 							HandleError(err, w, r, marshalers)
 						}
@@ -326,30 +809,143 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source CRUD, ma
 		}
 	}
 
-	api.router.Handle("POST", baseURL, func(w http.ResponseWriter, r *http.Request) {
+	if linkMethods {
+		toManyRelations := map[string]string{}
+		if casted, ok := prototype.(jsonapi.MarshalReferences); ok {
+			for _, relation := range casted.GetReferences() {
+				if relation.Name == jsonapi.Pluralize(relation.Name) {
+					toManyRelations[relation.Name] = relation.Type
+				}
+			}
+		}
+
+		handle("LINK", idURL, func(w http.ResponseWriter, r *http.Request) {
+			err := res.handleLink(r.Context(), w, r, params, toManyRelations, false)
+			if err != nil {
+				HandleError(err, w, r, marshalers)
+			}
+		})
+
+		handle("UNLINK", idURL, func(w http.ResponseWriter, r *http.Request) {
+			err := res.handleLink(r.Context(), w, r, params, toManyRelations, true)
+			if err != nil {
+				HandleError(err, w, r, marshalers)
+			}
+		})
+	}
+
+	if copier {
+		handle("COPY", idURL, func(w http.ResponseWriter, r *http.Request) {
+			err := res.handleCopy(r.Context(), w, r, params)
+			if err != nil {
+				HandleError(err, w, r, marshalers)
+			}
+		})
+	}
+
+	handle("POST", baseURL, func(w http.ResponseWriter, r *http.Request) {
 		err := res.handleCreate(r.Context(), w, r)
 		if err != nil {
 			HandleError(err, w, r, marshalers)
 		}
 	})
 
-	api.router.Handle("DELETE", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request) {
-		err := res.handleDelete(r.Context(), w, r, api.router.Param)
+	handle("DELETE", idURL, func(w http.ResponseWriter, r *http.Request) {
+		err := res.handleDelete(r.Context(), w, r, params)
 		if err != nil {
 			HandleError(err, w, r, marshalers)
 		}
 	})
 
-	api.router.Handle("PATCH", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request) {
-		err := res.handleUpdate(r.Context(), w, r, api.router.Param)
+	if filterDeleter {
+		handle("DELETE", baseURL, func(w http.ResponseWriter, r *http.Request) {
+			err := res.handleDeleteWhere(r.Context(), w, r)
+			if err != nil {
+				HandleError(err, w, r, marshalers)
+			}
+		})
+	}
+
+	handle("PATCH", idURL, func(w http.ResponseWriter, r *http.Request) {
+		err := res.handleUpdate(r.Context(), w, r, params)
 		if err != nil {
 			HandleError(err, w, r, marshalers)
 		}
 	})
 
+	if _, ok := source.(BulkPatcher); ok {
+		handle("PATCH", baseURL, func(w http.ResponseWriter, r *http.Request) {
+			err := res.handleBulkPatch(r.Context(), w, r)
+			if err != nil {
+				HandleError(err, w, r, marshalers)
+			}
+		})
+	}
+
+	res.register(api.router, api.info.prefix)
 	api.resources = append(api.resources, res)
 
-	return &res
+	return res
+}
+
+// readMethods are the methods that are registered for read-only resources
+var readMethods = map[string]bool{"GET": true, "HEAD": true, "OPTIONS": true}
+
+// readOnlyHandler answers requests to routes of read-only resources, which
+// would change data, with a 405 error and the methods that are allowed instead
+func readOnlyHandler(route, baseURL, idURL string, marshalers map[string]ContentMarshaler) http.HandlerFunc {
+	allow := "GET"
+	switch route {
+	case baseURL:
+		allow = "GET,OPTIONS"
+	case idURL:
+		allow = "GET,HEAD,OPTIONS"
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		HandleError(NewHTTPError(nil, "Method Not Allowed", http.StatusMethodNotAllowed), w, r, marshalers)
+	}
+}
+
+// strictQueryParams rejects all requests that contain query parameters
+// which are not part of the jsonapi query parameter families
+func strictQueryParams(marshalers map[string]ContentMarshaler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			unknown := []string{}
+			for key := range r.URL.Query() {
+				name := key
+				if index := strings.Index(key, "["); index >= 0 {
+					name = key[:index]
+				}
+
+				if !knownQueryParams[name] && !(name == "embed" && embeddingEnabled(r)) {
+					unknown = append(unknown, key)
+				}
+			}
+
+			if len(unknown) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			sort.Strings(unknown)
+			httpError := NewHTTPError(nil, "Unknown query parameters", http.StatusBadRequest)
+			for _, param := range unknown {
+				httpError.Errors = append(httpError.Errors, Error{
+					Status: strconv.Itoa(http.StatusBadRequest),
+					Code:   codeUnknownQueryParam,
+					Title:  fmt.Sprintf(`Query parameter "%s" is not supported`, param),
+					Source: &ErrorSource{
+						Parameter: param,
+					},
+				})
+			}
+
+			HandleError(httpError, w, r, marshalers)
+		})
+	}
 }
 
 func BuildRequest(c context.Context, r *http.Request) Request {
@@ -358,23 +954,73 @@ func BuildRequest(c context.Context, r *http.Request) Request {
 	for key, values := range r.URL.Query() {
 		params[key] = strings.Split(values[0], ",")
 	}
+	// sources read the page number and size, also if the client used the aliases
+	for alias, name := range paginationAliases {
+		if _, ok := params[name]; !ok && params[alias] != nil {
+			params[name] = params[alias]
+		}
+	}
 	req.QueryParams = params
 	req.Header = r.Header
 	req.Context = c
+	if pathParams, ok := c.Value(api_path_params).(map[string]string); ok {
+		req.PathParams = pathParams
+	}
+	req.Search = r.URL.Query().Get("filter[q]")
+	// invalid filters are rejected by handleIndex before
+	req.Filters, _ = ParseFilters(r.URL.Query())
+	req.FilterTree, _ = ParseFilterTree(r.URL.Query())
+	req.GeoFilter, _ = ParseGeoFilter(r.URL.Query())
+	req.Includes = ParseIncludes(r.URL.Query())
+	if api, ok := c.Value(api_api).(*API); ok && api.embedding {
+		req.Embeds = parseRelationshipPaths(r.URL.Query().Get("embed"))
+	}
 	return req
 }
 
 func (res *resource) handleIndex(c context.Context, w http.ResponseWriter, r *http.Request) error {
 	info := c.Value(api_info).(Information)
 
+	if _, err := ParseFilters(r.URL.Query()); err != nil {
+		return NewHTTPError(err, err.Error(), http.StatusBadRequest)
+	}
+
+	if _, err := ParseGeoFilter(r.URL.Query()); err != nil {
+		return NewHTTPError(err, err.Error(), http.StatusBadRequest)
+	}
+
 	pagination := NewPaginationQueryParams(r)
-	if pagination.IsValid() {
+	valid, err := pagination.IsValidWithError()
+	if err != nil {
+		return NewHTTPError(err, err.Error(), http.StatusBadRequest)
+	}
+
+	req := BuildRequest(c, r)
+
+	// the search takes precedence over pagination, the page parameters are passed on in req
+	if searchable, ok := res.source.(Searchable); ok {
+		if req.Search != "" {
+			response, err := searchable.Search(req.Search, req)
+			if err != nil {
+				return err
+			}
+
+			return res.respondWithCollection(c, response, req, w, r)
+		}
+	}
+
+	if valid {
 		source, ok := res.source.(PaginatedFindAll)
 		if !ok {
 			return NewHTTPError(nil, "Resource does not implement the PaginatedFindAll interface", http.StatusNotFound)
 		}
 
-		count, response, err := source.PaginatedFindAll(BuildRequest(c, r))
+		count, response, err := source.PaginatedFindAll(req)
+		if err != nil {
+			return err
+		}
+
+		response, err = res.afterFetch(response, req)
 		if err != nil {
 			return err
 		}
@@ -384,74 +1030,270 @@ func (res *resource) handleIndex(c context.Context, w http.ResponseWriter, r *ht
 			return err
 		}
 
-		return RespondWithPagination(response, info, http.StatusOK, paginationLinks, w, r, res.marshalers)
+		response, err = res.withEmbeds(c, res.maskFields(response, req), req)
+		if err != nil {
+			return err
+		}
+
+		response, err = res.withIncludes(c, response, req)
+		if err != nil {
+			return err
+		}
+
+		return RespondWithPagination(response, info, http.StatusOK, paginationLinks, count, w, r, res.marshalers)
+	}
+
+	if geoFilterable, ok := res.source.(GeoFilterable); ok {
+		if req.GeoFilter != nil {
+			response, err := geoFilterable.FindAllWithGeo(req)
+			if err != nil {
+				return err
+			}
+
+			return res.respondWithCollection(c, response, req, w, r)
+		}
 	}
+
+	if filterable, ok := res.source.(Filterable); ok {
+		if len(req.FilterTree.Children) > 0 {
+			response, err := filterable.FilterAll(req.FilterTree, req)
+			if err != nil {
+				return err
+			}
+
+			return res.respondWithCollection(c, response, req, w, r)
+		}
+	}
+
+	if projectable, ok := res.source.(Projectable); ok {
+		query := r.URL.Query()
+		if fields := parseQueryFields(&query); len(fields) > 0 {
+			response, err := projectable.FindAllWithProjection(fields, req)
+			if err != nil {
+				return err
+			}
+
+			return res.respondWithCollection(c, response, req, w, r)
+		}
+	}
+
 	source, ok := res.source.(FindAll)
 	if !ok {
 		return NewHTTPError(nil, "Resource does not implement the FindAll interface", http.StatusNotFound)
 	}
 
-	response, err := source.FindAll(BuildRequest(c, r))
+	response, err := source.FindAll(req)
 	if err != nil {
 		return err
 	}
 
-	return RespondWith(response, http.StatusOK, c, w, r)
+	return res.respondWithCollection(c, response, req, w, r)
 }
 
 func (res *resource) handleRead(c context.Context, w http.ResponseWriter, r *http.Request, params func(context.Context, string) string) error {
 	id := params(c, "id")
+	req := BuildRequest(c, r)
 
-	response, err := res.source.FindOne(id, BuildRequest(c, r))
+	response, err := res.source.FindOne(id, req)
 
 	if err != nil {
 		return err
 	}
 
+	return res.respondWithIncludes(c, response, req, w, r)
+}
+
+// respondWithIncludes masks the fields of `response` and responds with it and
+// the related resources requested with the `include` and `embed` query parameters
+func (res *resource) respondWithIncludes(c context.Context, response Responder, req Request, w http.ResponseWriter, r *http.Request) error {
+	response, err := res.withEmbeds(c, res.maskFields(response, req), req)
+	if err != nil {
+		return err
+	}
+
+	response, err = res.withIncludes(c, response, req)
+	if err != nil {
+		return err
+	}
+
 	return RespondWith(response, http.StatusOK, c, w, r)
 }
 
+// respondWithCollection passes the result of `response` to AfterFetch before
+// responding with it like respondWithIncludes
+func (res *resource) respondWithCollection(c context.Context, response Responder, req Request, w http.ResponseWriter, r *http.Request) error {
+	response, err := res.afterFetch(response, req)
+	if err != nil {
+		return err
+	}
+
+	return res.respondWithIncludes(c, response, req, w, r)
+}
+
+// afterFetch lets an AfterFetch source replace the fetched collection
+func (res *resource) afterFetch(response Responder, req Request) (Responder, error) {
+	hook, ok := res.source.(AfterFetch)
+	if !ok || response == nil {
+		return response, nil
+	}
+
+	result := reflect.ValueOf(response.Result())
+	if result.Kind() != reflect.Slice {
+		return response, nil
+	}
+
+	objs := make([]interface{}, result.Len())
+	for i := range objs {
+		objs[i] = result.Index(i).Interface()
+	}
+
+	objs, err := hook.AfterFetch(objs, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return maskedResponder{Responder: response, result: objs}, nil
+}
+
+// maskedResponder replaces the result of a Responder with the masked or transformed objects
+type maskedResponder struct {
+	Responder
+	result interface{}
+}
+
+func (m maskedResponder) Result() interface{} {
+	return m.result
+}
+
+// maskFields lets a FieldMasker source mask every object of the response
+func (res *resource) maskFields(response Responder, req Request) Responder {
+	masker, ok := res.source.(FieldMasker)
+	if !ok || response.Result() == nil {
+		return response
+	}
+
+	result := reflect.ValueOf(response.Result())
+	if result.Kind() != reflect.Slice {
+		return maskedResponder{Responder: response, result: masker.MaskFields(response.Result(), req)}
+	}
+
+	masked := make([]interface{}, result.Len())
+	for i := range masked {
+		masked[i] = masker.MaskFields(result.Index(i).Interface(), req)
+	}
+
+	return maskedResponder{Responder: response, result: masked}
+}
+
+// handleHead checks for the existence of a resource by calling FindOne, the
+// result is only reported via status code and header, a body is never written
+func (res *resource) handleHead(c context.Context, w http.ResponseWriter, r *http.Request, params func(context.Context, string) string) {
+	id := params(c, "id")
+
+	_, err := res.source.FindOne(id, BuildRequest(c, r))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if e, ok := err.(HTTPError); ok {
+			status = e.status
+		}
+
+		if status == http.StatusNotFound {
+			w.Header().Set(headerResourceExists, "false")
+		} else {
+			log.Println(err)
+		}
+
+		w.WriteHeader(status)
+		return
+	}
+
+	_, contentType := selectContentMarshaler(r, res.marshalers)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set(headerResourceExists, "true")
+	w.WriteHeader(http.StatusOK)
+}
+
 func (res *resource) handleReadRelation(c context.Context, w http.ResponseWriter, r *http.Request, params func(context.Context, string) string) error {
 	id := params(c, "id")
+	req := BuildRequest(c, r)
 
-	obj, err := res.source.FindOne(id, BuildRequest(c, r))
+	obj, err := res.source.FindOne(id, req)
+	if err != nil {
+		return err
+	}
+
+	info := c.Value(api_info).(Information)
+	relName := c.Value(api_relation).(string)
+	result, err := relationshipDocument(obj.Result(), relName, info)
 	if err != nil {
 		return err
 	}
 
+	meta := obj.Metadata()
+	if len(meta) > 0 {
+		result["meta"] = meta
+	}
+
+	if includer, ok := res.source.(RelationshipIncluder); ok {
+		included, err := includer.IncludeRelationship(relName, obj.Result(), req)
+		if err != nil {
+			return err
+		}
+
+		if included != nil {
+			includedData, err := jsonapi.MarshalWithURLs(included.Result(), info)
+			if err != nil {
+				return err
+			}
+
+			switch data := includedData["data"].(type) {
+			case []map[string]interface{}:
+				result["included"] = data
+			case map[string]interface{}:
+				result["included"] = []map[string]interface{}{data}
+			}
+		}
+	}
+
+	return marshalResponse(c, result, w, http.StatusOK, r, res.marshalers)
+}
+
+// relationshipDocument returns the linkage data and links of the relationship `relName` of `obj`
+func relationshipDocument(obj interface{}, relName string, info Information) (map[string]interface{}, error) {
 	internalError := NewHTTPError(nil, "Internal server error, invalid object structure", http.StatusInternalServerError)
 
-	info := c.Value(api_info).(Information)
-	marshalled, err := jsonapi.MarshalWithURLs(obj.Result(), info)
+	marshalled, err := jsonapi.MarshalWithURLs(obj, info)
+	if err != nil {
+		return nil, err
+	}
 	data, ok := marshalled["data"]
 	if !ok {
-		return internalError
+		return nil, internalError
 	}
 	relationships, ok := data.(map[string]interface{})["relationships"]
 	if !ok {
-		return internalError
+		return nil, internalError
 	}
 
-	relName := c.Value(api_relation).(string)
 	rel, ok := relationships.(map[string]map[string]interface{})[relName]
 	if !ok {
-		return NewHTTPError(nil, fmt.Sprintf("There is no relation with the name %s", relName), http.StatusNotFound)
+		return nil, NewHTTPError(nil, fmt.Sprintf("There is no relation with the name %s", relName), http.StatusNotFound)
 	}
 	links, ok := rel["links"].(map[string]string)
 	if !ok {
-		return internalError
+		return nil, internalError
 	}
 	self, ok := links["self"]
 	if !ok {
-		return internalError
+		return nil, internalError
 	}
 	related, ok := links["related"]
 	if !ok {
-		return internalError
+		return nil, internalError
 	}
 	relationData, ok := rel["data"]
 	if !ok {
-		return internalError
+		return nil, internalError
 	}
 
 	result := map[string]interface{}{}
@@ -460,12 +1302,11 @@ func (res *resource) handleReadRelation(c context.Context, w http.ResponseWriter
 		"related": related,
 	}
 	result["data"] = relationData
-	meta := obj.Metadata()
-	if len(meta) > 0 {
+	if meta, ok := rel["meta"]; ok {
 		result["meta"] = meta
 	}
 
-	return marshalResponse(result, w, http.StatusOK, r, res.marshalers)
+	return result, nil
 }
 
 // try to find the referenced resource and call the findAll Method with referencing resource id as param
@@ -481,13 +1322,17 @@ func (res *resource) handleLinked(c context.Context, api *API, w http.ResponseWr
 
 			// check for pagination, otherwise normal FindAll
 			pagination := NewPaginationQueryParams(r)
-			if pagination.IsValid() {
+			valid, err := pagination.IsValidWithError()
+			if err != nil {
+				return NewHTTPError(err, err.Error(), http.StatusBadRequest)
+			}
+
+			if valid {
 				source, ok := resource.source.(PaginatedFindAll)
 				if !ok {
 					return NewHTTPError(nil, "Resource does not implement the PaginatedFindAll interface", http.StatusNotFound)
 				}
 
-				var count uint
 				count, response, err := source.PaginatedFindAll(request)
 				if err != nil {
 					return err
@@ -498,7 +1343,7 @@ func (res *resource) handleLinked(c context.Context, api *API, w http.ResponseWr
 					return err
 				}
 
-				return RespondWithPagination(response, info, http.StatusOK, paginationLinks, w, r, res.marshalers)
+				return RespondWithPagination(response, info, http.StatusOK, paginationLinks, count, w, r, res.marshalers)
 			}
 
 			source, ok := resource.source.(FindAll)
@@ -528,20 +1373,26 @@ func (res *resource) handleLinked(c context.Context, api *API, w http.ResponseWr
 
 func (res *resource) handleCreate(c context.Context, w http.ResponseWriter, r *http.Request) error {
 	ctx, err := unmarshalRequest(r, res.marshalers)
-	prefix := c.Value(api_prefix).(string)
 	if err != nil {
 		return err
 	}
-	newObjs := reflect.MakeSlice(reflect.SliceOf(res.resourceType), 0, 0)
+	resourceType := res.resourceType
+	if res.types != nil {
+		if registered, ok := res.types.Lookup(requestedType(ctx)); ok {
+			resourceType = registered
+		}
+	}
 
-	structType := res.resourceType
+	newObjs := reflect.MakeSlice(reflect.SliceOf(resourceType), 0, 0)
+
+	structType := resourceType
 	if structType.Kind() == reflect.Ptr {
 		structType = structType.Elem()
 	}
 
 	err = jsonapi.UnmarshalInto(ctx, structType, &newObjs)
 	if err != nil {
-		return err
+		return newUnmarshalError(err)
 	}
 	if newObjs.Len() != 1 {
 		return errors.New("expected one object in POST")
@@ -550,18 +1401,36 @@ func (res *resource) handleCreate(c context.Context, w http.ResponseWriter, r *h
 	//TODO create multiple objects not only one.
 	newObj := newObjs.Index(0).Interface()
 
-	response, err := res.source.Create(newObj, BuildRequest(c, r))
+	req := BuildRequest(c, r)
+	response, err := res.source.Create(newObj, req)
 	if err != nil {
 		return err
 	}
 
-	result, ok := response.Result().(jsonapi.MarshalIdentifier)
+	// there is no created object to point to for 204 responses
+	var id, location string
+	if pollingURL, ok := asyncPollingURL(response); ok {
+		// the object is created later, the client can poll for it
+		location = pollingURL
+	} else if response.StatusCode() != http.StatusNoContent {
+		result, ok := response.Result().(jsonapi.MarshalIdentifier)
 
-	if !ok {
-		return fmt.Errorf("Expected one newly created object by resource %s", res.name)
+		if !ok {
+			return fmt.Errorf("Expected one newly created object by resource %s", res.name)
+		}
+
+		id = result.GetID()
+		location = res.location(c, id)
+	}
+
+	if err := res.publish(c, EventCreated, id, response.Result(), req); err != nil {
+		return err
 	}
 
-	w.Header().Set("Location", "/"+prefix+"/"+res.name+"/"+result.GetID())
+	// the error response of a failed publish must not point to the new entry
+	if location != "" {
+		w.Header().Set("Location", location)
+	}
 
 	// handle 200 status codes
 	switch response.StatusCode() {
@@ -578,8 +1447,37 @@ func (res *resource) handleCreate(c context.Context, w http.ResponseWriter, r *h
 	}
 }
 
+// location returns the path of the entry with the given id for the Location header
+func (res *resource) location(c context.Context, id string) string {
+	prefix := c.Value(api_prefix).(string)
+	if pathParams, ok := c.Value(api_path_params).(map[string]string); ok {
+		return "/" + prefix + "/" + res.parent + "/" + pathParams["parentID"] + "/" + res.name + "/" + id
+	}
+
+	return "/" + prefix + "/" + res.name + "/" + id
+}
+
+// asyncPollingURL returns the polling url of a 202 Accepted response to Create from
+// AsyncResponder or the `polling_url` meta data
+func asyncPollingURL(response Responder) (string, bool) {
+	if response.StatusCode() != http.StatusAccepted {
+		return "", false
+	}
+
+	if async, ok := response.(AsyncResponder); ok && async.PollingURL() != "" {
+		return async.PollingURL(), true
+	}
+
+	pollingURL, ok := response.Metadata()["polling_url"].(string)
+	return pollingURL, ok && pollingURL != ""
+}
+
 func (res *resource) handleUpdate(c context.Context, w http.ResponseWriter, r *http.Request, params func(context.Context, string) string) error {
 	id := params(c, "id")
+	if updater, ok := res.source.(JSONPatchUpdater); ok && isJSONPatch(r) {
+		return res.handleJSONPatch(c, w, r, id, updater)
+	}
+
 	obj, err := res.source.FindOne(id, BuildRequest(c, r))
 	if err != nil {
 		return err
@@ -593,35 +1491,48 @@ func (res *resource) handleUpdate(c context.Context, w http.ResponseWriter, r *h
 	data, ok := ctx["data"]
 
 	if !ok {
-		return NewHTTPError(
+		return newDocumentError(
 			errors.New("Forbidden"),
 			"missing mandatory data key.",
 			http.StatusForbidden,
+			"/data",
 		)
 	}
 
 	check, ok := data.(map[string]interface{})
 	if !ok {
-		return NewHTTPError(
+		return newDocumentError(
 			errors.New("Forbidden"),
 			"data must contain an object.",
 			http.StatusForbidden,
+			"/data",
 		)
 	}
 
 	if _, ok := check["id"]; !ok {
-		return NewHTTPError(
+		return newDocumentError(
 			errors.New("Forbidden"),
 			"missing mandatory id key.",
 			http.StatusForbidden,
+			"/data",
 		)
 	}
 
 	if _, ok := check["type"]; !ok {
-		return NewHTTPError(
+		return newDocumentError(
 			errors.New("Forbidden"),
 			"missing mandatory type key.",
 			http.StatusForbidden,
+			"/data",
+		)
+	}
+
+	if check["id"] != id {
+		return newDocumentError(
+			errors.New("Conflict"),
+			fmt.Sprintf("id %v in the request document does not match id %s in the url.", check["id"], id),
+			http.StatusConflict,
+			"/data/id",
 		)
 	}
 
@@ -636,7 +1547,7 @@ func (res *resource) handleUpdate(c context.Context, w http.ResponseWriter, r *h
 	err = jsonapi.UnmarshalInto(ctx, structType, &updatingObjs)
 
 	if err != nil {
-		return err
+		return newUnmarshalError(err)
 	}
 	if updatingObjs.Len() != 1 {
 		return errors.New("expected one object")
@@ -644,12 +1555,23 @@ func (res *resource) handleUpdate(c context.Context, w http.ResponseWriter, r *h
 
 	updatingObj := updatingObjs.Index(0).Interface()
 
-	response, err := res.source.Update(updatingObj, BuildRequest(c, r))
+	req := BuildRequest(c, r)
+	response, err := res.source.Update(updatingObj, req)
 
 	if err != nil {
 		return err
 	}
 
+	if err := res.publish(c, EventUpdated, id, updatingObj, req); err != nil {
+		return err
+	}
+
+	return res.respondUpdated(c, w, r, id, response)
+}
+
+// respondUpdated answers an update of the object with the given id depending on the
+// status code of the response. For 200 without result the object is loaded with FindOne.
+func (res *resource) respondUpdated(c context.Context, w http.ResponseWriter, r *http.Request, id string, response Responder) error {
 	switch response.StatusCode() {
 	case http.StatusOK:
 		updated := response.Result()
@@ -678,6 +1600,10 @@ func (res *resource) handleUpdate(c context.Context, w http.ResponseWriter, r *h
 }
 
 func (res *resource) handleReplaceRelation(c context.Context, w http.ResponseWriter, r *http.Request, params func(context.Context, string) string) error {
+	if handler, ok := res.source.(RelationshipHandler); ok {
+		return res.handleCustomRelation(c, w, r, params, handler, RelationshipReplace)
+	}
+
 	var (
 		err     error
 		editObj interface{}
@@ -714,29 +1640,143 @@ func (res *resource) handleReplaceRelation(c context.Context, w http.ResponseWri
 	}
 
 	if resType == reflect.Struct {
-		_, err = res.source.Update(reflect.ValueOf(editObj).Elem().Interface(), BuildRequest(c, r))
-	} else {
-		_, err = res.source.Update(editObj, BuildRequest(c, r))
+		editObj = reflect.ValueOf(editObj).Elem().Interface()
+	}
+
+	updated, err := res.source.Update(editObj, BuildRequest(c, r))
+	if err != nil {
+		return err
+	}
+
+	switch updated.StatusCode() {
+	case http.StatusOK:
+		// answer with the linkage of the updated object, or of the sent object if there is none
+		result := updated.Result()
+		if result == nil {
+			result = editObj
+		}
+
+		document, err := relationshipDocument(result, relName, c.Value(api_info).(Information))
+		if err != nil {
+			return err
+		}
+
+		if meta := updated.Metadata(); len(meta) > 0 {
+			document["meta"] = meta
+		}
+
+		return marshalResponse(c, document, w, http.StatusOK, r, res.marshalers)
+	case http.StatusAccepted:
+		w.WriteHeader(http.StatusAccepted)
+		return nil
+	case http.StatusNoContent:
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	default:
+		return fmt.Errorf("invalid status code %d from resource %s for method Update", updated.StatusCode(), res.name)
+	}
+}
+
+func (res *resource) handleBulkReplaceRelation(c context.Context, w http.ResponseWriter, r *http.Request) error {
+	source, ok := res.source.(BulkRelationshipPatcher)
+	if !ok {
+		return NewHTTPError(nil, "Resource does not implement the BulkRelationshipPatcher interface", http.StatusNotFound)
+	}
+
+	inc, err := unmarshalRequest(r, res.marshalers)
+	if err != nil {
+		return err
+	}
+
+	data, ok := inc["data"].([]interface{})
+	if !ok {
+		return NewHTTPError(nil, "data must be an array of resource objects", http.StatusBadRequest)
+	}
+
+	relName := c.Value(api_relation).(string)
+	updates := []RelationshipUpdate{}
+
+	for _, entry := range data {
+		obj, ok := entry.(map[string]interface{})
+		if !ok {
+			return NewHTTPError(nil, "entry in data array must be an object", http.StatusBadRequest)
+		}
+
+		id, ok := obj["id"].(string)
+		if !ok {
+			return NewHTTPError(nil, "no id field found inside data object", http.StatusBadRequest)
+		}
+
+		relationships, _ := obj["relationships"].(map[string]interface{})
+		rel, ok := relationships[relName].(map[string]interface{})
+		if !ok {
+			return NewHTTPError(nil, fmt.Sprintf("missing relationship %s for id %s", relName, id), http.StatusBadRequest)
+		}
+
+		linkage, ok := rel["data"]
+		if !ok {
+			return NewHTTPError(nil, fmt.Sprintf("missing data field of relationship %s for id %s", relName, id), http.StatusBadRequest)
+		}
+
+		newIDs, err := relationshipIDs(linkage)
+		if err != nil {
+			return NewHTTPError(err, err.Error(), http.StatusBadRequest)
+		}
+
+		updates = append(updates, RelationshipUpdate{ResourceID: id, NewIDs: newIDs})
+	}
+
+	err = source.PatchRelationships(relName, updates, BuildRequest(c, r))
+	if err != nil {
+		return err
 	}
 
 	w.WriteHeader(http.StatusNoContent)
-	return err
+	return nil
+}
+
+// relationshipIDs returns all IDs of a to-one or to-many relationship data object
+func relationshipIDs(linkage interface{}) ([]string, error) {
+	IDs := []string{}
+
+	switch data := linkage.(type) {
+	case nil:
+		return IDs, nil
+	case map[string]interface{}:
+		ID, ok := data["id"].(string)
+		if !ok {
+			return nil, errors.New("no id field found inside data object")
+		}
+
+		return append(IDs, ID), nil
+	case []interface{}:
+		for _, entry := range data {
+			casted, ok := entry.(map[string]interface{})
+			if !ok {
+				return nil, errors.New("entry in data object invalid")
+			}
+			ID, ok := casted["id"].(string)
+			if !ok {
+				return nil, errors.New("no id field found inside data object")
+			}
+
+			IDs = append(IDs, ID)
+		}
+
+		return IDs, nil
+	default:
+		return nil, errors.New("data must be an object, an array or null")
+	}
 }
 
 func (res *resource) handleAddToManyRelation(c context.Context, w http.ResponseWriter, r *http.Request, params func(context.Context, string) string) error {
-	var (
-		err     error
-		editObj interface{}
-	)
+	if handler, ok := res.source.(RelationshipHandler); ok {
+		return res.handleCustomRelation(c, w, r, params, handler, RelationshipAdd)
+	}
 
 	id := params(c, "id")
 	relName := c.Value(api_relation).(string)
 
-	response, err := res.source.FindOne(id, BuildRequest(c, r))
-	if err != nil {
-		return err
-	}
-
 	inc, err := unmarshalRequest(r, res.marshalers)
 	if err != nil {
 		return err
@@ -767,6 +1807,79 @@ func (res *resource) handleAddToManyRelation(c context.Context, w http.ResponseW
 		newIDs = append(newIDs, newID)
 	}
 
+	err = res.editToManyRelations(c, r, id, map[string][]string{relName: newIDs}, false)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// handleLink adds (LINK) or deletes (UNLINK) the to-many relationships that are
+// referenced in the `Link` header, e.g. `Link: </v1/comments/1>; rel="comments"`
+func (res *resource) handleLink(c context.Context, w http.ResponseWriter, r *http.Request, params func(context.Context, string) string, toManyRelations map[string]string, unlink bool) error {
+	relationIDs, err := linkHeaderIDs(r.Header, toManyRelations)
+	if err != nil {
+		return err
+	}
+
+	err = res.editToManyRelations(c, r, params(c, "id"), relationIDs, unlink)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// linkHeaderIDs returns the ids of all links per relation name. `toManyRelations` maps
+// the relation names to their types, which must be the second to last path segment of
+// the link URL, the id is the last one.
+func linkHeaderIDs(headers http.Header, toManyRelations map[string]string) (map[string][]string, error) {
+	links, ok := header.ParseLink(headers, "Link")
+	if !ok {
+		return nil, NewHTTPError(nil, "Invalid Link header", http.StatusBadRequest)
+	}
+
+	relationIDs := map[string][]string{}
+	for _, link := range links {
+		relName := link.Params["rel"]
+		relType, ok := toManyRelations[relName]
+		if !ok {
+			return nil, NewHTTPError(nil, fmt.Sprintf("There is no to-many relationship with the name %s", relName), http.StatusBadRequest)
+		}
+
+		target, err := url.Parse(link.URL)
+		if err != nil {
+			return nil, NewHTTPError(err, fmt.Sprintf("Invalid Link header %s", link.URL), http.StatusBadRequest)
+		}
+
+		dir, id := path.Split(target.Path)
+		if id == "" || path.Base(dir) != relType {
+			return nil, NewHTTPError(nil, fmt.Sprintf("Link %s does not reference %s", link.URL, relType), http.StatusBadRequest)
+		}
+
+		relationIDs[relName] = append(relationIDs[relName], id)
+	}
+
+	if len(relationIDs) == 0 {
+		return nil, NewHTTPError(nil, "Missing Link header", http.StatusBadRequest)
+	}
+
+	return relationIDs, nil
+}
+
+// editToManyRelations adds or deletes (`remove`) the ids of the to-many relationships of
+// the entry with the given id and updates it
+func (res *resource) editToManyRelations(c context.Context, r *http.Request, id string, relationIDs map[string][]string, remove bool) error {
+	var editObj interface{}
+
+	response, err := res.source.FindOne(id, BuildRequest(c, r))
+	if err != nil {
+		return err
+	}
+
 	resType := reflect.TypeOf(response.Result()).Kind()
 	if resType == reflect.Struct {
 		editObj = getPointerToStruct(response.Result())
@@ -778,7 +1891,14 @@ func (res *resource) handleAddToManyRelation(c context.Context, w http.ResponseW
 	if !ok {
 		return errors.New("target struct must implement jsonapi.EditToManyRelations")
 	}
-	targetObj.AddToManyIDs(relName, newIDs)
+
+	for relName, ids := range relationIDs {
+		if remove {
+			targetObj.DeleteToManyIDs(relName, ids)
+		} else {
+			targetObj.AddToManyIDs(relName, ids)
+		}
+	}
 
 	if resType == reflect.Struct {
 		_, err = res.source.Update(reflect.ValueOf(targetObj).Elem().Interface(), BuildRequest(c, r))
@@ -786,25 +1906,17 @@ func (res *resource) handleAddToManyRelation(c context.Context, w http.ResponseW
 		_, err = res.source.Update(targetObj, BuildRequest(c, r))
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-
 	return err
 }
 
 func (res *resource) handleDeleteToManyRelation(c context.Context, w http.ResponseWriter, r *http.Request, params func(context.Context, string) string) error {
-	var (
-		err     error
-		editObj interface{}
-	)
+	if handler, ok := res.source.(RelationshipHandler); ok {
+		return res.handleCustomRelation(c, w, r, params, handler, RelationshipDelete)
+	}
 
 	id := params(c, "id")
 	relName := c.Value(api_relation).(string)
 
-	response, err := res.source.FindOne(id, BuildRequest(c, r))
-	if err != nil {
-		return err
-	}
-
 	inc, err := unmarshalRequest(r, res.marshalers)
 	if err != nil {
 		return err
@@ -835,28 +1947,13 @@ func (res *resource) handleDeleteToManyRelation(c context.Context, w http.Respon
 		obsoleteIDs = append(obsoleteIDs, obsoleteID)
 	}
 
-	resType := reflect.TypeOf(response.Result()).Kind()
-	if resType == reflect.Struct {
-		editObj = getPointerToStruct(response.Result())
-	} else {
-		editObj = response.Result()
-	}
-
-	targetObj, ok := editObj.(jsonapi.EditToManyRelations)
-	if !ok {
-		return errors.New("target struct must implement jsonapi.EditToManyRelations")
-	}
-	targetObj.DeleteToManyIDs(relName, obsoleteIDs)
-
-	if resType == reflect.Struct {
-		_, err = res.source.Update(reflect.ValueOf(targetObj).Elem().Interface(), BuildRequest(c, r))
-	} else {
-		_, err = res.source.Update(targetObj, BuildRequest(c, r))
+	err = res.editToManyRelations(c, r, id, map[string][]string{relName: obsoleteIDs}, true)
+	if err != nil {
+		return err
 	}
 
 	w.WriteHeader(http.StatusNoContent)
-
-	return err
+	return nil
 }
 
 // returns a pointer to an interface{} struct
@@ -869,18 +1966,27 @@ func getPointerToStruct(oldObj interface{}) interface{} {
 
 func (res *resource) handleDelete(c context.Context, w http.ResponseWriter, r *http.Request, params func(context.Context, string) string) error {
 	id := params(c, "id")
-	response, err := res.source.Delete(id, BuildRequest(c, r))
+	req := BuildRequest(c, r)
+	response, err := res.source.Delete(id, req)
 	if err != nil {
 		return err
 	}
 
+	if err := res.publish(c, EventDeleted, id, nil, req); err != nil {
+		return err
+	}
+
 	switch response.StatusCode() {
 	case http.StatusOK:
+		if deleting, ok := response.(DeletingResponder); ok && deleting.ReturnsDeleted() && response.Result() != nil {
+			return RespondWith(res.maskFields(response, req), http.StatusOK, c, w, r)
+		}
+
 		data := map[string]interface{}{
 			"meta": response.Metadata(),
 		}
 
-		return marshalResponse(data, w, http.StatusOK, r, res.marshalers)
+		return marshalResponse(c, data, w, http.StatusOK, r, res.marshalers)
 	case http.StatusAccepted:
 		w.WriteHeader(http.StatusAccepted)
 		return nil
@@ -906,45 +2012,177 @@ func RespondWith(obj Responder, status int, c context.Context, w http.ResponseWr
 		return err
 	}
 
-	meta := obj.Metadata()
+	meta := map[string]interface{}{}
+	for key, value := range queryMeta(obj) {
+		meta[key] = value
+	}
+	for key, value := range obj.Metadata() {
+		meta[key] = value
+	}
 	if len(meta) > 0 {
 		data["meta"] = meta
 	}
 
-	return marshalResponse(data, w, status, r, marshalers)
+	if r != nil && r.URL != nil {
+		data["links"] = map[string]string{"self": selfLink(r, info)}
+	}
+
+	if err := mergeIncluded(data, obj, info); err != nil {
+		return err
+	}
+
+	if err := mergeEmbedded(data, obj, info); err != nil {
+		return err
+	}
+
+	return marshalResponse(c, data, w, status, r, marshalers)
+}
+
+// queryMeta returns the meta data of a QueryMetaProvider, also if the
+// Responder has been wrapped for field masking, includes or embeds
+func queryMeta(obj Responder) map[string]interface{} {
+	for {
+		switch wrapped := obj.(type) {
+		case QueryMetaProvider:
+			return wrapped.QueryMeta()
+		case maskedResponder:
+			obj = wrapped.Responder
+		case includedResponder:
+			obj = wrapped.Responder
+		case embeddedResponder:
+			obj = wrapped.Responder
+		default:
+			return nil
+		}
+	}
+}
+
+// selfLink returns the url of the request, which produced the response document,
+// with the query parameters in the same order as the pagination links
+func selfLink(r *http.Request, info Information) string {
+	// a path like //evil.com/v1/posts would result in a protocol-relative url to another host
+	link := fmt.Sprintf("%s/%s", info.GetBaseURL(), strings.TrimLeft(r.URL.Path, "/\\"))
+	if query, _ := url.QueryUnescape(r.URL.Query().Encode()); query != "" {
+		link += "?" + query
+	}
+
+	return link
 }
 
-func RespondWithPagination(obj Responder, info Information, status int, links map[string]string, w http.ResponseWriter, r *http.Request, marshalers map[string]ContentMarshaler) error {
+// RespondWithPagination marshals a paginated result with the generated pagination links.
+// The total `count` is added as `total` to the meta object, which is merged with the
+// QueryMeta and meta data of the Responder. Entries of the Responder take precedence.
+// If enabled with ExposeCountHeader, the count is also sent as `X-Total-Count` header.
+// A negative count is an error.
+func RespondWithPagination(obj Responder, info Information, status int, links map[string]string, count int64, w http.ResponseWriter, r *http.Request, marshalers map[string]ContentMarshaler) error {
+	if count < 0 {
+		return errNegativeCount
+	}
+
 	data, err := jsonapi.MarshalWithURLs(obj.Result(), info)
 	if err != nil {
 		return err
 	}
 
-	data["links"] = links
-	meta := obj.Metadata()
-	if len(meta) > 0 {
-		data["meta"] = meta
+	pageLinks := map[string]string{"self": selfLink(r, info)}
+	for key, link := range links {
+		pageLinks[key] = link
+	}
+	data["links"] = pageLinks
+	meta := map[string]interface{}{
+		"total": count,
+	}
+	for key, value := range queryMeta(obj) {
+		meta[key] = value
+	}
+	for key, value := range obj.Metadata() {
+		meta[key] = value
+	}
+	data["meta"] = meta
+
+	if err := mergeIncluded(data, obj, info); err != nil {
+		return err
+	}
+
+	if err := mergeEmbedded(data, obj, info); err != nil {
+		return err
 	}
 
-	return marshalResponse(data, w, status, r, marshalers)
+	if api, ok := r.Context().Value(api_api).(*API); ok && api.exposeCountHeader {
+		w.Header().Set(headerTotalCount, strconv.FormatInt(count, 10))
+		w.Header().Add("Access-Control-Expose-Headers", headerTotalCount)
+	}
+
+	return marshalResponse(r.Context(), data, w, status, r, marshalers)
 }
 
 func unmarshalRequest(r *http.Request, marshalers map[string]ContentMarshaler) (map[string]interface{}, error) {
-	defer r.Body.Close()
-	data, err := ioutil.ReadAll(r.Body)
+	if !supportedContentType(r, marshalers) {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		return nil, NewHTTPError(nil, fmt.Sprintf("Content-Type %s is not supported", r.Header.Get("Content-Type")), http.StatusUnsupportedMediaType)
+	}
+
+	// the body is cached, so that it can be read again with Request.Body
+	data, err := readBody(r)
 	if err != nil {
 		return nil, err
 	}
 	result := map[string]interface{}{}
 	marshaler, _ := selectContentMarshaler(r, marshalers)
-	err = marshaler.Unmarshal(data, &result)
+	if _, ok := marshaler.(JSONContentMarshaler); ok {
+		err = decodeJSONWithNumbers(data, &result)
+	} else {
+		err = marshaler.Unmarshal(data, &result)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-func marshalResponse(resp interface{}, w http.ResponseWriter, status int, r *http.Request, marshalers map[string]ContentMarshaler) error {
+// newUnmarshalError returns a 400 error for a request body that can not be unmarshaled
+// into the resource, e.g. because of an unknown relationship. HTTPErrors of the resource
+// are returned unchanged.
+func newUnmarshalError(err error) error {
+	if _, ok := err.(HTTPError); ok {
+		return err
+	}
+
+	return NewHTTPError(err, err.Error(), http.StatusBadRequest)
+}
+
+// decodeJSONWithNumbers unmarshals like json.Unmarshal, but keeps numbers as json.Number,
+// so that large integers do not lose precision before they are set into the target struct
+func decodeJSONWithNumbers(data []byte, target interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(target); err != nil {
+		return err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("invalid data after top-level value")
+	}
+
+	return nil
+}
+
+// marshalResponse marshals `resp` with the marshaler matching the request and writes it.
+// Nothing is written if `c` has been canceled in the meantime, e.g. because the client
+// disconnected or the deadline of the request has passed; the context error is returned instead.
+// HandleError does not write it either if the client disconnected, deadlines are answered
+// with an error document.
+func marshalResponse(c context.Context, resp interface{}, w http.ResponseWriter, status int, r *http.Request, marshalers map[string]ContentMarshaler) error {
+	if err := c.Err(); err != nil {
+		return err
+	}
+
+	status = withoutPartialData(resp, status)
+
+	addDescribedBy(c, resp)
+
 	marshaler, contentType := selectContentMarshaler(r, marshalers)
 	filtered, err := filterSparseFields(resp, r)
 	if err != nil {
@@ -954,10 +2192,64 @@ func marshalResponse(resp interface{}, w http.ResponseWriter, status int, r *htt
 	if err != nil {
 		return err
 	}
+
+	if err := c.Err(); err != nil {
+		return err
+	}
+
 	writeResult(w, result, status, contentType)
 	return nil
 }
 
+// withoutPartialData removes `data` and `included` from documents that contain `errors`,
+// because jsonapi does not allow them to coexist. It returns the status for the document,
+// which is at least 400 if there are errors.
+func withoutPartialData(resp interface{}, status int) int {
+	document, ok := resp.(map[string]interface{})
+	if !ok {
+		return status
+	}
+
+	if _, ok := document["errors"]; !ok {
+		return status
+	}
+
+	delete(document, "data")
+	delete(document, "included")
+
+	if status < http.StatusBadRequest {
+		return http.StatusBadRequest
+	}
+
+	return status
+}
+
+// addDescribedBy adds the schema url of the resource or the api as `links.describedby`
+// to a response document
+func addDescribedBy(c context.Context, resp interface{}) {
+	document, ok := resp.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	schemaURL, _ := c.Value(api_schema).(string)
+	if api, ok := c.Value(api_api).(*API); ok && schemaURL == "" {
+		schemaURL = api.schemaURL
+	}
+	if schemaURL == "" {
+		return
+	}
+
+	switch links := document["links"].(type) {
+	case map[string]string:
+		links["describedby"] = schemaURL
+	case map[string]interface{}:
+		links["describedby"] = schemaURL
+	case nil:
+		document["links"] = map[string]string{"describedby": schemaURL}
+	}
+}
+
 func filterSparseFields(resp interface{}, r *http.Request) (interface{}, error) {
 	query := r.URL.Query()
 	queryParams := parseQueryFields(&query)
@@ -965,12 +2257,22 @@ func filterSparseFields(resp interface{}, r *http.Request) (interface{}, error)
 		return resp, nil
 	}
 
+	// the valid fields of registered resources, other types are validated by their attributes
+	validFields := map[string]map[string]struct{}{}
+	if api, ok := r.Context().Value(api_api).(*API); ok {
+		for _, res := range api.resources {
+			if _, ok := queryParams[res.name]; ok {
+				validFields[res.name] = res.validFields
+			}
+		}
+	}
+
 	if content, ok := resp.(map[string]interface{}); ok {
 		wrongFields := map[string][]string{}
 
 		// single entry in data
 		if data, ok := content["data"].(map[string]interface{}); ok {
-			errors := replaceAttributes(&queryParams, &data)
+			errors := replaceAttributes(&queryParams, &data, validFields)
 			for t, v := range errors {
 				wrongFields[t] = v
 			}
@@ -979,7 +2281,23 @@ func filterSparseFields(resp interface{}, r *http.Request) (interface{}, error)
 		// data can be a slice too
 		if datas, ok := content["data"].([]map[string]interface{}); ok {
 			for index, data := range datas {
-				errors := replaceAttributes(&queryParams, &data)
+				errors := replaceAttributes(&queryParams, &data, validFields)
+				for t, v := range errors {
+					wrongFields[t] = v
+				}
+				datas[index] = data
+			}
+		}
+
+		// or a slice of interfaces, if the document was not built by jsonapi.MarshalWithURLs
+		if datas, ok := content["data"].([]interface{}); ok {
+			for index, element := range datas {
+				data, ok := element.(map[string]interface{})
+				if !ok {
+					continue
+				}
+
+				errors := replaceAttributes(&queryParams, &data, validFields)
 				for t, v := range errors {
 					wrongFields[t] = v
 				}
@@ -990,7 +2308,7 @@ func filterSparseFields(resp interface{}, r *http.Request) (interface{}, error)
 		// included slice
 		if included, ok := content["included"].([]map[string]interface{}); ok {
 			for index, include := range included {
-				errors := replaceAttributes(&queryParams, &include)
+				errors := replaceAttributes(&queryParams, &include, validFields)
 				for t, v := range errors {
 					wrongFields[t] = v
 				}
@@ -1032,28 +2350,115 @@ func parseQueryFields(query *url.Values) (result map[string][]string) {
 	return
 }
 
-func filterAttributes(attributes map[string]interface{}, fields []string) (filteredAttributes map[string]interface{}, wrongFields []string) {
+// filterAttributes keeps the requested fields of the attributes. Fields that are not part
+// of the attributes are wrong, unless they are contained in validFields, e.g. zero dates.
+// A field can be a dot-separated path into a nested object, e.g. `address.city`.
+func filterAttributes(attributes map[string]interface{}, fields []string, validFields map[string]struct{}) (filteredAttributes map[string]interface{}, wrongFields []string) {
 	wrongFields = []string{}
-	filteredAttributes = map[string]interface{}{}
+	requested := fieldTree{}
 
 	for _, field := range fields {
-		if attribute, ok := attributes[field]; ok {
-			filteredAttributes[field] = attribute
-		} else {
+		if _, ok := attributes[field]; ok {
+			requested.add([]string{field})
+			continue
+		}
+
+		path := strings.Split(field, ".")
+		if len(path) > 1 && hasNestedAttribute(attributes, path) {
+			requested.add(path)
+			continue
+		}
+
+		// only paths into attributes that are missing, e.g. zero dates, can be valid
+		if _, ok := attributes[path[0]]; ok {
+			wrongFields = append(wrongFields, field)
+		} else if _, ok := validFields[path[0]]; !ok {
 			wrongFields = append(wrongFields, field)
 		}
 	}
 
+	filteredAttributes = requested.filter(attributes)
 	return
 }
 
-func replaceAttributes(query *map[string][]string, entry *map[string]interface{}) map[string][]string {
+// fieldTree contains the requested paths of sparse fieldsets. A nil subtree means that
+// the whole attribute has been requested.
+type fieldTree map[string]fieldTree
+
+func (t fieldTree) add(path []string) {
+	subtree, ok := t[path[0]]
+	if ok && subtree == nil {
+		return
+	}
+
+	if len(path) == 1 {
+		t[path[0]] = nil
+		return
+	}
+
+	if !ok {
+		subtree = fieldTree{}
+		t[path[0]] = subtree
+	}
+	subtree.add(path[1:])
+}
+
+func (t fieldTree) filter(attributes map[string]interface{}) map[string]interface{} {
+	filtered := map[string]interface{}{}
+	for name, subtree := range t {
+		if subtree == nil {
+			filtered[name] = attributes[name]
+			continue
+		}
+
+		nested, _ := nestedAttributes(attributes[name])
+		filtered[name] = subtree.filter(nested)
+	}
+
+	return filtered
+}
+
+// hasNestedAttribute checks if a dot-separated path exists in the attributes
+func hasNestedAttribute(attributes map[string]interface{}, path []string) bool {
+	for _, name := range path[:len(path)-1] {
+		nested, ok := nestedAttributes(attributes[name])
+		if !ok {
+			return false
+		}
+		attributes = nested
+	}
+
+	_, ok := attributes[path[len(path)-1]]
+	return ok
+}
+
+// nestedAttributes returns the members of an attribute that is an object. Structs are
+// converted with their json representation, just like they are marshaled.
+func nestedAttributes(attribute interface{}) (map[string]interface{}, bool) {
+	if nested, ok := attribute.(map[string]interface{}); ok {
+		return nested, true
+	}
+
+	data, err := json.Marshal(attribute)
+	if err != nil {
+		return nil, false
+	}
+
+	var nested map[string]interface{}
+	if err := decodeJSONWithNumbers(data, &nested); err != nil || nested == nil {
+		return nil, false
+	}
+
+	return nested, true
+}
+
+func replaceAttributes(query *map[string][]string, entry *map[string]interface{}, validFields map[string]map[string]struct{}) map[string][]string {
 	fieldType := (*entry)["type"].(string)
 	fields := (*query)[fieldType]
 	if len(fields) > 0 {
 		if attributes, ok := (*entry)["attributes"]; ok {
 			var wrongFields []string
-			(*entry)["attributes"], wrongFields = filterAttributes(attributes.(map[string]interface{}), fields)
+			(*entry)["attributes"], wrongFields = filterAttributes(attributes.(map[string]interface{}), fields, validFields[fieldType])
 			if len(wrongFields) > 0 {
 				return map[string][]string{
 					fieldType: wrongFields,
@@ -1081,17 +2486,73 @@ func selectContentMarshaler(r *http.Request, marshalers map[string]ContentMarsha
 
 	if marshaler == nil {
 		contentType = defaultContentTypeHeader
+		marshaler = marshalers[contentType]
+	}
+
+	if marshaler == nil {
 		marshaler = JSONContentMarshaler{}
 	}
 
 	return
 }
 
+// supportedContentType checks if the body of a request can be unmarshaled by one of
+// the marshalers and if its extensions and profiles are supported.
+// Requests without Content-Type are accepted for backwards compatibility.
+func supportedContentType(r *http.Request, marshalers map[string]ContentMarshaler) bool {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, mediaParams, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	// all requested extensions and profiles must be registered with AddProfile
+	api, _ := r.Context().Value(api_api).(*API)
+	for _, param := range []string{"ext", "profile"} {
+		for _, uri := range strings.Fields(mediaParams[param]) {
+			if api == nil || !api.profiles[uri] {
+				return false
+			}
+		}
+	}
+
+	for key := range marshalers {
+		if key == contentType {
+			return true
+		}
+
+		if supported, _, err := mime.ParseMediaType(key); err == nil && supported == mediaType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HandleError writes `err` as jsonapi error document. HTTPErrors are answered with their
+// status code, all other errors with 500. Nothing is written if the client canceled the
+// request, because there is no one left to receive the response.
 func HandleError(err error, w http.ResponseWriter, r *http.Request, marshalers map[string]ContentMarshaler) {
 	marshaler, contentType := selectContentMarshaler(r, marshalers)
 
-	log.Println(err)
+	if recorder := findLoggingResponseWriter(w); recorder != nil {
+		recorder.err = err
+	} else {
+		log.Println(err)
+	}
+
+	if r.Context().Err() == context.Canceled && errors.Is(err, context.Canceled) {
+		return
+	}
+
 	if e, ok := err.(HTTPError); ok {
+		if e.RetryAfter > 0 {
+			w.Header().Set("Retry-After", e.retryAfterSeconds())
+		}
 		writeResult(w, []byte(marshaler.MarshalError(err)), e.status, contentType)
 		return
 
@@ -5,6 +5,9 @@ import (
 	. "github.com/onsi/gomega"
 	"io/ioutil"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 
 	"testing"
 )
@@ -14,3 +17,29 @@ func TestApi2go(t *testing.T) {
 	log.SetOutput(ioutil.Discard)
 	RunSpecs(t, "Api2go Suite")
 }
+
+// newPostAPI returns an api with the prefix v1 that serves the posts of `source`
+func newPostAPI(source CRUD, options ...ResourceOption) *API {
+	api := NewAPI("v1")
+	api.AddResource(Post{}, source, options...)
+	return api
+}
+
+// newTestRequest returns a request with `body`, headers can be set before it is sent with serve
+func newTestRequest(method, URL, body string) *http.Request {
+	req, err := http.NewRequest(method, URL, strings.NewReader(body))
+	Expect(err).ToNot(HaveOccurred())
+	return req
+}
+
+// serve passes the request to the handler of `api` and returns the recorded response
+func serve(api *API, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	api.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+// sendRequest sends a request with `body` to `api` and returns the recorded response
+func sendRequest(api *API, method, URL, body string) *httptest.ResponseRecorder {
+	return serve(api, newTestRequest(method, URL, body))
+}
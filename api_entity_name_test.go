@@ -124,6 +124,9 @@ var _ = Describe("Test route renaming with EntityNamer interface", func() {
 		// check the ID here and returns something new ...
 		Expect(rec.Body.String()).To(MatchJSON(`
 		{
+			"links": {
+				"self": "/v1/baguette-tastes"
+			},
 			"data": {
 				"attributes": {
 					"taste": "smells awful"
@@ -1,6 +1,9 @@
 package api2go
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 // The CRUD interface MUST be implemented in order to use the api2go api.
 // Use Responder for success status codes and content/meta data. In case of an error,
@@ -49,8 +52,9 @@ type ContentMarshaler interface {
 // be generated by the api. You can use a combination of the following 2 query parameters:
 // page[number] AND page[size]
 // OR page[offset] AND page[limit]
+// The total count is an int64 like the counts of most databases, a negative count is an error.
 type PaginatedFindAll interface {
-	PaginatedFindAll(req Request) (totalCount uint, response Responder, err error)
+	PaginatedFindAll(req Request) (totalCount int64, response Responder, err error)
 }
 
 // The FindAll interface can be optionally implemented to fetch all records at once.
@@ -59,6 +63,123 @@ type FindAll interface {
 	FindAll(req Request) (Responder, error)
 }
 
+// RelationshipUpdate contains the new related IDs of one resource
+// for a bulk relationship update. For to-one relationships NewIDs
+// contains at most one ID, an empty slice removes the relationship.
+type RelationshipUpdate struct {
+	ResourceID string
+	NewIDs     []string
+}
+
+// The BulkRelationshipPatcher interface can be optionally implemented to replace a
+// relationship of multiple resources in one request via PATCH /resource/relationships/:name
+type BulkRelationshipPatcher interface {
+	PatchRelationships(relName string, updates []RelationshipUpdate, req Request) error
+}
+
+// The Searchable interface can be optionally implemented to support full-text search
+// via the `filter[q]` query parameter. If the parameter is not empty, Search will be
+// called instead of FindAll and PaginatedFindAll. Page parameters are not applied by
+// api2go then, use Request.GetPaginationParams to page the search results.
+type Searchable interface {
+	Search(query string, req Request) (Responder, error)
+}
+
+// The Filterable interface can be optionally implemented to support compound filters.
+// If the request contains `filter` query parameters, FilterAll will be called with
+// the parsed filter tree instead of FindAll. Search takes precedence over FilterAll.
+type Filterable interface {
+	FilterAll(filter Filter, req Request) (Responder, error)
+}
+
+// The GeoFilterable interface can be optionally implemented to support geographic filters
+// via `filter[geo][within]=lat,lng,radius` or `filter[geo][near]=lat,lng[,radius]`. If one
+// of them is given, FindAllWithGeo will be called with the parsed Request.GeoFilter
+// instead of FindAll. Search takes precedence over FindAllWithGeo.
+type GeoFilterable interface {
+	FindAllWithGeo(req Request) (Responder, error)
+}
+
+// The Projectable interface can be optionally implemented to select only the requested
+// attributes in the storage layer. If the request contains sparse fieldsets, e.g.
+// `fields[posts]=title`, FindAllWithProjection will be called with the requested fields
+// of every type instead of FindAll. The response is filtered by the sparse fieldsets
+// afterwards as usual. Search, FindAllWithGeo and FilterAll take precedence over it.
+type Projectable interface {
+	FindAllWithProjection(fields map[string][]string, req Request) (Responder, error)
+}
+
+// The RelationshipIncluder interface can be optionally implemented to return the complete
+// related resources from `GET /<resource>/<id>/relationships/<relation>`. `obj` is the
+// result of FindOne, the returned objects are added as `included` to the linkage data.
+type RelationshipIncluder interface {
+	IncludeRelationship(relation string, obj interface{}, req Request) (Responder, error)
+}
+
+// The IncludeProvider interface can be optionally implemented to load entries of a resource
+// by id, when they are requested with the `include` query parameter of another resource,
+// e.g. `GET /posts?include=author`. The returned objects are added to `included`, entries
+// that are already part of the response are skipped. `req` is the request of the other resource.
+// The Metadata of the returned Responder is added as `meta` to each of the included objects.
+type IncludeProvider interface {
+	FindIncluded(ids []string, req Request) (Responder, error)
+}
+
+// The EmbedProvider interface can be optionally implemented to load entries of a resource
+// by id, when they are requested with the `embed` query parameter of another resource, e.g.
+// `GET /posts?embed=author`. Instead of being added to `included`, the returned objects are
+// inlined into the attributes of the resources that reference them. Embedding must be
+// enabled with api.EnableEmbedding.
+type EmbedProvider interface {
+	FindEmbedded(ids []string, req Request) (Responder, error)
+}
+
+// The Initializer interface can be optionally implemented by a source that needs to be
+// set up before requests are served, e.g. to warm up a connection pool. Initialize is
+// called by api.WarmUp.
+type Initializer interface {
+	Initialize(ctx context.Context) error
+}
+
+// The SchemaURLProvider interface can be optionally implemented by a source to add
+// `links.describedby` with the url of its schema document to the responses of its
+// resource. It takes precedence over the url set with api.SetSchemaURL.
+type SchemaURLProvider interface {
+	SchemaURL() string
+}
+
+// The ResourceMiddleware interface can be optionally implemented by a source to wrap
+// the handlers of its own resource, e.g. to pick a tenant specific database connection.
+// The middleware is called after the middlewares added with WithMiddleware and only for
+// the routes of this resource.
+type ResourceMiddleware interface {
+	Middleware() func(http.Handler) http.Handler
+}
+
+// The AfterFetch interface can be optionally implemented by a source to transform the
+// objects returned by FindAll, PaginatedFindAll, Search, FindAllWithGeo and FilterAll,
+// e.g. to remove or decorate entries, before the response is built. The returned objects
+// are responded with instead. It is called before the fields are masked by FieldMasker.
+type AfterFetch interface {
+	AfterFetch(objs []interface{}, req Request) ([]interface{}, error)
+}
+
+// The FieldMasker interface can be optionally implemented to hide fields depending on
+// the request, e.g. the role of a user. MaskFields is called for every object that is
+// returned by FindOne, FindAll, PaginatedFindAll and Search before it is marshaled.
+// `obj` is a struct or a pointer, just as returned by the source, and the returned
+// object is marshaled instead.
+type FieldMasker interface {
+	MaskFields(obj interface{}, req Request) interface{}
+}
+
+// The Authorizer interface can be passed to WithAuthorizer to restrict access to a resource.
+// Authorize is called before every request to the resource, the request is aborted if
+// an error is returned. Errors that are not an HTTPError result in 403 Forbidden.
+type Authorizer interface {
+	Authorize(r *http.Request) error
+}
+
 //URLResolver allows you to implement a static
 //way to return a baseURL for all incoming
 //requests for one api2go instance.
@@ -82,6 +203,21 @@ type RequestAwareURLResolver interface {
 	SetRequest(http.Request)
 }
 
+// TenantAwareResolver allows you to replace the complete
+// Information of a request, not only the base url.
+//
+// This is useful for multi-tenant setups where the base url
+// and the prefix differ per tenant, e.g. depending on the
+// host or a header of the request.
+//
+// ResolveInformation is called for every request with the
+// Information api2go would use otherwise. Use NewInformation
+// to construct the replacement.
+type TenantAwareResolver interface {
+	URLResolver
+	ResolveInformation(r http.Request, info Information) Information
+}
+
 // The Responder interface is used by all Resource Methods as a container for the Response.
 // Metadata is additional Metadata. You can put anything you like into it, see jsonapi spec.
 // Result returns the actual payload. For FindOne, put only one entry in it.
@@ -91,3 +227,26 @@ type Responder interface {
 	Result() interface{}
 	StatusCode() int
 }
+
+// The AsyncResponder interface can be optionally implemented by the Responder of Create,
+// if the object is created asynchronously and the status code is 202 Accepted. The
+// Location header is set to PollingURL, where the client can poll for the result.
+// Alternatively, the URL can be returned as `polling_url` in Metadata.
+type AsyncResponder interface {
+	PollingURL() string
+}
+
+// The DeletingResponder interface can be optionally implemented by the Responder of Delete
+// to respond with the deleted resource, e.g. to allow clients to undo the deletion. If
+// ReturnsDeleted is true and the status code is 200, Result is responded as primary data
+// together with Metadata. Otherwise only Metadata is responded for 200.
+type DeletingResponder interface {
+	ReturnsDeleted() bool
+}
+
+// The QueryMetaProvider interface can be optionally implemented by a Responder to add
+// meta data about the query, e.g. `query_time_ms` for monitoring. It is merged into the
+// top-level meta object, entries of Metadata take precedence.
+type QueryMetaProvider interface {
+	QueryMeta() map[string]interface{}
+}
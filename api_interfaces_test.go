@@ -27,6 +27,15 @@ func (s *SomeData) SetID(ID string) error {
 	return nil
 }
 
+// deletedResponse responds with the deleted resource
+type deletedResponse struct {
+	Response
+}
+
+func (r deletedResponse) ReturnsDeleted() bool {
+	return true
+}
+
 type SomeResource struct{}
 
 func (s SomeResource) FindOne(ID string, req Request) (Responder, error) {
@@ -57,6 +66,10 @@ func (s SomeResource) Delete(ID string, req Request) (Responder, error) {
 		return &Response{Code: http.StatusOK, Meta: map[string]interface{}{"some": "cool stuff"}}, nil
 	case "202":
 		return &Response{Code: http.StatusAccepted}, nil
+	case "restorable":
+		return &deletedResponse{Response{Code: http.StatusOK, Res: SomeData{ID: ID, Data: "A Brezzn"}, Meta: map[string]interface{}{"restorable": true}}}, nil
+	case "result":
+		return &Response{Code: http.StatusOK, Res: SomeData{ID: ID, Data: "A Brezzn"}}, nil
 	default:
 		return &Response{Code: http.StatusNoContent}, nil
 	}
@@ -257,6 +270,26 @@ var _ = Describe("Test return code behavior", func() {
 			}))
 		})
 
+		It("returns the deleted resource of a DeletingResponder", func() {
+			delete("restorable")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{
+				"links": {"self": "/v1/someDatas/restorable"},
+				"data": {
+					"type": "someDatas",
+					"id": "restorable",
+					"attributes": {"data": "A Brezzn", "customerId": ""}
+				},
+				"meta": {"restorable": true}
+			}`))
+		})
+
+		It("does not return the result of other responders", func() {
+			delete("result")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).ToNot(ContainSubstring("data"))
+		})
+
 		It("returns 202 accepted if deletion is delayed", func() {
 			delete("202")
 			Expect(rec.Code).To(Equal(http.StatusAccepted))
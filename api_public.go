@@ -2,8 +2,13 @@ package api2go
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/manyminds/api2go/jsonapi"
 	"github.com/manyminds/api2go/routing"
@@ -18,25 +23,82 @@ var DefaultContentMarshalers = map[string]ContentMarshaler{
 
 // API is a REST JSONAPI.
 type API struct {
-	router      routing.Routeable
-	info        Information
-	resources   []resource
+	router    routing.Routeable
+	info      Information
+	resources []*resource
+	// routes are registered without a resource, e.g. by AddPing, and are kept by ClearResources
+	routes      []resourceRoute
 	marshalers  map[string]ContentMarshaler
 	middlewares routing.Chain
+	state       *serverState
 	Context     context.Context
+	// TrustedProxies contains the networks of the proxies whose `X-Forwarded-For`
+	// header is used by Request.ClientIP
+	TrustedProxies []net.IPNet
+
+	exposeCountHeader bool
+	profiles          map[string]bool
+	requestLogger     RequestLogger
+	eventBus          EventBus
+	defaultHeaders    http.Header
+	schemaURL         string
+	// sourceHealthChecks are the sources added with WireSourceHealthChecks
+	sourceHealthChecks []HealthChecker
+	// disableSecurityHeaders omits the defaultSecurityHeaders
+	disableSecurityHeaders bool
+	// embedding enables the `embed` query parameter
+	embedding bool
+	// panicHandler answers requests whose resource handler panicked, see SetPanicHandler
+	panicHandler func(recovered interface{}, w http.ResponseWriter, r *http.Request)
+	// handler holds the servedHandler of ServeHTTP, it is reset when the middlewares or the router change
+	handler atomic.Value
+}
+
+// servedHandler is stored in API.handler, because an atomic.Value can not store nil
+type servedHandler struct {
+	http.Handler
+}
+
+// defaultSecurityHeaders are set on every response, unless DisableDefaultSecurityHeaders is called
+var defaultSecurityHeaders = http.Header{
+	"X-Content-Type-Options": {"nosniff"},
+	"X-Frame-Options":        {"DENY"},
+	"X-Xss-Protection":       {"1; mode=block"},
 }
 
-func (api API) SetRouter(router routing.Routeable) {
+// SetRouter replaces the router of the API. It must be called before any resources
+// are added, because their routes are registered when they are added.
+func (api *API) SetRouter(router routing.Routeable) {
 	api.router = router
+	api.resetHandler()
 }
 
 // Handler returns the http.Handler instance for the API.
-func (api API) Handler() http.Handler {
+func (api *API) Handler() http.Handler {
 	return api.middlewares.Handler(api.router.Handler())
 }
 
-//Router returns the specified router on an api instance
-func (api API) Router() routing.Routeable {
+// ServeHTTP implements http.Handler, so that the API can be passed to e.g.
+// http.ListenAndServe directly. It serves the request with Handler, which is built
+// once and built again after UseMiddleware or SetRouter.
+func (api *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler, _ := api.handler.Load().(servedHandler)
+	if handler.Handler == nil {
+		handler = servedHandler{api.Handler()}
+		api.handler.Store(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// resetHandler makes ServeHTTP build the handler again
+func (api *API) resetHandler() {
+	api.handler.Store(servedHandler{})
+}
+
+// Router returns the specified router on an api instance. Routes that are added
+// with its Handle method are served by Handler, after the middlewares of the API.
+func (api *API) Router() routing.Routeable {
 	return api.router
 }
 
@@ -44,14 +106,352 @@ func (api API) Router() routing.Routeable {
 // At least the CRUD interface must be implemented, all the other interfaces are optional.
 // `resource` should be either an empty struct instance such as `Post{}` or a pointer to
 // a struct such as `&Post{}`. The same type will be used for constructing new elements.
-func (api *API) AddResource(prototype jsonapi.MarshalIdentifier, source CRUD) {
-	api.addResource(prototype, source, api.marshalers)
+// The resource can be configured with options such as WithName or WithMiddleware.
+func (api *API) AddResource(prototype jsonapi.MarshalIdentifier, source CRUD, options ...ResourceOption) {
+	api.addResource(prototype, source, api.marshalers, options...)
+}
+
+// AddSubResource registers a data source for a resource that is nested below another
+// resource. All routes of `child` are registered below `/<parent>/:parentID/<child>`, e.g.
+// `POST /users/1/articles`. The id of the parent is available as `parentID` in Request.PathParams.
+func (api *API) AddSubResource(parent jsonapi.MarshalIdentifier, child jsonapi.MarshalIdentifier, source CRUD, options ...ResourceOption) {
+	parentName := resourceName(parent)
+	options = append([]ResourceOption{func(res *resource) {
+		res.parent = parentName
+	}}, options...)
+	api.addResource(child, source, api.marshalers, options...)
+}
+
+// AddVersionedResource registers a data source for a version of a resource. All routes
+// are registered below the api prefix and `version`, e.g. `GET /api/v2/articles`, so the
+// same resource can be added once per version with different sources. Generated links
+// point to the routes of the same version.
+func (api *API) AddVersionedResource(version string, prototype jsonapi.MarshalIdentifier, source CRUD, options ...ResourceOption) {
+	version = strings.Trim(version, "/")
+	options = append([]ResourceOption{func(res *resource) {
+		res.version = version
+	}}, options...)
+	api.addResource(prototype, source, api.marshalers, options...)
+}
+
+// EnableStrictQueryParams rejects all requests with a 400 error if they contain
+// query parameters other than filter, sort, page, include and fields, or embed if
+// embedding is enabled.
+func (api *API) EnableStrictQueryParams() {
+	api.UseMiddleware(strictQueryParams(api.marshalers))
+}
+
+// AddProfile registers the URI of a JSON:API extension or profile that is supported
+// by the API. Requests with a Content-Type whose `ext` or `profile` parameter contains
+// any other URI are rejected with 415 Unsupported Media Type.
+func (api *API) AddProfile(uri string) {
+	if api.profiles == nil {
+		api.profiles = map[string]bool{}
+	}
+
+	api.profiles[uri] = true
+}
+
+// SetDefaultHeader sets a header on every response of the API, e.g. a
+// `Content-Security-Policy`. Default headers take precedence over the default security
+// headers, and handlers can overwrite default headers.
+func (api *API) SetDefaultHeader(key, value string) {
+	if api.defaultHeaders == nil {
+		api.defaultHeaders = http.Header{}
+	}
+
+	api.defaultHeaders.Set(key, value)
+}
+
+// SetSchemaURL adds `links.describedby` with the url of a schema document, e.g. an
+// OpenAPI specification, to every response document of the API. Sources can use a
+// different url for their resource by implementing SchemaURLProvider.
+func (api *API) SetSchemaURL(url string) {
+	api.schemaURL = url
+}
+
+// DisableDefaultSecurityHeaders stops adding the security headers that are set on every
+// response by default: `X-Content-Type-Options: nosniff`, `X-Frame-Options: DENY` and
+// `X-XSS-Protection: 1; mode=block`. Use this if they are already set by a proxy.
+func (api *API) DisableDefaultSecurityHeaders() {
+	api.disableSecurityHeaders = true
+}
+
+// EnablePrettyPrint replaces all JSONContentMarshalers of the API with one that
+// indents the output, which is easier to read during development.
+func (api *API) EnablePrettyPrint() {
+	for contentType, marshaler := range api.marshalers {
+		if _, ok := marshaler.(JSONContentMarshaler); ok {
+			api.marshalers[contentType] = JSONContentMarshaler{Indent: true}
+		}
+	}
+}
+
+// ExposeCountHeader enables the `X-Total-Count` header on paginated responses.
+// The header contains the same value as `meta.total` and is added to
+// `Access-Control-Expose-Headers`, so that browser clients can read it.
+func (api *API) ExposeCountHeader(enabled bool) {
+	api.exposeCountHeader = enabled
+}
+
+// Source returns the data source that has been registered for the resource
+// with the given name, or false if there is no such resource. Resources added
+// with AddExternalResource have no source and are skipped.
+func (api *API) Source(name string) (CRUD, bool) {
+	for _, res := range api.resources {
+		if res.name == name && !res.external() {
+			return res.source, true
+		}
+	}
+
+	return nil, false
+}
+
+// ResourceCount returns the number of registered resources, including sub resources.
+func (api *API) ResourceCount() int {
+	return len(api.resources)
+}
+
+// HasResource returns true if a resource with the given name has been registered.
+func (api *API) HasResource(name string) bool {
+	for _, res := range api.resources {
+		if res.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WarmUp calls Initialize of every registered source that implements Initializer,
+// in the order the resources have been added, and returns the first error.
+// It should be called once after all resources have been added, before serving requests.
+func (api *API) WarmUp(ctx context.Context) error {
+	for _, res := range api.resources {
+		initializer, ok := res.source.(Initializer)
+		if !ok {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := initializer.Initialize(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetTypeRegistry lets the resource with the given name create instances of the
+// types in `registry`, depending on the `type` of the object in a POST request.
+// Objects with a type that is not registered are handled as before.
+// It panics if no resource with this name has been registered.
+func (api *API) SetTypeRegistry(name string, registry *TypeRegistry) {
+	for _, res := range api.resources {
+		if res.name == name {
+			res.types = registry
+			return
+		}
+	}
+
+	panic("there is no resource with the name " + name)
+}
+
+// DeprecateResource marks all resources with the given name as deprecated, e.g. every
+// version of it. Every response of these resources will contain the header
+// `Deprecation: true`, the `Sunset` header if `sunsetDate` is not the zero time, as well
+// as a `Link` header with rel="deprecation" if `link` is not empty. See RFC 8594 for more
+// information. It panics if no resource with this name has been registered.
+func (api *API) DeprecateResource(name string, sunsetDate time.Time, link string) {
+	api.DeprecateResourceSince(name, time.Time{}, sunsetDate, link)
+}
+
+// DeprecateResourceSince works like DeprecateResource, but sends the date `deprecatedAt`
+// in the `Deprecation` header. If it is the zero time, `Deprecation: true` is sent.
+func (api *API) DeprecateResourceSince(name string, deprecatedAt, sunsetDate time.Time, link string) {
+	deprecation := "true"
+	if !deprecatedAt.IsZero() {
+		deprecation = deprecatedAt.UTC().Format(http.TimeFormat)
+	}
+
+	found := false
+	for _, res := range api.resources {
+		if res.name != name {
+			continue
+		}
+		found = true
+
+		res.deprecation = http.Header{}
+		res.deprecation.Set("Deprecation", deprecation)
+		if !sunsetDate.IsZero() {
+			res.deprecation.Set("Sunset", sunsetDate.UTC().Format(http.TimeFormat))
+		}
+		if link != "" {
+			res.deprecation.Set("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, link))
+		}
+	}
+
+	if !found {
+		panic("there is no resource with the name " + name)
+	}
+}
+
+// DeprecateAndRemoveResource retires all resources with the given name, e.g. every version
+// of it. All of their routes answer with `410 Gone` afterwards, and a `Link` header with
+// rel="successor-version" if `replacementURL` is not empty, which replaces the one of a previous
+// call. The sources of the resources are not called anymore.
+// It panics if no resource with this name has been registered.
+func (api *API) DeprecateAndRemoveResource(name string, replacementURL string) {
+	detail := fmt.Sprintf("The resource %s has been removed", name)
+	if replacementURL != "" {
+		detail += ", use " + replacementURL + " instead"
+	}
+
+	found := false
+	for _, res := range api.resources {
+		if res.name != name {
+			continue
+		}
+		found = true
+
+		if res.deprecation != nil {
+			links := []string{}
+			for _, link := range res.deprecation.Values("Link") {
+				if !strings.HasSuffix(link, `rel="successor-version"`) {
+					links = append(links, link)
+				}
+			}
+			res.deprecation.Del("Link")
+			for _, link := range links {
+				res.deprecation.Add("Link", link)
+			}
+		}
+
+		if replacementURL != "" {
+			if res.deprecation == nil {
+				res.deprecation = http.Header{}
+			}
+			res.deprecation.Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, replacementURL))
+		}
+
+		removal := NewHTTPError(nil, "Gone", http.StatusGone)
+		removal.Errors = []Error{{
+			Status: strconv.Itoa(http.StatusGone),
+			Title:  "Gone",
+			Detail: detail,
+		}}
+		res.removal = &removal
+	}
+
+	if !found {
+		panic("there is no resource with the name " + name)
+	}
+}
+
+// MountAt registers the API handler below `pattern` of a http.ServeMux. The pattern
+// must end with the prefix of the API, everything in front of the prefix is stripped
+// from the request path. For example an API with the prefix `v1` that is mounted
+// at `/api/v1/` handles `/api/v1/posts` as `/v1/posts`. It panics if the pattern
+// does not end with the prefix.
+func (api *API) MountAt(mux *http.ServeMux, pattern string) {
+	pattern = strings.TrimSuffix(pattern, "/")
+	strip := pattern
+
+	prefix := strings.Trim(api.info.prefix, "/")
+	if prefix != "" {
+		if !strings.HasSuffix(pattern, "/"+prefix) {
+			panic(fmt.Sprintf("pattern %s does not end with the api prefix %s", pattern, prefix))
+		}
+
+		strip = strings.TrimSuffix(pattern, "/"+prefix)
+	}
+
+	handler := api.Handler()
+	if strip != "" {
+		handler = http.StripPrefix(strip, handler)
+	}
+
+	mux.Handle(pattern+"/", handler)
+}
+
+// SetBasePath changes the prefix of the API after construction. Generated links use
+// the new prefix and the routes of all resources and of AddPing are registered below it.
+// Routes below the old prefix can not be removed from the router, they are still answered.
+// It must not be called while the API serves requests, because they read the prefix
+// without synchronization.
+func (api *API) SetBasePath(prefix string) {
+	api.info.prefix = prefix
+	for _, res := range api.resources {
+		res.register(api.router, prefix)
+	}
+	for _, route := range api.routes {
+		api.registerRoute(route, prefix)
+	}
+}
+
+// ClearResources removes all resources and their routes, e.g. to register new sources
+// between tests that share an API. Routes that do not belong to a resource, e.g. the ones
+// of AddPing, AddHealthCheck or ServeFiles, are registered again. It panics if the router does not implement
+// routing.Resettable, because the routes could not be removed otherwise.
+func (api *API) ClearResources() {
+	router, ok := api.router.(routing.Resettable)
+	if !ok {
+		panic("can not clear the resources of an api whose router does not implement routing.Resettable")
+	}
+
+	router.Reset()
+	api.resources = nil
+	for i, route := range api.routes {
+		if route.prefixes != nil {
+			api.routes[i].prefixes = map[string]bool{}
+		}
+		api.registerRoute(api.routes[i], api.info.prefix)
+	}
+}
+
+// Clone returns a new API with the same prefix, resolver, marshalers, middlewares and
+// settings, but without any resources. This is useful in tests to register mock sources
+// without modifying the original API. Middlewares are copied shallowly.
+// It panics if the API does not use the default router, because other routers can not
+// be copied.
+func (api *API) Clone() *API {
+	if _, ok := api.router.(*routing.HTTPRouter); !ok {
+		panic("can not clone an api that does not use the internal httpRouter")
+	}
+
+	marshalers := copyMarshalers(api.marshalers)
+	router := routing.NewHTTPRouter(api.info.prefix, NotAllowedHandler{marshalers: marshalers})
+	router.(*routing.HTTPRouter).SetNotFoundHandler(NotFoundHandler{marshalers: marshalers})
+	clone := newAPI(api.info.prefix, api.info.resolver, marshalers, router, api.Context)
+
+	// the first middleware is added by newAPI and references the original api
+	clone.middlewares = append(clone.middlewares, api.middlewares[1:]...)
+	clone.exposeCountHeader = api.exposeCountHeader
+	clone.requestLogger = api.requestLogger
+	clone.eventBus = api.eventBus
+	clone.disableSecurityHeaders = api.disableSecurityHeaders
+	clone.TrustedProxies = api.TrustedProxies
+	clone.schemaURL = api.schemaURL
+	clone.embedding = api.embedding
+	clone.panicHandler = api.panicHandler
+	for uri := range api.profiles {
+		clone.AddProfile(uri)
+	}
+	for key := range api.defaultHeaders {
+		clone.SetDefaultHeader(key, api.defaultHeaders.Get(key))
+	}
+
+	return clone
 }
 
 // UseMiddleware registers middlewares that implement the api2go.HandlerFunc
 // Middleware is run before any generated routes.
 func (api *API) UseMiddleware(middleware ...func(http.Handler) http.Handler) {
 	api.middlewares = append(api.middlewares, middleware...)
+	api.resetHandler()
 }
 
 // SetRedirectTrailingSlash enables 307 redirects on urls ending with /
@@ -79,7 +479,9 @@ func (api *API) SetRedirectTrailingSlash(enabled bool) {
 // preferred content type, otherwise it will respond using whatever content
 // type the client provided in its Content-Type request header.
 func NewAPIWithMarshalling(prefix string, resolver URLResolver, marshalers map[string]ContentMarshaler, ctx context.Context) *API {
+	marshalers = copyMarshalers(marshalers)
 	r := routing.NewHTTPRouter(prefix, NotAllowedHandler{marshalers: marshalers})
+	r.(*routing.HTTPRouter).SetNotFoundHandler(NotFoundHandler{marshalers: marshalers})
 	return newAPI(prefix, resolver, marshalers, r, ctx)
 }
 
@@ -123,7 +525,18 @@ func NewAPIWithContextAndRouting(prefix string, ctx context.Context, router rout
 //
 // if you have no specific marshalling needs, use `DefaultContentMarshalers`
 func NewAPIWithRouting(prefix string, resolver URLResolver, marshalers map[string]ContentMarshaler, router routing.Routeable) *API {
-	return newAPI(prefix, resolver, marshalers, router, nil)
+	return newAPI(prefix, resolver, copyMarshalers(marshalers), router, nil)
+}
+
+// copyMarshalers returns a copy of the marshalers map, every API has its own map
+// so that EnablePrettyPrint does not modify the marshalers of other APIs
+func copyMarshalers(marshalers map[string]ContentMarshaler) map[string]ContentMarshaler {
+	result := make(map[string]ContentMarshaler, len(marshalers))
+	for contentType, marshaler := range marshalers {
+		result[contentType] = marshaler
+	}
+
+	return result
 }
 
 // newAPI is now an internal method that can be changed if params are changing
@@ -146,6 +559,7 @@ func newAPI(prefix string, resolver URLResolver, marshalers map[string]ContentMa
 		router:     router,
 		info:       info,
 		marshalers: marshalers,
+		state:      &serverState{},
 		Context:    ctx,
 	}
 
@@ -158,6 +572,10 @@ func newAPI(prefix string, resolver URLResolver, marshalers map[string]ContentMa
 			info = api.info
 		}
 
+		if resolver, ok := api.info.resolver.(TenantAwareResolver); ok {
+			info = resolver.ResolveInformation(*r, info)
+		}
+
 		return info
 	}
 
@@ -169,9 +587,24 @@ func newAPI(prefix string, resolver URLResolver, marshalers map[string]ContentMa
 			} else {
 				c = r.Context()
 			}
-			c = context.WithValue(c, api_info, requestInfo(r, api))
-			c = context.WithValue(c, api_prefix, strings.Trim(api.info.prefix, "/"))
+			info := requestInfo(r, api)
+			c = context.WithValue(c, api_info, info)
+			c = context.WithValue(c, api_prefix, strings.Trim(info.prefix, "/"))
 			c = context.WithValue(c, api_api, api)
+			if !api.disableSecurityHeaders {
+				for key, values := range defaultSecurityHeaders {
+					w.Header()[key] = append([]string{}, values...)
+				}
+			}
+			for key := range api.defaultHeaders {
+				w.Header().Set(key, api.defaultHeaders.Get(key))
+			}
+
+			if api.requestLogger != nil {
+				api.serveLogged(next, w, r.WithContext(c))
+				return
+			}
+
 			next.ServeHTTP(w, r.WithContext(c))
 		})
 	})
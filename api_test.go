@@ -4,13 +4,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/manyminds/api2go/jsonapi"
+	"github.com/manyminds/api2go/routing"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"golang.org/x/net/context"
@@ -33,6 +36,20 @@ func (m *requestURLResolver) SetRequest(r http.Request) {
 	m.r = r
 }
 
+type tenantResolver struct{}
+
+func (t tenantResolver) GetBaseURL() string {
+	return "https://example.com"
+}
+
+func (t tenantResolver) ResolveInformation(r http.Request, info Information) Information {
+	if tenant := r.Header.Get("X-Tenant"); tenant != "" {
+		return NewInformation("/"+tenant+"/", NewStaticResolver("https://"+tenant+".example.com"))
+	}
+
+	return info
+}
+
 type invalid string
 
 func (i invalid) GetID() string {
@@ -263,7 +280,7 @@ func (s *fixtureSource) FindAll(req Request) (Responder, error) {
 }
 
 // this does not read the query parameters, which you would do to limit the result in real world usage
-func (s *fixtureSource) PaginatedFindAll(req Request) (uint, Responder, error) {
+func (s *fixtureSource) PaginatedFindAll(req Request) (int64, Responder, error) {
 	if s.pointers {
 		postsSlice := []*Post{}
 
@@ -271,7 +288,7 @@ func (s *fixtureSource) PaginatedFindAll(req Request) (uint, Responder, error) {
 			postsSlice = append(postsSlice, post)
 		}
 
-		return uint(len(s.posts)), &Response{Res: postsSlice}, nil
+		return int64(len(s.posts)), &Response{Res: postsSlice}, nil
 	}
 
 	postsSlice := []Post{}
@@ -280,7 +297,7 @@ func (s *fixtureSource) PaginatedFindAll(req Request) (uint, Responder, error) {
 		postsSlice = append(postsSlice, *post)
 	}
 
-	return uint(len(s.posts)), &Response{Res: postsSlice}, nil
+	return int64(len(s.posts)), &Response{Res: postsSlice}, nil
 }
 
 func (s *fixtureSource) FindOne(id string, req Request) (Responder, error) {
@@ -344,6 +361,272 @@ func (s *fixtureSource) Update(obj interface{}, req Request) (Responder, error)
 	return &Response{}, NewHTTPError(nil, "post not found", http.StatusNotFound)
 }
 
+type searchableSource struct {
+	*fixtureSource
+}
+
+func (s searchableSource) Search(query string, req Request) (Responder, error) {
+	result := []Post{}
+	for i := 1; i <= len(s.posts); i++ {
+		post := s.posts[strconv.Itoa(i)]
+		if strings.Contains(post.Title, query) {
+			result = append(result, *post)
+		}
+	}
+
+	return &Response{Res: result}, nil
+}
+
+// projectableSource records the fields given to FindAllWithProjection
+type projectableSource struct {
+	*fixtureSource
+	fields map[string][]string
+}
+
+func (s *projectableSource) FindAllWithProjection(fields map[string][]string, req Request) (Responder, error) {
+	s.fields = fields
+	return s.fixtureSource.FindAll(req)
+}
+
+// afterFetchSource removes posts titled "Goodbye, World!" from collections
+type afterFetchSource struct {
+	*fixtureSource
+	err error
+}
+
+func (s afterFetchSource) AfterFetch(objs []interface{}, req Request) ([]interface{}, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	result := []interface{}{}
+	for _, obj := range objs {
+		post, ok := obj.(Post)
+		if !ok {
+			post = *obj.(*Post)
+		}
+
+		if post.Title != "Goodbye, World!" {
+			result = append(result, obj)
+		}
+	}
+
+	return result, nil
+}
+
+// maskingSource hides the title of posts from everyone but admins
+type maskingSource struct {
+	*fixtureSource
+}
+
+func (s maskingSource) MaskFields(obj interface{}, req Request) interface{} {
+	if req.Header.Get("X-Role") == "admin" {
+		return obj
+	}
+
+	switch post := obj.(type) {
+	case Post:
+		post.Title = ""
+		return post
+	case *Post:
+		masked := *post
+		masked.Title = ""
+		return &masked
+	}
+
+	return obj
+}
+
+// statusUpdateSource answers updates with a fixed status code
+type statusUpdateSource struct {
+	*fixtureSource
+	code int
+}
+
+func (s statusUpdateSource) Update(obj interface{}, req Request) (Responder, error) {
+	if _, err := s.fixtureSource.Update(obj, req); err != nil {
+		return nil, err
+	}
+
+	return &Response{Code: s.code}, nil
+}
+
+// noContentCreateSource accepts new posts without returning them
+type noContentCreateSource struct {
+	*fixtureSource
+}
+
+func (s noContentCreateSource) Create(obj interface{}, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+// asyncResponse is answered with 202 and the polling url of the creation job
+type asyncResponse struct {
+	Response
+	pollingURL string
+}
+
+func (r asyncResponse) PollingURL() string {
+	return r.pollingURL
+}
+
+// asyncCreateSource accepts objects to create them later
+type asyncCreateSource struct {
+	*fixtureSource
+	response Responder
+}
+
+func (s asyncCreateSource) Create(obj interface{}, req Request) (Responder, error) {
+	return s.response, nil
+}
+
+// bodyReadingSource reads the request body twice in Create
+type bodyReadingSource struct {
+	*fixtureSource
+	bodies []string
+}
+
+func (s *bodyReadingSource) Create(obj interface{}, req Request) (Responder, error) {
+	for i := 0; i < 2; i++ {
+		body, err := req.Body()
+		if err != nil {
+			return nil, err
+		}
+		s.bodies = append(s.bodies, string(body))
+	}
+
+	return s.fixtureSource.Create(obj, req)
+}
+
+// initializingSource implements Initializer with the given function
+type initializingSource struct {
+	*fixtureSource
+	initialize func(ctx context.Context) error
+}
+
+func (s initializingSource) Initialize(ctx context.Context) error {
+	return s.initialize(ctx)
+}
+
+// relationshipMetaPost adds meta to the author relationship of a Post
+type relationshipMetaPost struct {
+	Post
+}
+
+func (p relationshipMetaPost) GetRelationshipMeta(name string) map[string]interface{} {
+	if name == "author" {
+		return map[string]interface{}{"since": "2020"}
+	}
+
+	return nil
+}
+
+// wrappedRouter hides the type of the default router
+// schemaSource has its own schema document
+type schemaSource struct {
+	*userSource
+}
+
+func (s schemaSource) SchemaURL() string {
+	return "https://example.com/schemas/users.json"
+}
+
+type wrappedRouter struct {
+	routing.Routeable
+}
+
+// includingSource returns the author and comments of a post at the relationship urls
+type includingSource struct {
+	*fixtureSource
+}
+
+func (s includingSource) IncludeRelationship(relation string, obj interface{}, req Request) (Responder, error) {
+	var post Post
+	switch p := obj.(type) {
+	case Post:
+		post = p
+	case *Post:
+		post = *p
+	}
+
+	switch relation {
+	case "author":
+		if post.Author == nil {
+			return nil, nil
+		}
+		return &Response{Res: *post.Author}, nil
+	case "comments":
+		return &Response{Res: post.Comments}, nil
+	}
+
+	return nil, nil
+}
+
+// pathParamsSource records the path params of the last request
+type pathParamsSource struct {
+	*fixtureSource
+	pathParams map[string]string
+}
+
+func (s *pathParamsSource) FindAll(req Request) (Responder, error) {
+	s.pathParams = req.PathParams
+	return s.fixtureSource.FindAll(req)
+}
+
+func (s *pathParamsSource) FindOne(ID string, req Request) (Responder, error) {
+	s.pathParams = req.PathParams
+	return s.fixtureSource.FindOne(ID, req)
+}
+
+func (s *pathParamsSource) Create(obj interface{}, req Request) (Responder, error) {
+	s.pathParams = req.PathParams
+	return s.fixtureSource.Create(obj, req)
+}
+
+type metaPaginatedSource struct {
+	*fixtureSource
+}
+
+func (s metaPaginatedSource) PaginatedFindAll(req Request) (int64, Responder, error) {
+	count, response, err := s.fixtureSource.PaginatedFindAll(req)
+	return count, &Response{Res: response.Result(), Meta: map[string]interface{}{"author": "api2go"}}, err
+}
+
+// queryMetaResponse reports the query time with QueryMeta
+type queryMetaResponse struct {
+	Response
+}
+
+func (r queryMetaResponse) QueryMeta() map[string]interface{} {
+	return map[string]interface{}{"query_time_ms": 12, "author": "database"}
+}
+
+type queryMetaSource struct {
+	*fixtureSource
+}
+
+func (s queryMetaSource) FindAll(req Request) (Responder, error) {
+	response, err := s.fixtureSource.FindAll(req)
+	return queryMetaResponse{Response{Res: response.Result(), Meta: map[string]interface{}{"author": "api2go"}}}, err
+}
+
+func (s queryMetaSource) PaginatedFindAll(req Request) (int64, Responder, error) {
+	count, response, err := s.fixtureSource.PaginatedFindAll(req)
+	return count, queryMetaResponse{Response{Res: response.Result()}}, err
+}
+
+type bulkSource struct {
+	*fixtureSource
+	relName string
+	updates []RelationshipUpdate
+}
+
+func (s *bulkSource) PatchRelationships(relName string, updates []RelationshipUpdate, req Request) error {
+	s.relName = relName
+	s.updates = updates
+	return nil
+}
+
 type userSource struct {
 	pointers bool
 }
@@ -626,6 +909,7 @@ var _ = Describe("RestHandler", func() {
 			expected, err := json.Marshal(map[string]interface{}{
 				"data":     []map[string]interface{}{post1Json, post2Json, post3Json},
 				"included": post1LinkedJSON,
+				"links":    map[string]interface{}{"self": "/v1/posts"},
 			})
 			Expect(err).ToNot(HaveOccurred())
 			Expect(rec.Body.Bytes()).To(MatchJSON(expected))
@@ -639,6 +923,7 @@ var _ = Describe("RestHandler", func() {
 			expected, err := json.Marshal(map[string]interface{}{
 				"data":     post1Json,
 				"included": post1LinkedJSON,
+				"links":    map[string]interface{}{"self": "/v1/posts/1"},
 			})
 			Expect(err).ToNot(HaveOccurred())
 			Expect(rec.Body.Bytes()).To(MatchJSON(expected))
@@ -691,6 +976,45 @@ var _ = Describe("RestHandler", func() {
 			Expect(rec.Body.Bytes()).To(MatchJSON(`{"data": {"id": "1", "type": "users"}, "links": {"self": "/v1/posts/1/relationships/author", "related": "/v1/posts/1/author"}}`))
 		})
 
+		Context("with a RelationshipIncluder", func() {
+			BeforeEach(func() {
+				api = NewAPI("v1")
+				api.AddResource(Post{}, includingSource{source})
+			})
+
+			It("includes the related resources for to-many", func() {
+				req, err := http.NewRequest("GET", "/v1/posts/1/relationships/comments", nil)
+				Expect(err).ToNot(HaveOccurred())
+				api.Handler().ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusOK))
+				Expect(rec.Body.Bytes()).To(MatchJSON(`{
+					"data": [{"id": "1", "type": "comments"}],
+					"links": {"self": "/v1/posts/1/relationships/comments", "related": "/v1/posts/1/comments"},
+					"included": [{"id": "1", "type": "comments", "attributes": {"value": "This is a stupid post!"}}]
+				}`))
+			})
+
+			It("includes the related resource for to-one", func() {
+				req, err := http.NewRequest("GET", "/v1/posts/1/relationships/author", nil)
+				Expect(err).ToNot(HaveOccurred())
+				api.Handler().ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusOK))
+				Expect(rec.Body.Bytes()).To(MatchJSON(`{
+					"data": {"id": "1", "type": "users"},
+					"links": {"self": "/v1/posts/1/relationships/author", "related": "/v1/posts/1/author"},
+					"included": [{"id": "1", "type": "users", "attributes": {"name": "Dieter", "info": ""}}]
+				}`))
+			})
+
+			It("does not include anything if the includer returns nil", func() {
+				req, err := http.NewRequest("GET", "/v1/posts/2/relationships/author", nil)
+				Expect(err).ToNot(HaveOccurred())
+				api.Handler().ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusOK))
+				Expect(rec.Body.String()).ToNot(ContainSubstring("included"))
+			})
+		})
+
 		It("Gets 404 if a related struct was not found", func() {
 			req, err := http.NewRequest("GET", "/v1/posts/1/unicorns", nil)
 			Expect(err).ToNot(HaveOccurred())
@@ -749,9 +1073,56 @@ var _ = Describe("RestHandler", func() {
 						},
 					},
 				},
+				"links": map[string]interface{}{"self": "/v1/posts"},
 			}))
 		})
 
+		Context("accepted for asynchronous creation", func() {
+			doAsyncRequest := func(response Responder) {
+				api = NewAPI("v1")
+				api.AddResource(Post{}, asyncCreateSource{source, response})
+				reqBody := strings.NewReader(`{"data": {"attributes":{"title": "New Post" }, "type": "posts"}}`)
+				req, err := http.NewRequest("POST", "/v1/posts", reqBody)
+				Expect(err).To(BeNil())
+				api.Handler().ServeHTTP(rec, req)
+			}
+
+			It("sets Location to the polling url of an AsyncResponder", func() {
+				doAsyncRequest(asyncResponse{Response{Code: http.StatusAccepted}, "/v1/jobs/1"})
+				Expect(rec.Code).To(Equal(http.StatusAccepted))
+				Expect(rec.Header().Get("Location")).To(Equal("/v1/jobs/1"))
+			})
+
+			It("sets Location to the polling_url meta data", func() {
+				doAsyncRequest(&Response{Code: http.StatusAccepted, Meta: map[string]interface{}{"polling_url": "/v1/jobs/2"}})
+				Expect(rec.Code).To(Equal(http.StatusAccepted))
+				Expect(rec.Header().Get("Location")).To(Equal("/v1/jobs/2"))
+			})
+
+			It("still sets Location to the accepted object without polling url", func() {
+				doAsyncRequest(&Response{Code: http.StatusAccepted, Res: Post{ID: "5"}})
+				Expect(rec.Code).To(Equal(http.StatusAccepted))
+				Expect(rec.Header().Get("Location")).To(Equal("/v1/posts/5"))
+			})
+
+			It("fails without polling url and object", func() {
+				doAsyncRequest(&Response{Code: http.StatusAccepted})
+				Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+
+		It("POSTSs new objects without Location for 204 responses", func() {
+			api = NewAPI("v1")
+			api.AddResource(Post{}, noContentCreateSource{source})
+			reqBody := strings.NewReader(`{"data": {"attributes":{"title": "New Post" }, "type": "posts"}}`)
+			req, err := http.NewRequest("POST", "/v1/posts", reqBody)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(rec.Header().Get("Location")).To(BeEmpty())
+			Expect(rec.Body.Bytes()).To(BeEmpty())
+		})
+
 		It("POSTSs new objects with trailing slash automatic redirect enabled", func() {
 			reqBody := strings.NewReader(`{"data": [{"title": "New Post", "type": "posts"}]}`)
 			req, err := http.NewRequest("POST", "/v1/posts/", reqBody)
@@ -769,6 +1140,24 @@ var _ = Describe("RestHandler", func() {
 			Expect(rec.Code).To(Equal(http.StatusCreated))
 		})
 
+		It("POSTSs new objects with inline to-one relationship", func() {
+			reqBody := strings.NewReader(`{"data": {"attributes": {"title": "New Post"}, "type": "posts", "relationships": {"author": {"data": {"id": "2", "type": "users"}}}}}`)
+			req, err := http.NewRequest("POST", "/v1/posts", reqBody)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(source.posts["4"].Author).To(Equal(&User{ID: "2"}))
+		})
+
+		It("does not POST new objects with unknown inline to-one relationship", func() {
+			reqBody := strings.NewReader(`{"data": {"attributes": {"title": "New Post"}, "type": "posts", "relationships": {"editor": {"data": {"id": "2", "type": "users"}}}}}`)
+			req, err := http.NewRequest("POST", "/v1/posts", reqBody)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(source.posts).To(HaveLen(3))
+		})
+
 		It("POSTSs new objects with trailing slash automatic redirect disabled", func() {
 			reqBody := strings.NewReader(`{"data": [{"title": "New Post", "type": "posts"}]}`)
 			req, err := http.NewRequest("POST", "/v1/posts/", reqBody)
@@ -783,7 +1172,7 @@ var _ = Describe("RestHandler", func() {
 			req, err := http.NewRequest("POST", "/v1/posts", reqBody)
 			Expect(err).To(BeNil())
 			api.Handler().ServeHTTP(rec, req)
-			Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
 			Expect(rec.Header().Get("Location")).To(Equal(""))
 			Expect(rec.Body.Bytes()).ToNot(HaveLen(0))
 		})
@@ -800,7 +1189,26 @@ var _ = Describe("RestHandler", func() {
 			api.Handler().ServeHTTP(rec, req)
 			Expect(err).To(BeNil())
 			Expect(rec.Code).To(Equal(http.StatusNoContent))
-			Expect(rec.Header().Get("Allow")).To(Equal("GET,PATCH,DELETE,OPTIONS"))
+			Expect(rec.Header().Get("Allow")).To(Equal("GET,HEAD,PATCH,DELETE,OPTIONS"))
+		})
+
+		It("HEAD on existing element", func() {
+			req, err := http.NewRequest("HEAD", "/v1/posts/1", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get("X-Resource-Exists")).To(Equal("true"))
+			Expect(rec.Header().Get("Content-Type")).To(Equal(defaultContentTypeHeader))
+			Expect(rec.Body.Bytes()).To(BeEmpty())
+		})
+
+		It("HEAD on missing element", func() {
+			req, err := http.NewRequest("HEAD", "/v1/posts/23", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(rec.Header().Get("X-Resource-Exists")).To(Equal("false"))
+			Expect(rec.Body.Bytes()).To(BeEmpty())
 		})
 
 		It("DELETEs", func() {
@@ -817,7 +1225,7 @@ var _ = Describe("RestHandler", func() {
 			Expect(err).To(BeNil())
 			api.Handler().ServeHTTP(rec, req)
 			Expect(rec.Code).To(Equal(http.StatusForbidden))
-			Expect(string(rec.Body.Bytes())).To(MatchJSON(`{"errors":[{"status":"403","title":"missing mandatory type key."}]}`))
+			Expect(string(rec.Body.Bytes())).To(MatchJSON(`{"errors":[{"status":"403","title":"missing mandatory type key.","source":{"pointer":"/data"}}]}`))
 		})
 
 		It("patch must contain type and id but does not have id", func() {
@@ -826,7 +1234,34 @@ var _ = Describe("RestHandler", func() {
 			Expect(err).To(BeNil())
 			api.Handler().ServeHTTP(rec, req)
 			Expect(rec.Code).To(Equal(http.StatusForbidden))
-			Expect(string(rec.Body.Bytes())).To(MatchJSON(`{"errors":[{"status":"403","title":"missing mandatory id key."}]}`))
+			Expect(string(rec.Body.Bytes())).To(MatchJSON(`{"errors":[{"status":"403","title":"missing mandatory id key.","source":{"pointer":"/data"}}]}`))
+		})
+
+		It("patch must not contain a different id than the url", func() {
+			reqBody := strings.NewReader(`{"data": {"id": "2", "type": "posts", "attributes": {"title": "New Title"}}}`)
+			req, err := http.NewRequest("PATCH", "/v1/posts/1", reqBody)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+			Expect(string(rec.Body.Bytes())).To(MatchJSON(`{"errors":[{"status":"409","title":"id 2 in the request document does not match id 1 in the url.","source":{"pointer":"/data/id"}}]}`))
+		})
+
+		It("patch must not contain unknown inline to-one relationships", func() {
+			reqBody := strings.NewReader(`{"data": {"id": "1", "type": "posts", "relationships": {"editor": {"data": {"id": "2", "type": "users"}}}}}`)
+			req, err := http.NewRequest("PATCH", "/v1/posts/1", reqBody)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(source.posts["1"].Author).To(Equal(&User{ID: "1", Name: "Dieter"}))
+		})
+
+		It("patch must contain a data object", func() {
+			reqBody := strings.NewReader(`{"data": "posts"}`)
+			req, err := http.NewRequest("PATCH", "/v1/posts/1", reqBody)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusForbidden))
+			Expect(string(rec.Body.Bytes())).To(MatchJSON(`{"errors":[{"status":"403","title":"data must contain an object.","source":{"pointer":"/data"}}]}`))
 		})
 
 		Context("Updating", func() {
@@ -977,6 +1412,38 @@ var _ = Describe("RestHandler", func() {
 				target := source.posts["1"]
 				Expect(target.Comments).To(HaveLen(0))
 			})
+
+			Context("with status codes of Update", func() {
+				doStatusRequest := func(code int) {
+					api = NewAPI("v1")
+					api.AddResource(Post{}, statusUpdateSource{source, code})
+					reqBody := strings.NewReader(`{"data": [{"type": "comments", "id": "2"}]}`)
+					req, err := http.NewRequest("PATCH", "/v1/posts/1/relationships/comments", reqBody)
+					Expect(err).To(BeNil())
+					api.Handler().ServeHTTP(rec, req)
+				}
+
+				It("Relationship PATCH route answers with the linkage for 200", func() {
+					doStatusRequest(http.StatusOK)
+					Expect(rec.Code).To(Equal(http.StatusOK))
+					Expect(rec.Body.Bytes()).To(MatchJSON(`{
+						"data": [{"id": "2", "type": "comments"}],
+						"links": {"self": "/v1/posts/1/relationships/comments", "related": "/v1/posts/1/comments"}
+					}`))
+					Expect(source.posts["1"].Comments[0].GetID()).To(Equal("2"))
+				})
+
+				It("Relationship PATCH route answers with 202", func() {
+					doStatusRequest(http.StatusAccepted)
+					Expect(rec.Code).To(Equal(http.StatusAccepted))
+					Expect(rec.Body.String()).To(BeEmpty())
+				})
+
+				It("Relationship PATCH route rejects invalid status codes", func() {
+					doStatusRequest(http.StatusTeapot)
+					Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+				})
+			})
 		})
 	}
 
@@ -1038,7 +1505,7 @@ var _ = Describe("RestHandler", func() {
 				"1": {ID: "1", Title: "Hello, World!"},
 			}, false}
 
-			jsonResponse = `{"data":{"attributes":{"title":"Hello, World!","value":null},"id":"1","relationships":{"author":{"data":null,"links":{"related":"/posts/1/author","self":"/posts/1/relationships/author"}},"bananas":{"data":[],"links":{"related":"/posts/1/bananas","self":"/posts/1/relationships/bananas"}},"comments":{"data":[],"links":{"related":"/posts/1/comments","self":"/posts/1/relationships/comments"}}},"type":"posts"}}`
+			jsonResponse = `{"data":{"attributes":{"title":"Hello, World!","value":null},"id":"1","relationships":{"author":{"data":null,"links":{"related":"/posts/1/author","self":"/posts/1/relationships/author"}},"bananas":{"data":[],"links":{"related":"/posts/1/bananas","self":"/posts/1/relationships/bananas"}},"comments":{"data":[],"links":{"related":"/posts/1/comments","self":"/posts/1/relationships/comments"}}},"type":"posts"},"links":{"self":"/posts/1"}}`
 			prettyResponse = `{
     "data": {
         "attributes": {
@@ -1070,6 +1537,9 @@ var _ = Describe("RestHandler", func() {
             }
         },
         "type": "posts"
+    },
+    "links": {
+        "self": "/posts/1"
     }
 }`
 
@@ -1139,6 +1609,78 @@ var _ = Describe("RestHandler", func() {
 		})
 	})
 
+	Context("content type validation", func() {
+		var (
+			api *API
+			rec *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			source := &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false}
+
+			api = NewAPI("v1")
+			api.AddResource(Post{}, source)
+			rec = httptest.NewRecorder()
+		})
+
+		doRequest := func(method, URL, contentType string) {
+			reqBody := strings.NewReader(`{"data": {"type": "posts", "id": "1", "attributes": {"title": "New Title"}}}`)
+			req, err := http.NewRequest(method, URL, reqBody)
+			Expect(err).To(BeNil())
+			if contentType != "" {
+				req.Header.Set("Content-Type", contentType)
+			}
+			api.Handler().ServeHTTP(rec, req)
+		}
+
+		It("rejects POST requests with an unsupported Content-Type", func() {
+			doRequest("POST", "/v1/posts", "application/json")
+			Expect(rec.Code).To(Equal(http.StatusUnsupportedMediaType))
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"415","title":"Content-Type application/json is not supported"}]}`))
+		})
+
+		It("rejects PATCH requests with an unsupported Content-Type", func() {
+			doRequest("PATCH", "/v1/posts/1", "text/plain")
+			Expect(rec.Code).To(Equal(http.StatusUnsupportedMediaType))
+		})
+
+		It("accepts the jsonapi media type with and without parameters", func() {
+			doRequest("PATCH", "/v1/posts/1", "application/vnd.api+json")
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+
+			rec = httptest.NewRecorder()
+			doRequest("PATCH", "/v1/posts/1", defaultContentTypeHeader)
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+		})
+
+		It("rejects unknown extensions and profiles", func() {
+			doRequest("POST", "/v1/posts", `application/vnd.api+json; ext="https://example.com/ext/atomic"`)
+			Expect(rec.Code).To(Equal(http.StatusUnsupportedMediaType))
+
+			rec = httptest.NewRecorder()
+			doRequest("POST", "/v1/posts", `application/vnd.api+json; profile="https://example.com/profiles/timestamps"`)
+			Expect(rec.Code).To(Equal(http.StatusUnsupportedMediaType))
+		})
+
+		It("accepts registered extensions and profiles", func() {
+			api.AddProfile("https://example.com/ext/atomic")
+			api.AddProfile("https://example.com/profiles/timestamps")
+			doRequest("POST", "/v1/posts", `application/vnd.api+json; ext="https://example.com/ext/atomic"; profile="https://example.com/profiles/timestamps"`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+
+			rec = httptest.NewRecorder()
+			doRequest("POST", "/v1/posts", `application/vnd.api+json; ext="https://example.com/ext/atomic https://example.com/ext/other"`)
+			Expect(rec.Code).To(Equal(http.StatusUnsupportedMediaType))
+		})
+
+		It("accepts requests without Content-Type", func() {
+			doRequest("POST", "/v1/posts", "")
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+		})
+	})
+
 	Context("Extracting query parameters with complete BaseURL API", func() {
 		var (
 			source    *fixtureSource
@@ -1234,7 +1776,8 @@ var _ = Describe("RestHandler", func() {
 			var result map[string]interface{}
 			Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(BeNil())
 			Expect(result).To(Equal(map[string]interface{}{
-				"data": []interface{}{post1JSON, post2JSON},
+				"data":  []interface{}{post1JSON, post2JSON},
+				"links": map[string]interface{}{"self": "http://localhost:1337/v0/posts"},
 			}))
 		})
 
@@ -1246,7 +1789,8 @@ var _ = Describe("RestHandler", func() {
 			var result map[string]interface{}
 			Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(BeNil())
 			Expect(result).To(Equal(map[string]interface{}{
-				"data": []interface{}{post1JSON},
+				"data":  []interface{}{post1JSON},
+				"links": map[string]interface{}{"self": "http://localhost:1337/v0/posts?limit=1"},
 			}))
 		})
 
@@ -1352,51 +1896,106 @@ var _ = Describe("RestHandler", func() {
 			})
 		})
 
-		// If the combination of parameters is invalid, no links are generated and the normal FindAll method get's called
+		Context("page & per_page links", func() {
+			It("uses the aliases in all links", func() {
+				links := doRequest("/v1/posts?page[page]=2&page[per_page]=2")
+				Expect(links).To(HaveLen(4))
+				Expect(links["first"]).To(Equal("/v1/posts?page[page]=1&page[per_page]=2"))
+				Expect(links["prev"]).To(Equal("/v1/posts?page[page]=1&page[per_page]=2"))
+				Expect(links["next"]).To(Equal("/v1/posts?page[page]=3&page[per_page]=2"))
+				Expect(links["last"]).To(Equal("/v1/posts?page[page]=4&page[per_page]=2"))
+			})
+
+			It("prefers page[number] and page[size]", func() {
+				req, err := http.NewRequest("GET", "/v1/posts?page[number]=2&page[page]=3&page[size]=1&page[per_page]=4", nil)
+				Expect(err).ToNot(HaveOccurred())
+				pagination := NewPaginationQueryParams(req)
+				Expect(pagination).To(Equal(PaginationQueryParams{number: "2", size: "1"}))
+			})
+
+			It("passes the page number and size to the source", func() {
+				req, err := http.NewRequest("GET", "/v1/posts?page[page]=2&page[per_page]=3", nil)
+				Expect(err).ToNot(HaveOccurred())
+				request := BuildRequest(req.Context(), req)
+				Expect(request.QueryParams["page[number]"]).To(Equal([]string{"2"}))
+				Expect(request.QueryParams["page[size]"]).To(Equal([]string{"3"}))
+			})
+		})
+
+		// If the combination of parameters is invalid, the request is answered with 400 Bad Request
 		Context("invalid parameter combinations", func() {
+			// helper function that expects a bad request error with the given title
+			doInvalidRequest := func(URL, title string) {
+				req, err := http.NewRequest("GET", URL, nil)
+				Expect(err).ToNot(HaveOccurred())
+				api.Handler().ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+				Expect(rec.Body.String()).To(MatchJSON(fmt.Sprintf(`{"errors":[{"status":"400","title":"%s"}]}`, title)))
+			}
+
 			It("all 4 of them", func() {
-				links := doRequest("/v1/posts?page[number]=1&page[size]=1&page[offset]=1&page[limit]=1")
-				Expect(links).To(HaveLen(0))
+				doInvalidRequest("/v1/posts?page[number]=1&page[size]=1&page[offset]=1&page[limit]=1", "page[number] and page[size] can not be combined with page[offset] and page[limit]")
 			})
 
 			It("number only", func() {
-				links := doRequest("/v1/posts?page[number]=1")
-				Expect(links).To(HaveLen(0))
+				doInvalidRequest("/v1/posts?page[number]=1", "page[number] requires page[size]")
 			})
 
 			It("size only", func() {
-				links := doRequest("/v1/posts?page[size]=1")
-				Expect(links).To(HaveLen(0))
+				doInvalidRequest("/v1/posts?page[size]=1", "page[size] requires page[number]")
 			})
 
 			It("offset only", func() {
-				links := doRequest("/v1/posts?page[offset]=1")
-				Expect(links).To(HaveLen(0))
+				doInvalidRequest("/v1/posts?page[offset]=1", "page[offset] requires page[limit]")
 			})
 
 			It("limit only", func() {
-				links := doRequest("/v1/posts?page[limit]=1")
-				Expect(links).To(HaveLen(0))
+				doInvalidRequest("/v1/posts?page[limit]=1", "page[limit] requires page[offset]")
 			})
 
 			It("number, size & offset", func() {
-				links := doRequest("/v1/posts?page[number]=1&page[size]=1&page[offset]=1")
-				Expect(links).To(HaveLen(0))
+				doInvalidRequest("/v1/posts?page[number]=1&page[size]=1&page[offset]=1", "page[number] and page[size] can not be combined with page[offset] and page[limit]")
 			})
 
 			It("number, size & limit", func() {
-				links := doRequest("/v1/posts?page[number]=1&page[size]=1&page[limit]=1")
-				Expect(links).To(HaveLen(0))
+				doInvalidRequest("/v1/posts?page[number]=1&page[size]=1&page[limit]=1", "page[number] and page[size] can not be combined with page[offset] and page[limit]")
 			})
 
 			It("limit, offset & number", func() {
-				links := doRequest("/v1/posts?page[limit]=1&page[offset]=1&page[number]=1")
-				Expect(links).To(HaveLen(0))
+				doInvalidRequest("/v1/posts?page[limit]=1&page[offset]=1&page[number]=1", "page[number] and page[size] can not be combined with page[offset] and page[limit]")
 			})
 
 			It("limit, offset & size", func() {
-				links := doRequest("/v1/posts?page[limit]=1&page[offset]=1&page[size]=1")
-				Expect(links).To(HaveLen(0))
+				doInvalidRequest("/v1/posts?page[limit]=1&page[offset]=1&page[size]=1", "page[number] and page[size] can not be combined with page[offset] and page[limit]")
+			})
+
+			It("page only", func() {
+				doInvalidRequest("/v1/posts?page[page]=1", "page[page] requires page[per_page]")
+			})
+
+			It("per_page only", func() {
+				doInvalidRequest("/v1/posts?page[per_page]=1", "page[per_page] requires page[page]")
+			})
+
+			It("page & size", func() {
+				doInvalidRequest("/v1/posts?page[page]=1&page[size]=1", "page[page] can not be combined with page[size]")
+			})
+
+			It("number & per_page", func() {
+				doInvalidRequest("/v1/posts?page[number]=1&page[per_page]=1", "page[number] can not be combined with page[per_page]")
+			})
+
+			It("page, per_page & offset", func() {
+				doInvalidRequest("/v1/posts?page[page]=1&page[per_page]=1&page[offset]=1", "page[page] and page[per_page] can not be combined with page[offset] and page[limit]")
+			})
+
+			It("IsValid stays compatible", func() {
+				req, err := http.NewRequest("GET", "/v1/posts?page[number]=1", nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(NewPaginationQueryParams(req).IsValid()).To(BeFalse())
+				req, err = http.NewRequest("GET", "/v1/posts?page[number]=1&page[size]=2", nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(NewPaginationQueryParams(req).IsValid()).To(BeTrue())
 			})
 		})
 
@@ -1415,78 +2014,1440 @@ var _ = Describe("RestHandler", func() {
 				Expect(links["last"]).To(Equal("/v1/posts?page[limit]=2&page[offset]=5"))
 			})
 
-			It("All links on offset = 2, limit = 2", func() {
-				links := doRequest("/v1/posts?page[offset]=2&page[limit]=2")
-				Expect(links).To(HaveLen(4))
-				Expect(links["first"]).To(Equal("/v1/posts?page[limit]=2&page[offset]=0"))
-				Expect(links["prev"]).To(Equal("/v1/posts?page[limit]=2&page[offset]=0"))
-				Expect(links["next"]).To(Equal("/v1/posts?page[limit]=2&page[offset]=4"))
-				Expect(links["last"]).To(Equal("/v1/posts?page[limit]=2&page[offset]=5"))
-			})
+			It("All links on offset = 2, limit = 2", func() {
+				links := doRequest("/v1/posts?page[offset]=2&page[limit]=2")
+				Expect(links).To(HaveLen(4))
+				Expect(links["first"]).To(Equal("/v1/posts?page[limit]=2&page[offset]=0"))
+				Expect(links["prev"]).To(Equal("/v1/posts?page[limit]=2&page[offset]=0"))
+				Expect(links["next"]).To(Equal("/v1/posts?page[limit]=2&page[offset]=4"))
+				Expect(links["last"]).To(Equal("/v1/posts?page[limit]=2&page[offset]=5"))
+			})
+
+			It("No next and last on offset = 5, limit = 2", func() {
+				links := doRequest("/v1/posts?page[offset]=5&page[limit]=2")
+				Expect(links).To(HaveLen(2))
+				Expect(links["prev"]).To(Equal("/v1/posts?page[limit]=2&page[offset]=3"))
+				Expect(links["first"]).To(Equal("/v1/posts?page[limit]=2&page[offset]=0"))
+			})
+
+			It("Does not generate links if results fit on one page", func() {
+				links := doRequest("/v1/posts?page[offset]=0&page[limit]=10")
+				Expect(links).To(HaveLen(0))
+			})
+		})
+
+		Context("self link", func() {
+			getLinks := func(URL string) map[string]interface{} {
+				req, err := http.NewRequest("GET", URL, nil)
+				Expect(err).ToNot(HaveOccurred())
+				api.Handler().ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusOK))
+				var document struct {
+					Links map[string]interface{} `json:"links"`
+				}
+				Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+				return document.Links
+			}
+
+			It("points to the current page", func() {
+				links := getLinks("/v1/posts?page[size]=2&page[number]=2")
+				Expect(links["self"]).To(Equal("/v1/posts?page[number]=2&page[size]=2"))
+				Expect(links["first"]).To(Equal("/v1/posts?page[number]=1&page[size]=2"))
+			})
+
+			It("is set without pagination", func() {
+				Expect(getLinks("/v1/posts?sort=title")).To(Equal(map[string]interface{}{"self": "/v1/posts?sort=title"}))
+			})
+
+			It("does not generate protocol-relative links", func() {
+				req, err := http.NewRequest("GET", "/v1/posts", nil)
+				Expect(err).ToNot(HaveOccurred())
+				req.URL.Path = "//evil.com/v1/posts"
+				Expect(selfLink(req, NewInformation("v1", NewStaticResolver("")))).To(Equal("/evil.com/v1/posts"))
+			})
+		})
+
+		Context("link sanitization", func() {
+			It("does not generate protocol-relative links", func() {
+				req, err := http.NewRequest("GET", "/v1/posts?page[number]=2&page[size]=1", nil)
+				Expect(err).ToNot(HaveOccurred())
+				req.URL.Path = "//evil.com/v1/posts"
+				pagination := NewPaginationQueryParams(req)
+				links, err := pagination.GetLinks(req, 3, NewInformation("v1", NewStaticResolver("")))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(links["first"]).To(Equal("/evil.com/v1/posts?page[number]=1&page[size]=1"))
+			})
+		})
+
+		Context("current link", func() {
+			getLinks := func(URL string, count uint64) map[string]string {
+				req, err := http.NewRequest("GET", URL, nil)
+				Expect(err).ToNot(HaveOccurred())
+				links, err := NewPaginationQueryParams(req).GetLinks64(req, count, NewInformation("v1", NewStaticResolver("http://localhost")))
+				Expect(err).ToNot(HaveOccurred())
+				return links
+			}
+
+			It("points to the requested page with normalized params", func() {
+				links := getLinks("/v1/posts?sort=title&page[size]=02&page[number]=002", 7)
+				Expect(links["current"]).To(Equal("http://localhost/v1/posts?page[number]=2&page[size]=2&sort=title"))
+				Expect(links["next"]).To(Equal("http://localhost/v1/posts?page[number]=3&page[size]=2&sort=title"))
+			})
+
+			It("uses the page aliases", func() {
+				links := getLinks("/v1/posts?page[page]=1&page[per_page]=5", 7)
+				Expect(links["current"]).To(Equal("http://localhost/v1/posts?page[page]=1&page[per_page]=5"))
+			})
+
+			It("is set for offset and limit", func() {
+				links := getLinks("/v1/posts?page[limit]=2&page[offset]=04", 7)
+				Expect(links["current"]).To(Equal("http://localhost/v1/posts?page[limit]=2&page[offset]=4"))
+			})
+
+			It("is set if all results fit on one page", func() {
+				links := getLinks("/v1/posts?page[number]=1&page[size]=10", 7)
+				Expect(links).To(Equal(map[string]string{"current": "http://localhost/v1/posts?page[number]=1&page[size]=10"}))
+			})
+
+			It("is part of the response", func() {
+				req, err := http.NewRequest("GET", "/v1/posts?page[number]=2&page[size]=2", nil)
+				Expect(err).ToNot(HaveOccurred())
+				api.Handler().ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusOK))
+				var document struct {
+					Links map[string]string `json:"links"`
+				}
+				Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+				Expect(document.Links["current"]).To(Equal("/v1/posts?page[number]=2&page[size]=2"))
+			})
+		})
+
+		Context("large collections", func() {
+			It("calculates links for counts beyond the uint32 range", func() {
+				req, err := http.NewRequest("GET", "/v1/posts?page[number]=1&page[size]=10", nil)
+				Expect(err).ToNot(HaveOccurred())
+				pagination := NewPaginationQueryParams(req)
+				links, err := pagination.GetLinks64(req, 50000000000, NewInformation("v1", NewStaticResolver("")))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(links["last"]).To(Equal("/v1/posts?page[number]=5000000000&page[size]=10"))
+			})
+
+			It("calculates the last offset for counts beyond the uint32 range", func() {
+				req, err := http.NewRequest("GET", "/v1/posts?page[offset]=0&page[limit]=10", nil)
+				Expect(err).ToNot(HaveOccurred())
+				pagination := NewPaginationQueryParams(req)
+				links, err := pagination.GetLinks64(req, 50000000000, NewInformation("v1", NewStaticResolver("")))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(links["last"]).To(Equal("/v1/posts?page[limit]=10&page[offset]=49999999990"))
+			})
+
+			It("calculates links for the maximum count", func() {
+				req, err := http.NewRequest("GET", "/v1/posts?page[offset]=0&page[limit]=10", nil)
+				Expect(err).ToNot(HaveOccurred())
+				pagination := NewPaginationQueryParams(req)
+				links, err := pagination.GetLinks(req, math.MaxInt64, NewInformation("v1", NewStaticResolver("")))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(links["last"]).To(Equal("/v1/posts?page[limit]=10&page[offset]=9223372036854775797"))
+			})
+		})
+
+		Context("total count boundaries", func() {
+			It("only links the current page of empty collections", func() {
+				req, err := http.NewRequest("GET", "/v1/posts?page[number]=1&page[size]=10", nil)
+				Expect(err).ToNot(HaveOccurred())
+				pagination := NewPaginationQueryParams(req)
+				links, err := pagination.GetLinks(req, 0, NewInformation("v1", NewStaticResolver("")))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(links).To(Equal(map[string]string{"current": "/v1/posts?page[number]=1&page[size]=10"}))
+			})
+
+			It("rejects negative counts", func() {
+				req, err := http.NewRequest("GET", "/v1/posts?page[number]=1&page[size]=10", nil)
+				Expect(err).ToNot(HaveOccurred())
+				pagination := NewPaginationQueryParams(req)
+				_, err = pagination.GetLinks(req, -1, NewInformation("v1", NewStaticResolver("")))
+				Expect(err).To(HaveOccurred())
+
+				err = RespondWithPagination(&Response{Res: []Post{}}, NewInformation("v1", NewStaticResolver("")), http.StatusOK, nil, -1, httptest.NewRecorder(), req, DefaultContentMarshalers)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("pagination params of a request", func() {
+			It("returns valid pagination params", func() {
+				req, err := http.NewRequest("GET", "/v1/posts?page[number]=2&page[size]=1", nil)
+				Expect(err).ToNot(HaveOccurred())
+				pagination, ok := BuildRequest(req.Context(), req).GetPaginationParams()
+				Expect(ok).To(BeTrue())
+				Expect(pagination).To(Equal(NewPaginationQueryParams(req)))
+			})
+
+			It("returns false without or with invalid pagination params", func() {
+				for _, URL := range []string{"/v1/posts", "/v1/posts?page[number]=2", "/v1/posts?page[number]=2&page[limit]=1"} {
+					req, err := http.NewRequest("GET", URL, nil)
+					Expect(err).ToNot(HaveOccurred())
+					_, ok := BuildRequest(req.Context(), req).GetPaginationParams()
+					Expect(ok).To(BeFalse(), URL)
+				}
+			})
+
+			It("uses the query params without plain request", func() {
+				req := Request{QueryParams: map[string][]string{"page[offset]": {"4"}, "page[limit]": {"2"}}}
+				pagination, ok := req.GetPaginationParams()
+				Expect(ok).To(BeTrue())
+				Expect(pagination).To(Equal(PaginationQueryParams{offset: "4", limit: "2"}))
+			})
+		})
+
+		Context("pagination meta", func() {
+			getMeta := func(URL string) map[string]interface{} {
+				req, err := http.NewRequest("GET", URL, nil)
+				Expect(err).ToNot(HaveOccurred())
+				api.Handler().ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusOK))
+				var response map[string]interface{}
+				Expect(json.Unmarshal(rec.Body.Bytes(), &response)).To(BeNil())
+				return response["meta"].(map[string]interface{})
+			}
+
+			It("contains the total count", func() {
+				meta := getMeta("/v1/posts?page[number]=1&page[size]=2")
+				Expect(meta).To(Equal(map[string]interface{}{"total": float64(7)}))
+			})
+
+			It("is merged with the meta of the responder", func() {
+				api = NewAPI("v1")
+				api.AddResource(Post{}, metaPaginatedSource{source})
+				meta := getMeta("/v1/posts?page[number]=1&page[size]=2")
+				Expect(meta).To(Equal(map[string]interface{}{"total": float64(7), "author": "api2go"}))
+			})
+
+			Context("query meta", func() {
+				BeforeEach(func() {
+					api = NewAPI("v1")
+					api.AddResource(Post{}, queryMetaSource{source})
+				})
+
+				It("is merged with the meta of the responder", func() {
+					meta := getMeta("/v1/posts")
+					Expect(meta).To(Equal(map[string]interface{}{"query_time_ms": float64(12), "author": "api2go"}))
+				})
+
+				It("is merged with the pagination meta", func() {
+					meta := getMeta("/v1/posts?page[number]=1&page[size]=2")
+					Expect(meta).To(Equal(map[string]interface{}{"total": float64(7), "query_time_ms": float64(12), "author": "database"}))
+				})
+
+				It("is found in wrapped responders", func() {
+					var response Responder = queryMetaResponse{}
+					response = includedResponder{Responder: maskedResponder{Responder: response}}
+					Expect(queryMeta(response)).To(HaveKeyWithValue("query_time_ms", 12))
+					Expect(queryMeta(&Response{})).To(BeNil())
+				})
+			})
+		})
+
+		Context("total count header", func() {
+			It("is not sent by default", func() {
+				req, err := http.NewRequest("GET", "/v1/posts?page[number]=1&page[size]=2", nil)
+				Expect(err).ToNot(HaveOccurred())
+				api.Handler().ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusOK))
+				Expect(rec.Header().Get("X-Total-Count")).To(BeEmpty())
+				Expect(rec.Header().Get("Access-Control-Expose-Headers")).To(BeEmpty())
+			})
+
+			It("is sent and exposed if enabled", func() {
+				api.ExposeCountHeader(true)
+				req, err := http.NewRequest("GET", "/v1/posts?page[number]=1&page[size]=2", nil)
+				Expect(err).ToNot(HaveOccurred())
+				api.Handler().ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusOK))
+				Expect(rec.Header().Get("X-Total-Count")).To(Equal("7"))
+				Expect(rec.Header().Get("Access-Control-Expose-Headers")).To(Equal("X-Total-Count"))
+			})
+		})
+
+		Context("error codes", func() {
+			It("Should return the correct header on method not allowed", func() {
+				reqBody := strings.NewReader("")
+				req, err := http.NewRequest("PUT", "/v1/posts", reqBody)
+				Expect(err).To(BeNil())
+				api.Handler().ServeHTTP(rec, req)
+				expected := `{"errors":[{"status":"405","title":"Method Not Allowed"}]}`
+				Expect(rec.Body.String()).To(MatchJSON(expected))
+				Expect(rec.Result().Header.Get("Content-Type")).To(Equal(defaultContentTypeHeader))
+				Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+			})
+
+			It("Should return a jsonapi error for unknown resources", func() {
+				req, err := http.NewRequest("GET", "/v1/nonexistent", nil)
+				Expect(err).To(BeNil())
+				api.Handler().ServeHTTP(rec, req)
+				expected := `{"errors":[{"status":"404","title":"Not Found"}]}`
+				Expect(rec.Body.String()).To(MatchJSON(expected))
+				Expect(rec.Result().Header.Get("Content-Type")).To(Equal(defaultContentTypeHeader))
+				Expect(rec.Code).To(Equal(http.StatusNotFound))
+			})
+
+			It("Should not write the result for canceled requests", func() {
+				req, err := http.NewRequest("GET", "/v1/posts/1", nil)
+				Expect(err).To(BeNil())
+				ctx, cancel := context.WithCancel(req.Context())
+				cancel()
+				api.Handler().ServeHTTP(rec, req.WithContext(ctx))
+				Expect(rec.Body.String()).To(BeEmpty())
+			})
+
+			It("NotAllowedHandler implements http.Handler", func() {
+				var handler http.Handler = NotAllowedHandler{marshalers: DefaultContentMarshalers}
+				req, err := http.NewRequest("PUT", "/v1/posts", nil)
+				Expect(err).To(BeNil())
+				handler.ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+				Expect(rec.Result().Header.Get("Content-Type")).To(Equal(defaultContentTypeHeader))
+			})
+		})
+
+		Context("add resource panics with invalid resources", func() {
+			It("Should really panic", func() {
+				api := NewAPI("blub")
+				invalidDataStructure := new(invalid)
+				testFunc := func() {
+					api.AddResource(*invalidDataStructure, &userSource{})
+				}
+
+				Expect(testFunc).To(Panic())
+			})
+		})
+
+		Context("test utility function getPointerToStruct", func() {
+			type someStruct struct {
+				someEntry string
+			}
+
+			It("Should work as expected", func() {
+				testItem := someStruct{}
+				actual := getPointerToStruct(testItem)
+				Expect(&testItem).To(Equal(actual))
+			})
+
+			It("should not fail when using a pointer", func() {
+				testItem := &someStruct{}
+				actual := getPointerToStruct(testItem)
+				Expect(&testItem).To(Equal(actual))
+			})
+		})
+	})
+
+	Context("When using middleware", func() {
+		var (
+			api    *API
+			rec    *httptest.ResponseRecorder
+			source *fixtureSource
+		)
+
+		BeforeEach(func() {
+			source = &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false}
+
+			api = NewAPI("v1")
+			api.AddResource(Post{}, source)
+			MiddleTest := func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Add("x-test", "test123")
+					next.ServeHTTP(w, r)
+				})
+			}
+			api.UseMiddleware(MiddleTest)
+			rec = httptest.NewRecorder()
+		})
+
+		It("Should call the middleware and set value", func() {
+			rec = httptest.NewRecorder()
+			req, err := http.NewRequest("OPTIONS", "/v1/posts", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Header().Get("x-test")).To(Equal("test123"))
+		})
+	})
+
+	Context("Custom context", func() {
+		var (
+			api *API
+			//customContextCalled bool = false
+			rec    *httptest.ResponseRecorder
+			source *fixtureSource
+		)
+		type CustomContext struct {
+			context.Context
+		}
+
+		BeforeEach(func() {
+			source = &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false}
+
+			api = NewAPI("v1")
+			api.AddResource(Post{}, source)
+			rec = httptest.NewRecorder()
+		})
+
+		// It("calls into custom context allocator", func() {
+		// 	rec = httptest.NewRecorder()
+		// 	req, err := http.NewRequest("OPTIONS", "/v1/posts", nil)
+		// 	Expect(err).To(BeNil())
+		// 	api.Handler().ServeHTTP(rec, req)
+		// 	Expect(customContextCalled).To(BeTrue())
+		// })
+
+	})
+
+	Context("dynamic baseurl handling", func() {
+		var (
+			api    *API
+			rec    *httptest.ResponseRecorder
+			source *fixtureSource
+		)
+
+		BeforeEach(func() {
+			source = &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false}
+
+			marshalers := map[string]ContentMarshaler{
+				`application/vnd.api+json`: JSONContentMarshaler{},
+			}
+
+			api = NewAPIWithMarshalling("/secret/", &requestURLResolver{}, marshalers, nil)
+			api.AddResource(Post{}, source)
+			rec = httptest.NewRecorder()
+		})
+
+		It("should change dependening on request header in FindAll", func() {
+			firstURI := "https://god-mode.example.com"
+			secondURI := "https://top-secret.example.com"
+			req, err := http.NewRequest("GET", "/secret/posts", nil)
+			req.Header.Set("REQUEST_URI", firstURI)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Body.Bytes()).To(ContainSubstring(firstURI))
+			Expect(rec.Body.Bytes()).ToNot(ContainSubstring(secondURI))
+			rec = httptest.NewRecorder()
+			req2, err := http.NewRequest("GET", "/secret/posts", nil)
+			req2.Header.Set("REQUEST_URI", secondURI)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req2)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Body.Bytes()).To(ContainSubstring(secondURI))
+			Expect(rec.Body.Bytes()).ToNot(ContainSubstring(firstURI))
+		})
+
+		It("should change dependening on request header in FindOne", func() {
+			expected := "https://god-mode.example.com"
+			req, err := http.NewRequest("GET", "/secret/posts/1", nil)
+			req.Header.Set("REQUEST_URI", expected)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Body.Bytes()).To(ContainSubstring(expected))
+		})
+	})
+
+	Context("strict query params", func() {
+		var (
+			api *API
+			rec *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			source := &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false}
+
+			api = NewAPI("v1")
+			api.AddResource(Post{}, source)
+			api.EnableStrictQueryParams()
+			rec = httptest.NewRecorder()
+		})
+
+		It("accepts jsonapi query params", func() {
+			req, err := http.NewRequest("GET", "/v1/posts?filter[title]=Hello&sort=title&include=author&fields[posts]=title&page[number]=1&page[size]=1", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+
+		It("rejects unknown query params", func() {
+			req, err := http.NewRequest("GET", "/v1/posts?limit=1&filter[title]=Hello&unicorn[name]=bob", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors":[
+				{"status":"400","code":"UNKNOWN_QUERY_PARAM","title":"Query parameter \"limit\" is not supported","source":{"parameter":"limit"}},
+				{"status":"400","code":"UNKNOWN_QUERY_PARAM","title":"Query parameter \"unicorn[name]\" is not supported","source":{"parameter":"unicorn[name]"}}
+			]}`))
+		})
+	})
+
+	Context("bulk relationship updates", func() {
+		var (
+			api    *API
+			rec    *httptest.ResponseRecorder
+			source *bulkSource
+		)
+
+		BeforeEach(func() {
+			source = &bulkSource{fixtureSource: &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+				"2": {ID: "2", Title: "Goodbye, World!"},
+			}, false}}
+
+			api = NewAPI("v1")
+			api.AddResource(Post{}, source)
+			rec = httptest.NewRecorder()
+		})
+
+		It("passes all updates to PatchRelationships", func() {
+			reqBody := strings.NewReader(`{"data": [
+				{"type": "posts", "id": "1", "relationships": {"comments": {"data": [{"type": "comments", "id": "1"}, {"type": "comments", "id": "2"}]}}},
+				{"type": "posts", "id": "2", "relationships": {"comments": {"data": []}}}
+			]}`)
+			req, err := http.NewRequest("PATCH", "/v1/posts/relationships/comments", reqBody)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(source.relName).To(Equal("comments"))
+			Expect(source.updates).To(Equal([]RelationshipUpdate{
+				{ResourceID: "1", NewIDs: []string{"1", "2"}},
+				{ResourceID: "2", NewIDs: []string{}},
+			}))
+		})
+
+		It("supports to-one relationships", func() {
+			reqBody := strings.NewReader(`{"data": [
+				{"type": "posts", "id": "1", "relationships": {"author": {"data": {"type": "users", "id": "3"}}}},
+				{"type": "posts", "id": "2", "relationships": {"author": {"data": null}}}
+			]}`)
+			req, err := http.NewRequest("PATCH", "/v1/posts/relationships/author", reqBody)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(source.updates).To(Equal([]RelationshipUpdate{
+				{ResourceID: "1", NewIDs: []string{"3"}},
+				{ResourceID: "2", NewIDs: []string{}},
+			}))
+		})
+
+		It("rejects data that is not an array", func() {
+			reqBody := strings.NewReader(`{"data": {"type": "posts", "id": "1"}}`)
+			req, err := http.NewRequest("PATCH", "/v1/posts/relationships/comments", reqBody)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(source.updates).To(BeNil())
+		})
+	})
+
+	Context("full-text search", func() {
+		var (
+			api *API
+			rec *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			source := &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+				"2": {ID: "2", Title: "Goodbye, World!"},
+			}, false}
+
+			api = NewAPI("v1")
+			api.AddResource(Post{}, searchableSource{source})
+			rec = httptest.NewRecorder()
+		})
+
+		It("calls Search if filter[q] is set", func() {
+			req, err := http.NewRequest("GET", "/v1/posts?filter[q]=Goodbye", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			var result map[string]interface{}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+			Expect(result["data"]).To(HaveLen(1))
+			Expect(rec.Body.String()).To(ContainSubstring("Goodbye, World!"))
+		})
+
+		It("calls Search instead of PaginatedFindAll with page params", func() {
+			req, err := http.NewRequest("GET", "/v1/posts?filter[q]=Goodbye&page[number]=1&page[size]=10", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			var result map[string]interface{}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+			Expect(result["data"]).To(HaveLen(1))
+			Expect(rec.Body.String()).To(ContainSubstring("Goodbye, World!"))
+		})
+
+		It("calls FindAll without filter[q]", func() {
+			req, err := http.NewRequest("GET", "/v1/posts", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			var result map[string]interface{}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+			Expect(result["data"]).To(HaveLen(2))
+		})
+	})
+
+	Context("sub resources", func() {
+		var (
+			api    *API
+			rec    *httptest.ResponseRecorder
+			source *pathParamsSource
+		)
+
+		BeforeEach(func() {
+			source = &pathParamsSource{fixtureSource: &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false}}
+
+			api = NewAPI("v1")
+			api.AddResource(User{}, &userSource{})
+			api.AddSubResource(User{}, Post{}, source)
+			rec = httptest.NewRecorder()
+		})
+
+		It("lists the sub resources of a parent", func() {
+			req, err := http.NewRequest("GET", "/v1/users/42/posts", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(source.pathParams).To(Equal(map[string]string{"parentID": "42"}))
+		})
+
+		It("reads a single sub resource", func() {
+			req, err := http.NewRequest("GET", "/v1/users/42/posts/1", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring("Hello, World!"))
+			Expect(source.pathParams).To(Equal(map[string]string{"parentID": "42"}))
+		})
+
+		It("creates a sub resource", func() {
+			reqBody := strings.NewReader(`{"data": {"type": "posts", "attributes": {"title": "New Post"}}}`)
+			req, err := http.NewRequest("POST", "/v1/users/42/posts", reqBody)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(rec.Header().Get("Location")).To(Equal("/v1/users/42/posts/2"))
+			Expect(source.pathParams).To(Equal(map[string]string{"parentID": "42"}))
+		})
+
+		It("keeps the routes of the parent", func() {
+			req, err := http.NewRequest("OPTIONS", "/v1/users/42", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+		})
+
+		It("does not register the sub resource at the top level", func() {
+			req, err := http.NewRequest("GET", "/v1/posts/1", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Context("after fetch", func() {
+		var (
+			api *API
+			rec *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			source := &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+				"2": {ID: "2", Title: "Goodbye, World!"},
+			}, false}
+			api = NewAPI("v1")
+			api.AddResource(Post{}, afterFetchSource{fixtureSource: source})
+			api.AddResource(Post{}, afterFetchSource{source, NewHTTPError(nil, "not allowed", http.StatusForbidden)}, WithName("failing"))
+			rec = httptest.NewRecorder()
+		})
+
+		doRequest := func(URL string) map[string]interface{} {
+			req, err := http.NewRequest("GET", URL, nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			var result map[string]interface{}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+			return result
+		}
+
+		It("transforms the result of FindAll", func() {
+			result := doRequest("/v1/posts")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(result["data"]).To(HaveLen(1))
+			Expect(rec.Body.String()).To(ContainSubstring("Hello, World!"))
+			Expect(rec.Body.String()).ToNot(ContainSubstring("Goodbye, World!"))
+		})
+
+		It("transforms the result of PaginatedFindAll before responding", func() {
+			result := doRequest("/v1/posts?page[number]=1&page[size]=1")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(result["data"]).To(HaveLen(1))
+			Expect(rec.Body.String()).ToNot(ContainSubstring("Goodbye, World!"))
+			Expect(result["meta"]).To(HaveKeyWithValue("total", float64(2)))
+		})
+
+		It("does not change single objects", func() {
+			doRequest("/v1/posts/2")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring("Goodbye, World!"))
+		})
+
+		It("returns the error of AfterFetch", func() {
+			doRequest("/v1/failing")
+			Expect(rec.Code).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Context("field masking", func() {
+		var (
+			api    *API
+			rec    *httptest.ResponseRecorder
+			source *fixtureSource
+		)
+
+		BeforeEach(func() {
+			source = &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+				"2": {ID: "2", Title: "Goodbye, World!"},
+			}, false}
+			rec = httptest.NewRecorder()
+		})
+
+		doRequest := func(URL, role string) {
+			req, err := http.NewRequest("GET", URL, nil)
+			Expect(err).To(BeNil())
+			req.Header.Set("X-Role", role)
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		}
+
+		for _, pointers := range []bool{false, true} {
+			pointers := pointers
+
+			Context(fmt.Sprintf("with pointers %t", pointers), func() {
+				BeforeEach(func() {
+					source.pointers = pointers
+					api = NewAPI("v1")
+					if pointers {
+						api.AddResource(&Post{}, maskingSource{source})
+					} else {
+						api.AddResource(Post{}, maskingSource{source})
+					}
+				})
+
+				It("masks fields of a single object", func() {
+					doRequest("/v1/posts/1", "user")
+					Expect(rec.Body.String()).ToNot(ContainSubstring("Hello, World!"))
+					Expect(rec.Body.String()).To(ContainSubstring(`"id":"1"`))
+				})
+
+				It("masks fields of all objects", func() {
+					doRequest("/v1/posts", "user")
+					Expect(rec.Body.String()).ToNot(ContainSubstring("World!"))
+					var result map[string]interface{}
+					Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+					Expect(result["data"]).To(HaveLen(2))
+				})
+
+				It("keeps fields the requester may see", func() {
+					doRequest("/v1/posts/1", "admin")
+					Expect(rec.Body.String()).To(ContainSubstring("Hello, World!"))
+					Expect(source.posts["1"].Title).To(Equal("Hello, World!"))
+				})
+			})
+		}
+	})
+
+	Context("empty to-one relationships", func() {
+		It("are rendered with null data", func() {
+			source := &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false}
+			api := NewAPI("v1")
+			api.AddResource(Post{}, source)
+			rec := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", "/v1/posts/1", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+
+			var result struct {
+				Data struct {
+					Relationships map[string]map[string]interface{}
+				}
+			}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+			Expect(result.Data.Relationships["author"]).To(HaveKeyWithValue("data", BeNil()))
+		})
+	})
+
+	Context("mounting at a http.ServeMux", func() {
+		var (
+			api *API
+			mux *http.ServeMux
+			rec *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			source := &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false}
+			api = NewAPI("v1")
+			api.AddResource(Post{}, source)
+			mux = http.NewServeMux()
+			rec = httptest.NewRecorder()
+		})
+
+		It("strips everything in front of the prefix", func() {
+			api.MountAt(mux, "/api/v1/")
+			req, err := http.NewRequest("GET", "/api/v1/posts/1", nil)
+			Expect(err).To(BeNil())
+			mux.ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring("Hello, World!"))
+		})
+
+		It("can be mounted at the prefix", func() {
+			api.MountAt(mux, "/v1")
+			req, err := http.NewRequest("GET", "/v1/posts/1", nil)
+			Expect(err).To(BeNil())
+			mux.ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+
+		It("panics if the pattern does not match the prefix", func() {
+			Expect(func() {
+				api.MountAt(mux, "/api/v2/")
+			}).To(Panic())
+		})
+	})
+
+	Context("changing the base path", func() {
+		var (
+			api    *API
+			source *fixtureSource
+			rec    *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			source = &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false}
+			api = NewAPI("v1")
+			api.AddResource(Post{}, source)
+			rec = httptest.NewRecorder()
+		})
+
+		doRequest := func(URL string) {
+			req, err := http.NewRequest("GET", URL, nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+		}
+
+		It("registers the routes below the new prefix", func() {
+			api.SetBasePath("/api/v2")
+			doRequest("/api/v2/posts/1")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring(`"self":"/api/v2/posts/1/relationships/author"`))
+		})
+
+		It("registers resources that are added later below the new prefix", func() {
+			api.SetBasePath("v2")
+			api.AddResource(Post{}, source, WithName("articles"))
+			doRequest("/v2/articles/1")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+
+		It("registers the ping route below the new prefix", func() {
+			api.AddPing()
+			api.SetBasePath("v2")
+			doRequest("/v2/ping")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+
+		It("can switch back to a previous prefix", func() {
+			api.AddPing()
+			api.SetBasePath("v2")
+			api.SetBasePath("v1")
+			doRequest("/v1/posts/1")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring(`"self":"/v1/posts/1/relationships/author"`))
+		})
+	})
+
+	Context("default headers", func() {
+		var (
+			api *API
+			rec *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			api = NewAPI("v1")
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false})
+			api.SetDefaultHeader("X-Content-Type-Options", "nosniff")
+			api.SetDefaultHeader("X-Frame-Options", "DENY")
+			rec = httptest.NewRecorder()
+		})
+
+		doRequest := func(method, URL string) {
+			req, err := http.NewRequest(method, URL, nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+		}
+
+		It("are set on successful responses", func() {
+			doRequest("GET", "/v1/posts/1")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get("X-Content-Type-Options")).To(Equal("nosniff"))
+			Expect(rec.Header().Get("X-Frame-Options")).To(Equal("DENY"))
+		})
+
+		It("are set on errors", func() {
+			doRequest("GET", "/v1/unicorns")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(rec.Header().Get("X-Content-Type-Options")).To(Equal("nosniff"))
+		})
+
+		It("can be overwritten by handlers", func() {
+			api.SetDefaultHeader("Content-Type", "text/plain")
+			doRequest("GET", "/v1/posts/1")
+			Expect(rec.Header().Get("Content-Type")).To(Equal(defaultContentTypeHeader))
+		})
+
+		It("include security headers", func() {
+			doRequest("GET", "/v1/posts/1")
+			Expect(rec.Header().Get("X-XSS-Protection")).To(Equal("1; mode=block"))
+		})
+
+		It("take precedence over security headers", func() {
+			api.SetDefaultHeader("X-Frame-Options", "SAMEORIGIN")
+			doRequest("GET", "/v1/posts/1")
+			Expect(rec.Header().Get("X-Frame-Options")).To(Equal("SAMEORIGIN"))
+		})
+
+		It("do not include security headers if they are disabled", func() {
+			plain := NewAPI("v1")
+			plain.AddResource(Post{}, &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false})
+			plain.DisableDefaultSecurityHeaders()
+			req, err := http.NewRequest("GET", "/v1/posts/1", nil)
+			Expect(err).To(BeNil())
+			plain.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get("X-Content-Type-Options")).To(BeEmpty())
+			Expect(rec.Header().Get("X-Frame-Options")).To(BeEmpty())
+			Expect(rec.Header().Get("X-XSS-Protection")).To(BeEmpty())
+		})
+	})
+
+	Context("pretty printing", func() {
+		var (
+			api *API
+			rec *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			api = NewAPI("v1")
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false})
+			rec = httptest.NewRecorder()
+		})
+
+		doRequest := func(URL string) {
+			req, err := http.NewRequest("GET", URL, nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+		}
+
+		It("returns compact json by default", func() {
+			doRequest("/v1/posts/1")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(HavePrefix(`{"data":{`))
+		})
+
+		It("indents responses and errors", func() {
+			api.EnablePrettyPrint()
+			doRequest("/v1/posts/1")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(HavePrefix("{\n  \"data\": {\n    "))
+
+			rec = httptest.NewRecorder()
+			doRequest("/v1/unicorns")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(rec.Body.String()).To(HavePrefix("{\n  \"errors\": [\n"))
+		})
+
+		It("does not change the marshalers of other apis", func() {
+			api.EnablePrettyPrint()
+			Expect(DefaultContentMarshalers[defaultContentTypeHeader]).To(Equal(JSONContentMarshaler{}))
+
+			other := NewAPI("v1")
+			other.AddResource(Post{}, &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false})
+			req, err := http.NewRequest("GET", "/v1/posts/1", nil)
+			Expect(err).To(BeNil())
+			other.Handler().ServeHTTP(rec, req)
+			Expect(rec.Body.String()).To(HavePrefix(`{"data":{`))
+		})
+	})
+
+	Context("cloning", func() {
+		var (
+			api *API
+			rec *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			api = NewAPIWithBaseURL("v1", "http://localhost")
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false})
+			api.UseMiddleware(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("X-Middleware", "called")
+					next.ServeHTTP(w, r)
+				})
+			})
+			api.AddProfile("https://example.com/profile")
+			api.SetDefaultHeader("X-Frame-Options", "DENY")
+			rec = httptest.NewRecorder()
+		})
+
+		doRequest := func(handler http.Handler, URL string) {
+			req, err := http.NewRequest("GET", URL, nil)
+			Expect(err).To(BeNil())
+			handler.ServeHTTP(rec, req)
+		}
+
+		It("does not copy the resources", func() {
+			clone := api.Clone()
+			doRequest(clone.Handler(), "/v1/posts/1")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(clone.resources).To(BeEmpty())
+		})
+
+		It("keeps the configuration", func() {
+			clone := api.Clone()
+			clone.AddResource(Post{}, &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Mocked"},
+			}, false})
+			doRequest(clone.Handler(), "/v1/posts/1")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring("Mocked"))
+			Expect(rec.Body.String()).To(ContainSubstring("http://localhost/v1/posts/1/relationships/author"))
+			Expect(rec.Header().Get("X-Middleware")).To(Equal("called"))
+			Expect(clone.profiles).To(HaveKey("https://example.com/profile"))
+			Expect(clone.defaultHeaders.Get("X-Frame-Options")).To(Equal("DENY"))
+		})
+
+		It("does not modify the original api", func() {
+			clone := api.Clone()
+			clone.AddResource(User{}, &userSource{})
+			clone.UseMiddleware(func(next http.Handler) http.Handler {
+				return next
+			})
+			Expect(api.resources).To(HaveLen(1))
+			Expect(api.middlewares).To(HaveLen(2))
+
+			doRequest(api.Handler(), "/v1/posts/1")
+			Expect(rec.Body.String()).To(ContainSubstring("Hello, World!"))
+		})
+
+		It("panics with a custom router", func() {
+			router := wrappedRouter{routing.NewHTTPRouter("v1", NotAllowedHandler{marshalers: DefaultContentMarshalers})}
+			custom := NewAPIWithRouting("v1", NewStaticResolver(""), DefaultContentMarshalers, router)
+			Expect(func() { custom.Clone() }).To(Panic())
+		})
+	})
+
+	Context("router", func() {
+		doRequest := func(api *API, URL string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", URL, nil)
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+			return rec
+		}
+
+		It("allows to add routes", func() {
+			api := NewAPI("v1")
+			api.Router().Handle("GET", "/v1/version", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("1.0.0"))
+			})
+
+			rec := doRequest(api, "/v1/version")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(Equal("1.0.0"))
+			Expect(rec.Header().Get("X-Content-Type-Options")).To(Equal("nosniff"))
+		})
+
+		It("can be replaced", func() {
+			api := NewAPI("v1")
+			router := routing.NewHTTPRouter("v1", NotAllowedHandler{marshalers: DefaultContentMarshalers})
+			api.SetRouter(router)
+			Expect(api.Router()).To(BeIdenticalTo(router))
+
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}, false})
+			Expect(doRequest(api, "/v1/posts/1").Code).To(Equal(http.StatusOK))
+		})
+
+		It("serves requests as http.Handler", func() {
+			api := NewAPI("v1")
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}, false})
+
+			var handler http.Handler = api
+			rec := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", "/v1/posts/1", nil)
+			Expect(err).ToNot(HaveOccurred())
+			handler.ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get("X-Content-Type-Options")).To(Equal("nosniff"))
+		})
+
+		It("builds the handler once and again after UseMiddleware", func() {
+			api := NewAPI("v1")
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}, false})
+			built := 0
+			api.UseMiddleware(func(next http.Handler) http.Handler {
+				built++
+				return next
+			})
+
+			serve := func() *httptest.ResponseRecorder {
+				rec := httptest.NewRecorder()
+				req, err := http.NewRequest("GET", "/v1/posts/1", nil)
+				Expect(err).ToNot(HaveOccurred())
+				api.ServeHTTP(rec, req)
+				return rec
+			}
+
+			serve()
+			serve()
+			Expect(built).To(Equal(1))
+
+			api.UseMiddleware(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("X-Middleware", "true")
+					next.ServeHTTP(w, r)
+				})
+			})
+			Expect(serve().Header().Get("X-Middleware")).To(Equal("true"))
+			Expect(built).To(Equal(2))
+		})
+	})
+
+	Context("schema url", func() {
+		var (
+			api *API
+			rec *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			api = NewAPI("v1")
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false})
+			api.AddResource(User{}, schemaSource{&userSource{}})
+			rec = httptest.NewRecorder()
+		})
+
+		getLinks := func(URL string) map[string]interface{} {
+			rec = httptest.NewRecorder()
+			req, err := http.NewRequest("GET", URL, nil)
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			var document struct {
+				Links map[string]interface{} `json:"links"`
+			}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+			return document.Links
+		}
+
+		It("is not added by default", func() {
+			Expect(getLinks("/v1/posts/1")).ToNot(HaveKey("describedby"))
+		})
+
+		It("is added to every response document", func() {
+			api.SetSchemaURL("https://example.com/openapi.json")
+			Expect(getLinks("/v1/posts/1")).To(Equal(map[string]interface{}{
+				"self":        "/v1/posts/1",
+				"describedby": "https://example.com/openapi.json",
+			}))
+			Expect(getLinks("/v1/posts")["describedby"]).To(Equal("https://example.com/openapi.json"))
+			Expect(getLinks("/v1/posts?page[number]=1&page[size]=1")).To(And(
+				HaveKeyWithValue("describedby", "https://example.com/openapi.json"),
+				HaveKeyWithValue("self", "/v1/posts?page[number]=1&page[size]=1"),
+			))
+		})
+
+		It("can be set per resource", func() {
+			api.SetSchemaURL("https://example.com/openapi.json")
+			Expect(getLinks("/v1/users?postsID=1")["describedby"]).To(Equal("https://example.com/schemas/users.json"))
+
+			description, ok := api.Describe("users")
+			Expect(ok).To(BeTrue())
+			Expect(description.Interfaces).To(ContainElement("SchemaURLProvider"))
+		})
+	})
+
+	Context("clearing resources", func() {
+		var (
+			api *API
+			rec *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			api = NewAPI("v1")
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false})
+			api.SetRedirectTrailingSlash(false)
+			rec = httptest.NewRecorder()
+		})
+
+		doRequest := func(handler http.Handler, URL string) {
+			rec = httptest.NewRecorder()
+			req, err := http.NewRequest("GET", URL, nil)
+			Expect(err).To(BeNil())
+			handler.ServeHTTP(rec, req)
+		}
+
+		It("removes the resources and their routes", func() {
+			handler := api.Handler()
+			api.ClearResources()
+			Expect(api.resources).To(BeEmpty())
+
+			doRequest(handler, "/v1/posts/1")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(rec.Header().Get("Content-Type")).To(Equal(defaultContentTypeHeader))
+		})
+
+		It("allows to register the resources again", func() {
+			handler := api.Handler()
+			api.ClearResources()
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Mocked"},
+			}, false})
+
+			doRequest(handler, "/v1/posts/1")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring("Mocked"))
+
+			doRequest(handler, "/v1/posts/1/")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("keeps the routes that do not belong to resources", func() {
+			api.AddPing()
+			handler := api.Handler()
+			api.ClearResources()
+
+			doRequest(handler, "/v1/ping")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(MatchJSON(`{"data": null, "meta": {"pong": true}}`))
+
+			doRequest(handler, "/v1/posts/1")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("panics with a router that can not be reset", func() {
+			router := wrappedRouter{routing.NewHTTPRouter("v1", NotAllowedHandler{marshalers: DefaultContentMarshalers})}
+			custom := NewAPIWithRouting("v1", NewStaticResolver(""), DefaultContentMarshalers, router)
+			Expect(func() { custom.ClearResources() }).To(Panic())
+		})
+	})
+
+	Context("registered sources", func() {
+		It("returns the source of a resource", func() {
+			source := &fixtureSource{map[string]*Post{}, false}
+			api := NewAPI("v1")
+			api.AddResource(Post{}, source)
+			registered, ok := api.Source("posts")
+			Expect(ok).To(BeTrue())
+			Expect(registered).To(BeIdenticalTo(source))
+		})
+
+		It("returns false for unknown resources", func() {
+			api := NewAPI("v1")
+			registered, ok := api.Source("posts")
+			Expect(ok).To(BeFalse())
+			Expect(registered).To(BeNil())
+		})
+
+		It("counts the registered resources", func() {
+			api := NewAPI("v1")
+			Expect(api.ResourceCount()).To(Equal(0))
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false})
+			api.AddResource(Comment{}, &commentSource{})
+			Expect(api.ResourceCount()).To(Equal(2))
+		})
+
+		It("checks if a resource is registered", func() {
+			api := NewAPI("v1")
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false})
+			Expect(api.HasResource("posts")).To(BeTrue())
+			Expect(api.HasResource("comments")).To(BeFalse())
+		})
+
+		It("panics if a resource is registered twice", func() {
+			api := NewAPI("v1")
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false}, WithName("articles"))
+			Expect(func() {
+				api.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false}, WithName("articles"))
+			}).To(PanicWith("resource 'articles' already registered"))
+
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false})
+			Expect(api.ResourceCount()).To(Equal(2))
+		})
+	})
+
+	Context("versioned resources", func() {
+		var (
+			api    *API
+			rec    *httptest.ResponseRecorder
+			first  *fixtureSource
+			second *fixtureSource
+		)
+
+		BeforeEach(func() {
+			first = &fixtureSource{map[string]*Post{"1": {ID: "1", Title: "First"}}, false}
+			second = &fixtureSource{map[string]*Post{"1": {ID: "1", Title: "Second"}}, false}
+			api = NewAPI("api")
+			api.AddVersionedResource("v1", Post{}, first)
+			api.AddVersionedResource("/v2/", Post{}, second)
+			rec = httptest.NewRecorder()
+		})
+
+		doRequest := func(method, URL, body string) {
+			req, err := http.NewRequest(method, URL, strings.NewReader(body))
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+		}
+
+		It("serves every version from its own source", func() {
+			doRequest("GET", "/api/v1/posts/1", "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring("First"))
+			Expect(rec.Body.String()).To(ContainSubstring(`"self":"/api/v1/posts/1/relationships/author"`))
+
+			rec = httptest.NewRecorder()
+			doRequest("GET", "/api/v2/posts/1", "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring("Second"))
+			Expect(rec.Body.String()).To(ContainSubstring(`"self":"/api/v2/posts/1/relationships/author"`))
+
+			rec = httptest.NewRecorder()
+			doRequest("GET", "/api/posts/1", "")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("sets the versioned Location header", func() {
+			doRequest("POST", "/api/v2/posts", `{"data": {"type": "posts", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(rec.Header().Get("Location")).To(Equal("/api/v2/posts/2"))
+			Expect(first.posts).To(HaveLen(1))
+			Expect(second.posts).To(HaveLen(2))
+		})
+
+		It("can add a resource once per version", func() {
+			Expect(api.ResourceCount()).To(Equal(2))
+			Expect(func() { api.AddVersionedResource("v1", Post{}, first) }).To(PanicWith("resource 'v1/posts' already registered"))
+		})
+	})
 
-			It("No next and last on offset = 5, limit = 2", func() {
-				links := doRequest("/v1/posts?page[offset]=5&page[limit]=2")
-				Expect(links).To(HaveLen(2))
-				Expect(links["prev"]).To(Equal("/v1/posts?page[limit]=2&page[offset]=3"))
-				Expect(links["first"]).To(Equal("/v1/posts?page[limit]=2&page[offset]=0"))
-			})
+	Context("context keys", func() {
+		It("do not collide with string keys of other packages", func() {
+			ctx := context.WithValue(context.Background(), "API:INFO", "foreign")
+			Expect(ctx.Value(api_info)).To(BeNil())
 
-			It("Does not generate links if results fit on one page", func() {
-				links := doRequest("/v1/posts?page[offset]=0&page[limit]=10")
-				Expect(links).To(HaveLen(0))
-			})
+			ctx = context.WithValue(ctx, api_info, NewInformation("v1", NewStaticResolver("")))
+			Expect(ctx.Value("API:INFO")).To(Equal("foreign"))
+			Expect(ctx.Value(api_info)).To(BeAssignableToTypeOf(Information{}))
 		})
 
-		// Context("error codes", func() {
-		// 	It("Should return the correct header on method not allowed", func() {
-		// 		reqBody := strings.NewReader("")
-		// 		req, err := http.NewRequest("PATCH", "/v1/posts", reqBody)
-		// 		Expect(err).To(BeNil())
-		// 		api.Handler().ServeHTTP(rec, req)
-		// 		expected := `{"errors":[{"status":"405","title":"Method Not Allowed"}]}`
-		// 		fmt.Println(rec.Body.String())
-		// 		Expect(rec.Body.String()).To(MatchJSON(expected))
-		// 		Expect(rec.Header().Get("Content-Type")).To(Equal(defaultContentTypeHeader))
-		// 		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
-		// 	})
-		// })
+		It("keeps API_ERROR as string", func() {
+			var key string = API_ERROR
+			Expect(key).To(Equal("API:ERROR"))
+		})
+	})
 
-		Context("add resource panics with invalid resources", func() {
-			It("Should really panic", func() {
-				api := NewAPI("blub")
-				invalidDataStructure := new(invalid)
-				testFunc := func() {
-					api.AddResource(*invalidDataStructure, &userSource{})
-				}
+	Context("warm up", func() {
+		var (
+			api         *API
+			initialized []string
+		)
 
-				Expect(testFunc).To(Panic())
-			})
+		BeforeEach(func() {
+			api = NewAPI("v1")
+			initialized = []string{}
 		})
 
-		Context("test utility function getPointerToStruct", func() {
-			type someStruct struct {
-				someEntry string
+		initializer := func(name string, err error) initializingSource {
+			return initializingSource{
+				fixtureSource: &fixtureSource{map[string]*Post{}, false},
+				initialize: func(ctx context.Context) error {
+					initialized = append(initialized, name)
+					return err
+				},
 			}
+		}
 
-			It("Should work as expected", func() {
-				testItem := someStruct{}
-				actual := getPointerToStruct(testItem)
-				Expect(&testItem).To(Equal(actual))
-			})
+		It("initializes all sources in order", func() {
+			api.AddResource(Post{}, initializer("posts", nil))
+			api.AddResource(Comment{}, &commentSource{})
+			api.AddResource(Post{}, initializer("articles", nil), WithName("articles"))
+			Expect(api.WarmUp(context.Background())).To(Succeed())
+			Expect(initialized).To(Equal([]string{"posts", "articles"}))
+		})
 
-			It("should not fail when using a pointer", func() {
-				testItem := &someStruct{}
-				actual := getPointerToStruct(testItem)
-				Expect(&testItem).To(Equal(actual))
-			})
+		It("returns the first error", func() {
+			api.AddResource(Post{}, initializer("posts", errors.New("no connection")))
+			api.AddResource(Post{}, initializer("articles", nil), WithName("articles"))
+			Expect(api.WarmUp(context.Background())).To(MatchError("no connection"))
+			Expect(initialized).To(Equal([]string{"posts"}))
+		})
+
+		It("stops when the context is done", func() {
+			api.AddResource(Post{}, initializer("posts", nil))
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			Expect(api.WarmUp(ctx)).To(MatchError(context.Canceled))
+			Expect(initialized).To(BeEmpty())
 		})
 	})
 
-	Context("When using middleware", func() {
+	Context("deprecated resources", func() {
 		var (
 			api    *API
 			rec    *httptest.ResponseRecorder
 			source *fixtureSource
+			sunset time.Time
 		)
 
 		BeforeEach(func() {
@@ -1494,59 +3455,150 @@ var _ = Describe("RestHandler", func() {
 				"1": {ID: "1", Title: "Hello, World!"},
 			}, false}
 
+			sunset = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
 			api = NewAPI("v1")
 			api.AddResource(Post{}, source)
-			MiddleTest := func(next http.Handler) http.Handler {
-				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					w.Header().Add("x-test", "test123")
-					next.ServeHTTP(w, r)
-				})
-			}
-			api.UseMiddleware(MiddleTest)
+			api.AddResource(User{}, &userSource{})
+			api.DeprecateResourceSince("posts", time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC), sunset, "https://example.com/deprecation")
 			rec = httptest.NewRecorder()
 		})
 
-		It("Should call the middleware and set value", func() {
-			rec = httptest.NewRecorder()
-			req, err := http.NewRequest("OPTIONS", "/v1/posts", nil)
+		It("adds deprecation headers to a deprecated resource", func() {
+			req, err := http.NewRequest("GET", "/v1/posts/1", nil)
 			Expect(err).To(BeNil())
 			api.Handler().ServeHTTP(rec, req)
-			Expect(rec.Header().Get("x-test")).To(Equal("test123"))
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get("Deprecation")).To(Equal("Sat, 01 Jun 2024 00:00:00 GMT"))
+			Expect(rec.Header().Get("Sunset")).To(Equal("Tue, 01 Jan 2030 00:00:00 GMT"))
+			Expect(rec.Header().Get("Link")).To(Equal(`<https://example.com/deprecation>; rel="deprecation"`))
+		})
+
+		It("sends Deprecation true without date", func() {
+			api.DeprecateResource("posts", sunset, "")
+			req, err := http.NewRequest("GET", "/v1/posts/1", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Header().Get("Deprecation")).To(Equal("true"))
+			Expect(rec.Header().Get("Link")).To(BeEmpty())
+		})
+
+		It("omits Sunset without date", func() {
+			api.DeprecateResource("posts", time.Time{}, "")
+			req, err := http.NewRequest("GET", "/v1/posts/1", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Header().Get("Deprecation")).To(Equal("true"))
+			Expect(rec.Header()).ToNot(HaveKey("Sunset"))
+		})
+
+		It("deprecates every version of the resource", func() {
+			api.AddVersionedResource("v2", Post{}, source)
+			api.DeprecateResource("posts", sunset, "")
+			req, err := http.NewRequest("GET", "/v1/v2/posts/1", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get("Deprecation")).To(Equal("true"))
+		})
+
+		It("does not add deprecation headers to other resources", func() {
+			req, err := http.NewRequest("OPTIONS", "/v1/users", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Header().Get("Deprecation")).To(BeEmpty())
+			Expect(rec.Header().Get("Sunset")).To(BeEmpty())
+		})
+
+		It("panics for unknown resources", func() {
+			Expect(func() {
+				api.DeprecateResource("unicorns", sunset, "")
+			}).To(Panic())
 		})
 	})
 
-	Context("Custom context", func() {
+	Context("removed resources", func() {
 		var (
-			api *API
-			//customContextCalled bool = false
+			api    *API
 			rec    *httptest.ResponseRecorder
 			source *fixtureSource
 		)
-		type CustomContext struct {
-			context.Context
-		}
 
 		BeforeEach(func() {
 			source = &fixtureSource{map[string]*Post{
 				"1": {ID: "1", Title: "Hello, World!"},
 			}, false}
-
 			api = NewAPI("v1")
 			api.AddResource(Post{}, source)
+			api.AddResource(User{}, &userSource{})
 			rec = httptest.NewRecorder()
 		})
 
-		// It("calls into custom context allocator", func() {
-		// 	rec = httptest.NewRecorder()
-		// 	req, err := http.NewRequest("OPTIONS", "/v1/posts", nil)
-		// 	Expect(err).To(BeNil())
-		// 	api.Handler().ServeHTTP(rec, req)
-		// 	Expect(customContextCalled).To(BeTrue())
-		// })
+		doRequest := func(method, URL string) {
+			req, err := http.NewRequest(method, URL, strings.NewReader(`{"data":{"type":"posts","attributes":{"title":"New"}}}`))
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+		}
+
+		It("answers all routes with 410 Gone", func() {
+			api.DeprecateAndRemoveResource("posts", "https://example.com/v2/articles")
+			for _, route := range [][]string{
+				{"GET", "/v1/posts"},
+				{"GET", "/v1/posts/1"},
+				{"POST", "/v1/posts"},
+				{"DELETE", "/v1/posts/1"},
+				{"GET", "/v1/posts/1/relationships/author"},
+			} {
+				rec = httptest.NewRecorder()
+				doRequest(route[0], route[1])
+				Expect(rec.Code).To(Equal(http.StatusGone), route[1])
+				Expect(rec.Header().Get("Link")).To(Equal(`<https://example.com/v2/articles>; rel="successor-version"`))
+				Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"410","title":"Gone","detail":"The resource posts has been removed, use https://example.com/v2/articles instead"}]}`))
+			}
+			Expect(source.posts).To(HaveKey("1"))
+		})
 
+		It("replaces the successor link of previous calls", func() {
+			api.DeprecateResource("posts", time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC), "https://example.com/deprecation")
+			api.DeprecateAndRemoveResource("posts", "https://example.com/v2/posts")
+			api.DeprecateAndRemoveResource("posts", "https://example.com/v2/articles")
+			doRequest("GET", "/v1/posts")
+			Expect(rec.Header().Values("Link")).To(Equal([]string{
+				`<https://example.com/deprecation>; rel="deprecation"`,
+				`<https://example.com/v2/articles>; rel="successor-version"`,
+			}))
+		})
+
+		It("removes every version of the resource", func() {
+			api.AddVersionedResource("v2", Post{}, source)
+			api.DeprecateAndRemoveResource("posts", "")
+			for _, URL := range []string{"/v1/posts", "/v1/v2/posts"} {
+				rec = httptest.NewRecorder()
+				doRequest("GET", URL)
+				Expect(rec.Code).To(Equal(http.StatusGone), URL)
+			}
+		})
+
+		It("does not add a link without replacement", func() {
+			api.DeprecateAndRemoveResource("posts", "")
+			doRequest("GET", "/v1/posts")
+			Expect(rec.Code).To(Equal(http.StatusGone))
+			Expect(rec.Header().Get("Link")).To(BeEmpty())
+		})
+
+		It("does not affect other resources", func() {
+			api.DeprecateAndRemoveResource("posts", "")
+			doRequest("OPTIONS", "/v1/users")
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+		})
+
+		It("panics for unknown resources", func() {
+			Expect(func() {
+				api.DeprecateAndRemoveResource("unicorns", "")
+			}).To(Panic())
+		})
 	})
 
-	Context("dynamic baseurl handling", func() {
+	Context("tenant aware url handling", func() {
 		var (
 			api    *API
 			rec    *httptest.ResponseRecorder
@@ -1558,46 +3610,26 @@ var _ = Describe("RestHandler", func() {
 				"1": {ID: "1", Title: "Hello, World!"},
 			}, false}
 
-			marshalers := map[string]ContentMarshaler{
-				`application/vnd.api+json`: JSONContentMarshaler{},
-			}
-
-			api = NewAPIWithMarshalling("/secret/", &requestURLResolver{}, marshalers, nil)
+			api = NewAPIWithMarshalling("v1", tenantResolver{}, DefaultContentMarshalers, nil)
 			api.AddResource(Post{}, source)
 			rec = httptest.NewRecorder()
 		})
 
-		It("should change dependening on request header in FindAll", func() {
-			firstURI := "https://god-mode.example.com"
-			secondURI := "https://top-secret.example.com"
-			req, err := http.NewRequest("GET", "/secret/posts", nil)
-			req.Header.Set("REQUEST_URI", firstURI)
+		It("replaces base url and prefix for a tenant", func() {
+			req, err := http.NewRequest("GET", "/v1/posts/1", nil)
 			Expect(err).To(BeNil())
+			req.Header.Set("X-Tenant", "acme")
 			api.Handler().ServeHTTP(rec, req)
 			Expect(rec.Code).To(Equal(http.StatusOK))
-			Expect(err).ToNot(HaveOccurred())
-			Expect(rec.Body.Bytes()).To(ContainSubstring(firstURI))
-			Expect(rec.Body.Bytes()).ToNot(ContainSubstring(secondURI))
-			rec = httptest.NewRecorder()
-			req2, err := http.NewRequest("GET", "/secret/posts", nil)
-			req2.Header.Set("REQUEST_URI", secondURI)
-			Expect(err).To(BeNil())
-			api.Handler().ServeHTTP(rec, req2)
-			Expect(rec.Code).To(Equal(http.StatusOK))
-			Expect(err).ToNot(HaveOccurred())
-			Expect(rec.Body.Bytes()).To(ContainSubstring(secondURI))
-			Expect(rec.Body.Bytes()).ToNot(ContainSubstring(firstURI))
+			Expect(rec.Body.String()).To(ContainSubstring("https://acme.example.com/acme/posts/1/relationships/author"))
 		})
 
-		It("should change dependening on request header in FindOne", func() {
-			expected := "https://god-mode.example.com"
-			req, err := http.NewRequest("GET", "/secret/posts/1", nil)
-			req.Header.Set("REQUEST_URI", expected)
+		It("keeps the default information without a tenant", func() {
+			req, err := http.NewRequest("GET", "/v1/posts/1", nil)
 			Expect(err).To(BeNil())
 			api.Handler().ServeHTTP(rec, req)
 			Expect(rec.Code).To(Equal(http.StatusOK))
-			Expect(err).ToNot(HaveOccurred())
-			Expect(rec.Body.Bytes()).To(ContainSubstring(expected))
+			Expect(rec.Body.String()).To(ContainSubstring("https://example.com/v1/posts/1/relationships/author"))
 		})
 	})
 
@@ -1624,7 +3656,8 @@ var _ = Describe("RestHandler", func() {
 			api.Handler().ServeHTTP(rec, req)
 			Expect(rec.Code).To(Equal(http.StatusOK))
 			Expect(rec.Body.Bytes()).To(MatchJSON(`
-				{"data": {
+				{"links": {"self": "/posts/1?fields[posts]=title,value"},
+				"data": {
 					"id": "1",
 					"type": "posts",
 					"attributes": {
@@ -1677,7 +3710,8 @@ var _ = Describe("RestHandler", func() {
 			api.Handler().ServeHTTP(rec, req)
 			Expect(rec.Code).To(Equal(http.StatusOK))
 			Expect(rec.Body.Bytes()).To(MatchJSON(`
-				{"data": {
+				{"links": {"self": "/posts/1?fields[posts]=title&fields[users]=name"},
+				"data": {
 					"id": "1",
 					"type": "posts",
 					"attributes": {
@@ -1728,7 +3762,8 @@ var _ = Describe("RestHandler", func() {
 			api.Handler().ServeHTTP(rec, req)
 			Expect(rec.Code).To(Equal(http.StatusOK))
 			Expect(rec.Body.Bytes()).To(MatchJSON(`
-				{"data": [{
+				{"links": {"self": "/posts?fields[posts]=title&fields[users]=name"},
+				"data": [{
 					"id": "1",
 					"type": "posts",
 					"attributes": {
@@ -1800,5 +3835,265 @@ var _ = Describe("RestHandler", func() {
 			Expect(error.Errors).To(ContainElement(expectedError("fluffy", "users")))
 			Expect(error.Errors).To(ContainElement(expectedError("pink", "users")))
 		})
+
+		It("filters data given as a slice of interfaces", func() {
+			req, err := http.NewRequest("GET", "/posts?fields[posts]=title", nil)
+			Expect(err).ToNot(HaveOccurred())
+			content := map[string]interface{}{
+				"data": []interface{}{
+					map[string]interface{}{
+						"type":       "posts",
+						"id":         "1",
+						"attributes": map[string]interface{}{"title": "Nice Post", "value": 13.37},
+					},
+				},
+			}
+
+			result, err := filterSparseFields(content, req)
+			Expect(err).ToNot(HaveOccurred())
+			data := result.(map[string]interface{})["data"].([]interface{})
+			Expect(data[0].(map[string]interface{})["attributes"]).To(Equal(map[string]interface{}{"title": "Nice Post"}))
+		})
+
+		It("computes the valid fields of a resource at registration", func() {
+			Expect(api.resources[0].validFields).To(Equal(map[string]struct{}{"title": {}, "value": {}}))
+		})
+
+		It("uses the attribute names of jsonapi for the valid fields", func() {
+			type appointment struct {
+				ID      string `jsonapi:"-"`
+				Date    time.Time
+				Room    string `jsonapi:"name=location"`
+				private string
+			}
+
+			Expect(attributeNames(reflect.TypeOf(&appointment{}))).To(Equal(map[string]struct{}{"date": {}, "location": {}}))
+		})
+
+		It("accepts valid fields that are missing from the attributes", func() {
+			req, err := http.NewRequest("GET", "/posts?fields[posts]=title,value", nil)
+			Expect(err).ToNot(HaveOccurred())
+			req = req.WithContext(context.WithValue(req.Context(), api_api, api))
+			content := map[string]interface{}{
+				"data": map[string]interface{}{
+					"type":       "posts",
+					"id":         "1",
+					"attributes": map[string]interface{}{"title": "Nice Post"},
+				},
+			}
+
+			result, err := filterSparseFields(content, req)
+			Expect(err).ToNot(HaveOccurred())
+			data := result.(map[string]interface{})["data"].(map[string]interface{})
+			Expect(data["attributes"]).To(Equal(map[string]interface{}{"title": "Nice Post"}))
+		})
+
+		It("filters nested attributes with dot notation", func() {
+			type geo struct {
+				Lat float64 `json:"lat"`
+				Lng float64 `json:"lng"`
+			}
+			type address struct {
+				Street string `json:"street"`
+				City   string `json:"city"`
+				Geo    geo    `json:"geo"`
+			}
+
+			attributes := map[string]interface{}{
+				"name":    "Marvin",
+				"address": address{Street: "Main Street", City: "Springfield", Geo: geo{Lat: 1.5, Lng: 2}},
+				"settings": map[string]interface{}{
+					"theme":  "dark",
+					"locale": "en",
+				},
+			}
+
+			filtered, wrongFields := filterAttributes(attributes, []string{"name", "address.city", "address.geo.lat", "settings.theme"}, nil)
+			Expect(wrongFields).To(BeEmpty())
+			Expect(filtered).To(Equal(map[string]interface{}{
+				"name": "Marvin",
+				"address": map[string]interface{}{
+					"city": "Springfield",
+					"geo":  map[string]interface{}{"lat": json.Number("1.5")},
+				},
+				"settings": map[string]interface{}{"theme": "dark"},
+			}))
+		})
+
+		It("keeps the whole nested object if it is requested too", func() {
+			settings := map[string]interface{}{"theme": "dark", "locale": "en"}
+			attributes := map[string]interface{}{"settings": settings}
+
+			for _, fields := range [][]string{{"settings.theme", "settings"}, {"settings", "settings.theme"}} {
+				filtered, wrongFields := filterAttributes(attributes, fields, nil)
+				Expect(wrongFields).To(BeEmpty())
+				Expect(filtered).To(Equal(map[string]interface{}{"settings": settings}))
+			}
+		})
+
+		It("rejects nested paths that do not exist", func() {
+			attributes := map[string]interface{}{
+				"name":     "Marvin",
+				"settings": map[string]interface{}{"theme": "dark"},
+			}
+
+			filtered, wrongFields := filterAttributes(attributes, []string{"settings.theme", "settings.font", "name.first", "address.city"}, map[string]struct{}{"address": {}})
+			Expect(wrongFields).To(Equal([]string{"settings.font", "name.first"}))
+			Expect(filtered).To(Equal(map[string]interface{}{"settings": map[string]interface{}{"theme": "dark"}}))
+		})
+
+		Context("with projection", func() {
+			var projectable *projectableSource
+
+			BeforeEach(func() {
+				projectable = &projectableSource{fixtureSource: source}
+				api = NewAPI("")
+				api.AddResource(Post{}, projectable)
+			})
+
+			It("passes the requested fields to the source", func() {
+				req, err := http.NewRequest("GET", "/posts?fields[posts]=title,value&fields[users]=name", nil)
+				Expect(err).ToNot(HaveOccurred())
+				api.Handler().ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusOK))
+				Expect(projectable.fields).To(Equal(map[string][]string{
+					"posts": {"title", "value"},
+					"users": {"name"},
+				}))
+
+				var document struct {
+					Data []struct {
+						Attributes map[string]interface{} `json:"attributes"`
+					} `json:"data"`
+				}
+				Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+				Expect(document.Data).To(HaveLen(1))
+				Expect(document.Data[0].Attributes).To(Equal(map[string]interface{}{"title": "Nice Post", "value": 13.37}))
+			})
+
+			It("uses FindAll without sparse fieldsets", func() {
+				req, err := http.NewRequest("GET", "/posts", nil)
+				Expect(err).ToNot(HaveOccurred())
+				api.Handler().ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusOK))
+				Expect(projectable.fields).To(BeNil())
+			})
+
+			It("rejects wrong fields", func() {
+				req, err := http.NewRequest("GET", "/posts?fields[posts]=nope", nil)
+				Expect(err).ToNot(HaveOccurred())
+				api.Handler().ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			})
+
+			It("announces the interface", func() {
+				description, ok := api.Describe("posts")
+				Expect(ok).To(BeTrue())
+				Expect(description.Interfaces).To(ContainElement("Projectable"))
+			})
+		})
+	})
+
+	Context("request bodies", func() {
+		It("can be read again after unmarshaling", func() {
+			source := &bodyReadingSource{fixtureSource: &fixtureSource{map[string]*Post{}, false}}
+			api := NewAPI("v1")
+			api.AddResource(Post{}, source)
+			body := `{"data":{"type":"posts","attributes":{"title":"New Post"}}}`
+			req, err := http.NewRequest("POST", "/v1/posts", strings.NewReader(body))
+			Expect(err).ToNot(HaveOccurred())
+			rec := httptest.NewRecorder()
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(source.bodies).To(Equal([]string{body, body}))
+		})
+
+		It("can be read before unmarshaling", func() {
+			body := `{"data":null}`
+			plain, err := http.NewRequest("POST", "/v1/posts", strings.NewReader(body))
+			Expect(err).ToNot(HaveOccurred())
+			req := Request{PlainRequest: plain}
+			read, err := req.Body()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(read)).To(Equal(body))
+
+			result, err := unmarshalRequest(plain, DefaultContentMarshalers)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(map[string]interface{}{"data": nil}))
+		})
+
+		It("returns an error without plain request", func() {
+			_, err := Request{}.Body()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("request numbers", func() {
+		It("keeps numbers as json.Number", func() {
+			req, err := http.NewRequest("POST", "/v1/posts", strings.NewReader(`{"data":{"attributes":{"value":9007199254740993}}}`))
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Content-Type", defaultContentTypeHeader)
+			result, err := unmarshalRequest(req, DefaultContentMarshalers)
+			Expect(err).ToNot(HaveOccurred())
+			attributes := result["data"].(map[string]interface{})["attributes"].(map[string]interface{})
+			Expect(attributes["value"]).To(Equal(json.Number("9007199254740993")))
+		})
+
+		It("rejects data after the document", func() {
+			result := map[string]interface{}{}
+			Expect(decodeJSONWithNumbers([]byte(`{"data":null} {}`), &result)).ToNot(Succeed())
+			Expect(decodeJSONWithNumbers([]byte(`{"data":null}`+"\n"), &result)).To(Succeed())
+		})
+	})
+
+	Context("relationship meta", func() {
+		It("is part of the relationship document", func() {
+			post := relationshipMetaPost{Post{ID: "1", Author: &User{ID: "2"}}}
+			document, err := relationshipDocument(post, "author", NewInformation("v1", NewStaticResolver("")))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(document["meta"]).To(Equal(map[string]interface{}{"since": "2020"}))
+
+			document, err = relationshipDocument(post, "comments", NewInformation("v1", NewStaticResolver("")))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(document).ToNot(HaveKey("meta"))
+		})
+	})
+
+	Context("partial data", func() {
+		marshal := func(document map[string]interface{}, status int) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", "/v1/posts", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(marshalResponse(context.Background(), document, rec, status, req, DefaultContentMarshalers)).To(Succeed())
+			return rec
+		}
+
+		It("does not write data together with errors", func() {
+			rec := marshal(map[string]interface{}{
+				"data":     []map[string]interface{}{{"type": "posts", "id": "1"}},
+				"included": []map[string]interface{}{{"type": "users", "id": "1"}},
+				"errors":   []Error{{Status: "403", Title: "Forbidden"}},
+				"meta":     map[string]interface{}{"total": 2},
+			}, http.StatusOK)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"403","title":"Forbidden"}],"meta":{"total":2}}`))
+		})
+
+		It("keeps error status codes", func() {
+			rec := marshal(map[string]interface{}{
+				"data":   map[string]interface{}{"type": "posts", "id": "1"},
+				"errors": []Error{{Status: "403", Title: "Forbidden"}},
+			}, http.StatusForbidden)
+			Expect(rec.Code).To(Equal(http.StatusForbidden))
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"403","title":"Forbidden"}]}`))
+		})
+
+		It("writes data without errors", func() {
+			rec := marshal(map[string]interface{}{
+				"data": map[string]interface{}{"type": "posts", "id": "1"},
+			}, http.StatusOK)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(MatchJSON(`{"data":{"type":"posts","id":"1"}}`))
+		})
 	})
 })
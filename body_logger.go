@@ -0,0 +1,82 @@
+package api2go
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// Logger is used to write debug output, it is implemented by *log.Logger
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// BodyLogger returns a middleware that logs the bodies of all requests and responses.
+// Bodies are truncated to `maxBodySize` bytes, use a value <= 0 to log complete bodies.
+// Gzip encoded bodies are decompressed before they are logged.
+//
+// The complete response is buffered before it is written to the client, so this
+// middleware should only be used for debugging.
+func BodyLogger(logger Logger, maxBodySize int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var requestBody []byte
+			if r.Body != nil {
+				var err error
+				requestBody, err = ioutil.ReadAll(r.Body)
+				r.Body.Close()
+				if err != nil {
+					logger.Printf("could not read request body: %s", err)
+				}
+				r.Body = ioutil.NopCloser(bytes.NewReader(requestBody))
+			}
+
+			logger.Printf("request %s %s: %s", r.Method, r.URL.RequestURI(), loggableBody(requestBody, r.Header, maxBodySize))
+
+			rec := httptest.NewRecorder()
+			next.ServeHTTP(rec, r)
+
+			logger.Printf("response %s %s (%d): %s", r.Method, r.URL.RequestURI(), rec.Code, loggableBody(rec.Body.Bytes(), rec.Header(), maxBodySize))
+
+			for key, values := range rec.Header() {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(rec.Code)
+			w.Write(rec.Body.Bytes())
+		})
+	}
+}
+
+// loggableBody decompresses and truncates a body for logging
+func loggableBody(body []byte, header http.Header, maxBodySize int64) string {
+	var reader io.Reader = bytes.NewReader(body)
+
+	if len(body) > 0 && strings.EqualFold(header.Get("Content-Encoding"), "gzip") {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return fmt.Sprintf("<invalid gzip body: %s>", err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	if maxBodySize > 0 {
+		reader = io.LimitReader(reader, maxBodySize+1)
+	}
+
+	result, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return fmt.Sprintf("<unreadable body: %s>", err)
+	}
+
+	if maxBodySize > 0 && int64(len(result)) > maxBodySize {
+		return string(result[:maxBodySize]) + "..."
+	}
+
+	return string(result)
+}
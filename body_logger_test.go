@@ -0,0 +1,90 @@
+package api2go
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+var _ = Describe("BodyLogger", func() {
+	var (
+		logger *recordingLogger
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		logger = &recordingLogger{}
+		rec = httptest.NewRecorder()
+	})
+
+	It("logs request and response bodies and replays the response", func() {
+		var received string
+		handler := BodyLogger(logger, 0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			received = string(body)
+			w.Header().Set("X-Test", "test")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"data":"response"}`))
+		}))
+
+		req, err := http.NewRequest("POST", "/v1/posts", strings.NewReader(`{"data":"request"}`))
+		Expect(err).ToNot(HaveOccurred())
+		handler.ServeHTTP(rec, req)
+
+		Expect(received).To(Equal(`{"data":"request"}`))
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(rec.Header().Get("X-Test")).To(Equal("test"))
+		Expect(rec.Body.String()).To(Equal(`{"data":"response"}`))
+		Expect(logger.lines).To(Equal([]string{
+			`request POST /v1/posts: {"data":"request"}`,
+			`response POST /v1/posts (201): {"data":"response"}`,
+		}))
+	})
+
+	It("truncates bodies", func() {
+		handler := BodyLogger(logger, 5)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("0123456789"))
+		}))
+
+		req, err := http.NewRequest("GET", "/v1/posts", nil)
+		Expect(err).ToNot(HaveOccurred())
+		handler.ServeHTTP(rec, req)
+
+		Expect(rec.Body.String()).To(Equal("0123456789"))
+		Expect(logger.lines[1]).To(Equal("response GET /v1/posts (200): 01234..."))
+	})
+
+	It("decompresses gzip responses for logging only", func() {
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		writer.Write([]byte("zipped"))
+		writer.Close()
+
+		handler := BodyLogger(logger, 0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compressed.Bytes())
+		}))
+
+		req, err := http.NewRequest("GET", "/v1/posts", nil)
+		Expect(err).ToNot(HaveOccurred())
+		handler.ServeHTTP(rec, req)
+
+		Expect(rec.Body.Bytes()).To(Equal(compressed.Bytes()))
+		Expect(logger.lines[1]).To(Equal("response GET /v1/posts (200): zipped"))
+	})
+})
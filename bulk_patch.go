@@ -0,0 +1,148 @@
+package api2go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+// The BulkPatcher interface can be optionally implemented by a source to update multiple
+// entries with one `PATCH /resource` request, whose `data` contains an array of resource
+// objects with id and type. Every entry is loaded with FindOne and only the attributes and
+// relationships given in the request are applied to it, just as for Update.
+//
+// The returned status code is handled as for Update. If only some of the updates succeeded,
+// BulkPatch can return 207 Multi-Status, the result and meta data are responded like for 200,
+// e.g. with the updated entries as result and the ids that failed in the meta data.
+//
+// One `<resource>.updated` event is published per updated entry before responding, for 207
+// only for the entries in the result.
+type BulkPatcher interface {
+	BulkPatch(updates []interface{}, req Request) (Responder, error)
+}
+
+func (res *resource) handleBulkPatch(c context.Context, w http.ResponseWriter, r *http.Request) error {
+	source, ok := res.source.(BulkPatcher)
+	if !ok {
+		return NewHTTPError(nil, "Resource does not implement the BulkPatcher interface", http.StatusMethodNotAllowed)
+	}
+
+	inc, err := unmarshalRequest(r, res.marshalers)
+	if err != nil {
+		return err
+	}
+
+	data, ok := inc["data"].([]interface{})
+	if !ok {
+		return newDocumentError(errors.New("Bad Request"), "data must be an array of resource objects", http.StatusBadRequest, "/data")
+	}
+
+	req := BuildRequest(c, r)
+	updates := make([]interface{}, 0, len(data))
+	for i, entry := range data {
+		pointer := "/data/" + strconv.Itoa(i)
+		update, err := res.bulkPatchEntry(entry, pointer, req)
+		if err != nil {
+			return err
+		}
+
+		updates = append(updates, update)
+	}
+
+	response, err := source.BulkPatch(updates, req)
+	if err != nil {
+		return err
+	}
+
+	if err := res.publishBulkPatch(c, response, updates, req); err != nil {
+		return err
+	}
+
+	switch response.StatusCode() {
+	case http.StatusOK, http.StatusMultiStatus:
+		return RespondWith(response, response.StatusCode(), c, w, r)
+	case http.StatusAccepted:
+		w.WriteHeader(http.StatusAccepted)
+		return nil
+	case http.StatusNoContent:
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	default:
+		return fmt.Errorf("invalid status code %d from resource %s for method BulkPatch", response.StatusCode(), res.name)
+	}
+}
+
+// publishBulkPatch publishes one updated event per updated entry. For 207 only the entries in
+// the result succeeded, otherwise all given updates did.
+func (res *resource) publishBulkPatch(c context.Context, response Responder, updates []interface{}, req Request) error {
+	updated := updates
+	if response.StatusCode() == http.StatusMultiStatus {
+		result := reflect.ValueOf(response.Result())
+		if result.Kind() != reflect.Slice {
+			return nil
+		}
+
+		updated = make([]interface{}, 0, result.Len())
+		for i := 0; i < result.Len(); i++ {
+			updated = append(updated, result.Index(i).Interface())
+		}
+	}
+
+	for _, obj := range updated {
+		identifier, ok := obj.(jsonapi.MarshalIdentifier)
+		if !ok {
+			continue
+		}
+
+		if err := res.publish(c, EventUpdated, identifier.GetID(), obj, req); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// bulkPatchEntry loads the entry with the id of a resource object in the bulk request and
+// applies the attributes and relationships of the resource object to it
+func (res *resource) bulkPatchEntry(entry interface{}, pointer string, req Request) (interface{}, error) {
+	object, ok := entry.(map[string]interface{})
+	if !ok {
+		return nil, newDocumentError(errors.New("Bad Request"), "entry in data array must be an object", http.StatusBadRequest, pointer)
+	}
+
+	id, ok := object["id"].(string)
+	if !ok || id == "" {
+		return nil, newDocumentError(errors.New("Bad Request"), "missing mandatory id key.", http.StatusBadRequest, pointer)
+	}
+
+	if _, ok := object["type"].(string); !ok {
+		return nil, newDocumentError(errors.New("Bad Request"), "missing mandatory type key.", http.StatusBadRequest, pointer)
+	}
+
+	found, err := res.source.FindOne(id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	updatingObjs := reflect.MakeSlice(reflect.SliceOf(res.resourceType), 1, 1)
+	updatingObjs.Index(0).Set(reflect.ValueOf(found.Result()))
+
+	structType := res.resourceType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	if err := jsonapi.UnmarshalInto(map[string]interface{}{"data": object}, structType, &updatingObjs); err != nil {
+		return nil, newDocumentError(err, err.Error(), http.StatusBadRequest, pointer)
+	}
+	if updatingObjs.Len() != 1 {
+		return nil, errors.New("expected one object")
+	}
+
+	return updatingObjs.Index(0).Interface(), nil
+}
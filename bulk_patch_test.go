@@ -0,0 +1,158 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/guregu/null.v2"
+)
+
+// bulkPatchSource records the updates of BulkPatch and answers with the given status code
+type bulkPatchSource struct {
+	*fixtureSource
+	updates []interface{}
+	code    int
+}
+
+func (s *bulkPatchSource) BulkPatch(updates []interface{}, req Request) (Responder, error) {
+	s.updates = updates
+	if s.code == http.StatusMultiStatus {
+		return &Response{Code: s.code, Res: updates[:1], Meta: map[string]interface{}{"failed": []string{"2"}}}, nil
+	}
+
+	return &Response{Code: s.code, Res: updates}, nil
+}
+
+var _ = Describe("BulkPatcher", func() {
+	var (
+		api    *API
+		source *bulkPatchSource
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		source = &bulkPatchSource{fixtureSource: &fixtureSource{map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!", Value: null.FloatFrom(13.37)},
+			"2": {ID: "2", Title: "Goodbye, World!"},
+		}, false}, code: http.StatusNoContent}
+		api = newPostAPI(source)
+	})
+
+	It("applies the given fields to the loaded entries", func() {
+		rec = sendRequest(api, "PATCH", "/v1/posts", `{"data": [
+			{"type": "posts", "id": "1", "attributes": {"title": "Done"}},
+			{"type": "posts", "id": "2", "attributes": {"value": 1}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.updates).To(Equal([]interface{}{
+			Post{ID: "1", Title: "Done", Value: null.FloatFrom(13.37)},
+			Post{ID: "2", Title: "Goodbye, World!", Value: null.FloatFrom(1)},
+		}))
+	})
+
+	It("publishes one updated event per updated entry", func() {
+		ids := []string{}
+		bus := NewEventBus()
+		bus.Subscribe("posts."+EventUpdated, func(event ResourceEvent) error {
+			ids = append(ids, event.ID)
+			return nil
+		})
+		api.SetEventBus(bus)
+
+		rec = sendRequest(api, "PATCH", "/v1/posts", `{"data": [
+			{"type": "posts", "id": "1", "attributes": {"title": "Done"}},
+			{"type": "posts", "id": "2", "attributes": {"title": "Done"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(ids).To(Equal([]string{"1", "2"}))
+	})
+
+	It("publishes updated events only for the result of 207", func() {
+		source.code = http.StatusMultiStatus
+		ids := []string{}
+		bus := NewEventBus()
+		bus.Subscribe("posts."+EventUpdated, func(event ResourceEvent) error {
+			ids = append(ids, event.ID)
+			return nil
+		})
+		api.SetEventBus(bus)
+
+		rec = sendRequest(api, "PATCH", "/v1/posts", `{"data": [
+			{"type": "posts", "id": "1", "attributes": {"title": "Done"}},
+			{"type": "posts", "id": "2", "attributes": {"title": "Done"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusMultiStatus))
+		Expect(ids).To(Equal([]string{"1"}))
+	})
+
+	It("responds with the result for 200", func() {
+		source.code = http.StatusOK
+		rec = sendRequest(api, "PATCH", "/v1/posts", `{"data": [{"type": "posts", "id": "1", "attributes": {"title": "Done"}}]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var document struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+		Expect(document.Data).To(HaveLen(1))
+		Expect(document.Data[0]["attributes"]).To(HaveKeyWithValue("title", "Done"))
+	})
+
+	It("responds with 207 for partial failures", func() {
+		source.code = http.StatusMultiStatus
+		rec = sendRequest(api, "PATCH", "/v1/posts", `{"data": [
+			{"type": "posts", "id": "1", "attributes": {"title": "Done"}},
+			{"type": "posts", "id": "2", "attributes": {"title": "Done"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusMultiStatus))
+
+		var document struct {
+			Data []map[string]interface{} `json:"data"`
+			Meta map[string]interface{}   `json:"meta"`
+		}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+		Expect(document.Data).To(HaveLen(1))
+		Expect(document.Meta).To(Equal(map[string]interface{}{"failed": []interface{}{"2"}}))
+	})
+
+	It("points to entries without id or type", func() {
+		for body, pointer := range map[string]string{
+			`{"data": [{"type": "posts", "id": "1"}, {"type": "posts"}]}`: "/data/1",
+			`{"data": [{"id": "1"}]}`:                                     "/data/0",
+			`{"data": ["1"]}`:                                             "/data/0",
+			`{"data": {"type": "posts", "id": "1"}}`:                      "/data",
+		} {
+			rec = sendRequest(api, "PATCH", "/v1/posts", body)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest), body)
+
+			var document struct {
+				Errors []Error `json:"errors"`
+			}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+			Expect(document.Errors).To(HaveLen(1))
+			Expect(document.Errors[0].Source.Pointer).To(Equal(pointer), body)
+		}
+		Expect(source.updates).To(BeNil())
+	})
+
+	It("fails if an entry does not exist", func() {
+		rec = sendRequest(api, "PATCH", "/v1/posts", `{"data": [{"type": "posts", "id": "3", "attributes": {"title": "Done"}}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(source.updates).To(BeNil())
+	})
+
+	It("announces the interface", func() {
+		description, ok := api.Describe("posts")
+		Expect(ok).To(BeTrue())
+		Expect(description.Interfaces).To(ContainElement("BulkPatcher"))
+	})
+
+	It("is not registered for other sources", func() {
+		api = newPostAPI(source.fixtureSource)
+		rec = sendRequest(api, "PATCH", "/v1/posts", `{"data": [{"type": "posts", "id": "1", "attributes": {"title": "Done"}}]}`)
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -0,0 +1,75 @@
+package api2go
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP returns the ip address of the client. If the request has been sent by one of the
+// TrustedProxies of the API, `X-Forwarded-For` is read from the right and the first address
+// that is not a trusted proxy is returned, because the entries left of it can be set by the
+// client. Otherwise, or if there is none, the address of the remote end of the connection is used.
+// It returns an empty string without PlainRequest.
+func (r Request) ClientIP() string {
+	if r.PlainRequest == nil {
+		return ""
+	}
+
+	remoteIP := r.PlainRequest.RemoteAddr
+	if host, _, err := net.SplitHostPort(remoteIP); err == nil {
+		remoteIP = host
+	}
+
+	c := r.Context
+	if c == nil {
+		c = r.PlainRequest.Context()
+	}
+
+	api, ok := c.Value(api_api).(*API)
+	if !ok || !api.trustedProxy(net.ParseIP(remoteIP)) {
+		return remoteIP
+	}
+
+	if ip := api.forwardedFor(r.PlainRequest.Header); ip != nil {
+		return ip.String()
+	}
+
+	return remoteIP
+}
+
+// trustedProxy checks if the ip address is contained in the TrustedProxies
+func (api *API) trustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, proxy := range api.TrustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forwardedFor returns the rightmost address of the `X-Forwarded-For` headers that is not
+// a trusted proxy. It returns nil if an entry is invalid or all entries are trusted proxies.
+func (api *API) forwardedFor(header http.Header) net.IP {
+	values := header.Values("X-Forwarded-For")
+	for i := len(values) - 1; i >= 0; i-- {
+		addresses := strings.Split(values[i], ",")
+		for j := len(addresses) - 1; j >= 0; j-- {
+			ip := net.ParseIP(strings.TrimSpace(addresses[j]))
+			if ip == nil {
+				return nil
+			}
+
+			if !api.trustedProxy(ip) {
+				return ip
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package api2go
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// clientIPSource records the client ip of FindAll requests
+type clientIPSource struct {
+	*fixtureSource
+	ip string
+}
+
+func (s *clientIPSource) FindAll(req Request) (Responder, error) {
+	s.ip = req.ClientIP()
+	return s.fixtureSource.FindAll(req)
+}
+
+var _ = Describe("ClientIP", func() {
+	var api *API
+
+	BeforeEach(func() {
+		_, proxies, err := net.ParseCIDR("10.0.0.0/8")
+		Expect(err).ToNot(HaveOccurred())
+		api = NewAPI("v1")
+		api.TrustedProxies = []net.IPNet{*proxies}
+	})
+
+	clientIP := func(remoteAddr string, forwardedFor ...string) string {
+		r, err := http.NewRequest("GET", "/v1/posts", nil)
+		Expect(err).ToNot(HaveOccurred())
+		r.RemoteAddr = remoteAddr
+		for _, value := range forwardedFor {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+
+		return BuildRequest(context.WithValue(r.Context(), api_api, api), r).ClientIP()
+	}
+
+	It("returns the remote address without X-Forwarded-For", func() {
+		Expect(clientIP("203.0.113.7:54321")).To(Equal("203.0.113.7"))
+		Expect(clientIP("10.0.0.1:80")).To(Equal("10.0.0.1"))
+		Expect(clientIP("[2001:db8::1]:443")).To(Equal("2001:db8::1"))
+	})
+
+	It("returns the rightmost untrusted address of X-Forwarded-For of trusted proxies", func() {
+		Expect(clientIP("10.0.0.1:80", "192.168.1.5, 198.51.100.23, 10.0.0.2")).To(Equal("198.51.100.23"))
+		Expect(clientIP("10.0.0.1:80", "invalid, 198.51.100.24", "10.0.0.3")).To(Equal("198.51.100.24"))
+		Expect(clientIP("10.0.0.1:80", "198.51.100.23, 192.168.1.5")).To(Equal("192.168.1.5"))
+	})
+
+	It("ignores addresses spoofed by the client", func() {
+		Expect(clientIP("10.0.0.1:80", "8.8.8.8, 198.51.100.23")).To(Equal("198.51.100.23"))
+		Expect(clientIP("10.0.0.1:80", "8.8.8.8", "198.51.100.23, 10.0.0.2")).To(Equal("198.51.100.23"))
+	})
+
+	It("falls back to the remote address if there is no untrusted address", func() {
+		Expect(clientIP("10.0.0.1:80", "10.0.0.3, 10.0.0.2")).To(Equal("10.0.0.1"))
+		Expect(clientIP("10.0.0.1:80", "198.51.100.23, invalid")).To(Equal("10.0.0.1"))
+	})
+
+	It("ignores X-Forwarded-For of untrusted clients", func() {
+		Expect(clientIP("203.0.113.7:54321", "198.51.100.23")).To(Equal("203.0.113.7"))
+
+		api.TrustedProxies = nil
+		Expect(clientIP("10.0.0.1:80", "198.51.100.23")).To(Equal("10.0.0.1"))
+	})
+
+	It("is available to sources", func() {
+		source := &clientIPSource{fixtureSource: &fixtureSource{map[string]*Post{}, false}}
+		api.AddResource(Post{}, source)
+
+		r, err := http.NewRequest("GET", "/v1/posts", nil)
+		Expect(err).ToNot(HaveOccurred())
+		r.RemoteAddr = "10.1.2.3:80"
+		r.Header.Set("X-Forwarded-For", "198.51.100.23")
+		rec := httptest.NewRecorder()
+		api.Handler().ServeHTTP(rec, r)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.ip).To(Equal("198.51.100.23"))
+	})
+
+	It("is empty without plain request", func() {
+		Expect(Request{}.ClientIP()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,100 @@
+package api2go
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// WithCompression gzips the responses of the resource if the client accepts gzip and
+// the body has at least `minSize` bytes. `level` is a compression level of compress/gzip,
+// e.g. gzip.BestSpeed. Responses are buffered to determine their size.
+func WithCompression(minSize int, level int) ResourceOption {
+	if _, err := gzip.NewWriterLevel(ioutil.Discard, level); err != nil {
+		panic(err)
+	}
+
+	return func(res *resource) {
+		res.middlewares = append(res.middlewares, compression(minSize, level))
+	}
+}
+
+func compression(minSize int, level int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if !acceptsGzip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			writer := &compressionWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(writer, r)
+			writer.flush(minSize, level)
+		})
+	}
+}
+
+// acceptsGzip checks if gzip is an accepted encoding of the request
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(encoding, ";")
+		name := strings.TrimSpace(parts[0])
+		if name != "gzip" && name != "*" {
+			continue
+		}
+
+		rejected := false
+		for _, param := range parts[1:] {
+			if strings.Replace(param, " ", "", -1) == "q=0" {
+				rejected = true
+			}
+		}
+
+		if !rejected {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compressionWriter buffers a response until it is compressed by flush
+type compressionWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *compressionWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *compressionWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+// Unwrap returns the original ResponseWriter
+func (w *compressionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressionWriter) flush(minSize int, level int) {
+	header := w.ResponseWriter.Header()
+	if w.body.Len() == 0 || w.body.Len() < minSize || header.Get("Content-Encoding") != "" {
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.body.Bytes())
+		return
+	}
+
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+
+	// the level has been validated by WithCompression
+	gzipWriter, _ := gzip.NewWriterLevel(w.ResponseWriter, level)
+	gzipWriter.Write(w.body.Bytes())
+	gzipWriter.Close()
+}
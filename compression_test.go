@@ -0,0 +1,105 @@
+package api2go
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Compression", func() {
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(method, URL, acceptEncoding string) {
+		req, err := http.NewRequest(method, URL, nil)
+		Expect(err).ToNot(HaveOccurred())
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	decompress := func() string {
+		reader, err := gzip.NewReader(rec.Body)
+		Expect(err).ToNot(HaveOccurred())
+		body, err := ioutil.ReadAll(reader)
+		Expect(err).ToNot(HaveOccurred())
+		return string(body)
+	}
+
+	Context("with compression", func() {
+		BeforeEach(func() {
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}, false}, WithCompression(10, gzip.BestSpeed))
+		})
+
+		It("compresses responses for clients that accept gzip", func() {
+			doRequest("GET", "/v1/posts/1", "deflate, gzip")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get("Content-Encoding")).To(Equal("gzip"))
+			Expect(rec.Header().Get("Content-Type")).To(Equal(defaultContentTypeHeader))
+			Expect(rec.Header().Get("Vary")).To(Equal("Accept-Encoding"))
+			Expect(decompress()).To(ContainSubstring("Hello, World!"))
+		})
+
+		It("compresses errors", func() {
+			doRequest("GET", "/v1/posts/42", "gzip")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(decompress()).To(ContainSubstring("post not found"))
+		})
+
+		It("does not compress for other clients", func() {
+			doRequest("GET", "/v1/posts/1", "gzip;q=0, deflate")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get("Content-Encoding")).To(BeEmpty())
+			Expect(rec.Body.String()).To(ContainSubstring("Hello, World!"))
+		})
+
+		It("does not compress empty responses", func() {
+			doRequest("DELETE", "/v1/posts/1", "gzip")
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(rec.Header().Get("Content-Encoding")).To(BeEmpty())
+			Expect(rec.Body.Len()).To(Equal(0))
+		})
+	})
+
+	It("does not compress responses below the minimum size", func() {
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}, false}, WithCompression(100000, gzip.BestSpeed))
+		doRequest("GET", "/v1/posts/1", "gzip")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(rec.Body.String()).To(ContainSubstring("Hello, World!"))
+	})
+
+	It("does not compress other resources", func() {
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}, false}, WithCompression(10, gzip.BestSpeed))
+		api.AddResource(User{}, &userSource{}, WithName("people"))
+		doRequest("OPTIONS", "/v1/people", "gzip")
+		Expect(rec.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(rec.Header().Get("Vary")).To(BeEmpty())
+	})
+
+	It("passes errors to the request logger", func() {
+		logger := &recordingRequestLogger{}
+		api.SetRequestLogger(logger)
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false}, WithCompression(10, gzip.BestSpeed))
+		doRequest("GET", "/v1/posts/42", "gzip")
+		Expect(logger.requests).To(HaveLen(1))
+		Expect(logger.requests[0].status).To(Equal(http.StatusNotFound))
+		Expect(logger.requests[0].err).To(HaveOccurred())
+	})
+
+	It("panics for invalid levels", func() {
+		Expect(func() { WithCompression(10, 42) }).To(Panic())
+	})
+})
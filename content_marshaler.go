@@ -5,10 +5,16 @@ import "encoding/json"
 // JSONContentMarshaler uses the standard encoding/json package for
 // decoding requests and encoding responses in JSON format.
 type JSONContentMarshaler struct {
+	// Indent pretty prints the output with an indentation of two spaces
+	Indent bool
 }
 
 // Marshal marshals with default JSON
 func (m JSONContentMarshaler) Marshal(i interface{}) ([]byte, error) {
+	if m.Indent {
+		return json.MarshalIndent(i, "", "  ")
+	}
+
 	return json.Marshal(i)
 }
 
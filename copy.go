@@ -0,0 +1,53 @@
+package api2go
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+// The Copier interface can be optionally implemented by a source to duplicate entries
+// with `COPY /resource/:id`. Copy must store a copy of the entry with the given id under
+// a new id and return it with status code 201 Created. The response contains the copy
+// and its url as Location header.
+type Copier interface {
+	Copy(id string, req Request) (Responder, error)
+}
+
+func (res *resource) handleCopy(c context.Context, w http.ResponseWriter, r *http.Request, params func(context.Context, string) string) error {
+	source, ok := res.source.(Copier)
+	if !ok {
+		return NewHTTPError(nil, "Resource does not implement the Copier interface", http.StatusMethodNotAllowed)
+	}
+
+	id := params(c, "id")
+	req := BuildRequest(c, r)
+	response, err := source.Copy(id, req)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode() != http.StatusCreated {
+		return fmt.Errorf("invalid status code %d from resource %s for method Copy", response.StatusCode(), res.name)
+	}
+
+	result, ok := response.Result().(jsonapi.MarshalIdentifier)
+	if !ok {
+		return fmt.Errorf("Expected one copied object by resource %s", res.name)
+	}
+
+	copyID := result.GetID()
+	if copyID == "" || copyID == id {
+		return fmt.Errorf("copy of %s by resource %s must have a new id", id, res.name)
+	}
+
+	if err := res.publish(c, EventCreated, copyID, result, req); err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", res.location(c, copyID))
+
+	return RespondWith(response, http.StatusCreated, c, w, r)
+}
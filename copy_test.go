@@ -0,0 +1,107 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// copierSource copies posts to the id `copyID`
+type copierSource struct {
+	*fixtureSource
+	copyID string
+}
+
+func (s *copierSource) Copy(id string, req Request) (Responder, error) {
+	post, ok := s.posts[id]
+	if !ok {
+		return nil, NewHTTPError(nil, "post not found", http.StatusNotFound)
+	}
+
+	copied := *post
+	copied.ID = s.copyID
+	s.posts[s.copyID] = &copied
+	return &Response{Code: http.StatusCreated, Res: copied}, nil
+}
+
+var _ = Describe("Copier", func() {
+	var (
+		api    *API
+		source *copierSource
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		source = &copierSource{fixtureSource: &fixtureSource{map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+		}, false}, copyID: "2"}
+		api = newPostAPI(source)
+	})
+
+	It("responds with the copy", func() {
+		rec = sendRequest(api, "COPY", "/v1/posts/1", "")
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(rec.Header().Get("Location")).To(Equal("/v1/posts/2"))
+
+		var document struct {
+			Data struct {
+				ID         string                 `json:"id"`
+				Attributes map[string]interface{} `json:"attributes"`
+			} `json:"data"`
+		}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+		Expect(document.Data.ID).To(Equal("2"))
+		Expect(document.Data.Attributes).To(HaveKeyWithValue("title", "Hello, World!"))
+		Expect(source.posts).To(HaveKey("2"))
+	})
+
+	It("passes errors of the source", func() {
+		rec = sendRequest(api, "COPY", "/v1/posts/3", "")
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("fails if the copy does not have a new id", func() {
+		source.copyID = "1"
+		rec = sendRequest(api, "COPY", "/v1/posts/1", "")
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		Expect(rec.Header().Get("Location")).To(BeEmpty())
+	})
+
+	It("does not set Location if publishing the copy fails", func() {
+		bus := NewEventBus()
+		bus.Subscribe("posts."+EventCreated, func(event ResourceEvent) error {
+			return NewHTTPError(nil, "out of stock", http.StatusConflict)
+		})
+		api.SetEventBus(bus)
+
+		rec = sendRequest(api, "COPY", "/v1/posts/1", "")
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(rec.Header().Get("Location")).To(BeEmpty())
+	})
+
+	It("announces the method", func() {
+		rec = sendRequest(api, "OPTIONS", "/v1/posts/1", "")
+		Expect(rec.Header().Get("Allow")).To(Equal("GET,HEAD,PATCH,DELETE,OPTIONS,COPY"))
+
+		description, ok := api.Describe("posts")
+		Expect(ok).To(BeTrue())
+		Expect(description.Interfaces).To(ContainElement("Copier"))
+		Expect(description.Methods).To(ContainElement("COPY"))
+	})
+
+	It("is not allowed for read-only resources", func() {
+		api = newPostAPI(source, WithReadOnly())
+		rec = sendRequest(api, "COPY", "/v1/posts/1", "")
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(source.posts).ToNot(HaveKey("2"))
+	})
+
+	It("is not registered for other sources", func() {
+		api = newPostAPI(source.fixtureSource)
+		rec = sendRequest(api, "COPY", "/v1/posts/1", "")
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
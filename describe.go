@@ -0,0 +1,158 @@
+package api2go
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// ResourceDescription describes a registered resource at runtime,
+// e.g. for documentation generators or admin interfaces
+type ResourceDescription struct {
+	Name string
+	// Interfaces contains the optional interfaces implemented by the source
+	Interfaces []string
+	// Relationships contains the names of all relationships of the resource
+	Relationships []string
+	// Methods contains all HTTP methods that are registered for the resource
+	Methods []string
+}
+
+// Describe returns the description of the resource with the given name,
+// or false if there is no such resource
+func (api *API) Describe(name string) (ResourceDescription, bool) {
+	for _, res := range api.resources {
+		if res.name == name {
+			return res.describe(), true
+		}
+	}
+
+	return ResourceDescription{}, false
+}
+
+func (res *resource) describe() ResourceDescription {
+	description := ResourceDescription{
+		Name:          res.name,
+		Interfaces:    []string{"CRUD"},
+		Relationships: append([]string{}, res.relationships...),
+		Methods:       []string{},
+	}
+
+	// resources of other services are only forwarded to, their interfaces are unknown
+	if res.external() {
+		description.Interfaces = []string{}
+	}
+
+	if _, ok := res.source.(FindAll); ok {
+		description.Interfaces = append(description.Interfaces, "FindAll")
+	}
+	if _, ok := res.source.(PaginatedFindAll); ok {
+		description.Interfaces = append(description.Interfaces, "PaginatedFindAll")
+	}
+	if _, ok := res.source.(Searchable); ok {
+		description.Interfaces = append(description.Interfaces, "Searchable")
+	}
+	if _, ok := res.source.(GeoFilterable); ok {
+		description.Interfaces = append(description.Interfaces, "GeoFilterable")
+	}
+	if _, ok := res.source.(Filterable); ok {
+		description.Interfaces = append(description.Interfaces, "Filterable")
+	}
+	if _, ok := res.source.(Projectable); ok {
+		description.Interfaces = append(description.Interfaces, "Projectable")
+	}
+	if _, ok := res.source.(RelationshipIncluder); ok {
+		description.Interfaces = append(description.Interfaces, "RelationshipIncluder")
+	}
+	if _, ok := res.source.(IncludeProvider); ok {
+		description.Interfaces = append(description.Interfaces, "IncludeProvider")
+	}
+	if _, ok := res.source.(EmbedProvider); ok {
+		description.Interfaces = append(description.Interfaces, "EmbedProvider")
+	}
+	if _, ok := res.source.(BulkRelationshipPatcher); ok {
+		description.Interfaces = append(description.Interfaces, "BulkRelationshipPatcher")
+	}
+	if _, ok := res.source.(JSONPatchUpdater); ok {
+		description.Interfaces = append(description.Interfaces, "JSONPatchUpdater")
+	}
+	if _, ok := res.source.(BulkPatcher); ok {
+		description.Interfaces = append(description.Interfaces, "BulkPatcher")
+	}
+	if _, ok := res.source.(Copier); ok {
+		description.Interfaces = append(description.Interfaces, "Copier")
+	}
+	if _, ok := res.source.(FilterDeleter); ok {
+		description.Interfaces = append(description.Interfaces, "FilterDeleter")
+	}
+	if _, ok := res.source.(RelationshipHandler); ok {
+		description.Interfaces = append(description.Interfaces, "RelationshipHandler")
+	}
+	if _, ok := res.source.(AfterFetch); ok {
+		description.Interfaces = append(description.Interfaces, "AfterFetch")
+	}
+	if _, ok := res.source.(HealthChecker); ok {
+		description.Interfaces = append(description.Interfaces, "HealthChecker")
+	}
+	if _, ok := res.source.(SchemaURLProvider); ok {
+		description.Interfaces = append(description.Interfaces, "SchemaURLProvider")
+	}
+	if _, ok := res.source.(FieldMasker); ok {
+		description.Interfaces = append(description.Interfaces, "FieldMasker")
+	}
+
+	for method := range res.methods {
+		description.Methods = append(description.Methods, method)
+	}
+	sort.Strings(description.Methods)
+
+	return description
+}
+
+// RouteTree returns a human readable list of all routes of the registered resources for
+// debugging, grouped by resource and path, e.g.
+//
+//	posts
+//	  /v1/posts        OPTIONS GET POST PATCH
+//	  /v1/posts/:id    OPTIONS GET HEAD DELETE PATCH
+//
+// Routes that are registered directly on the router, e.g. health checks, are not listed.
+func (api *API) RouteTree() string {
+	var buffer bytes.Buffer
+	w := tabwriter.NewWriter(&buffer, 0, 4, 4, ' ', 0)
+
+	for _, res := range api.resources {
+		fmt.Fprintln(w, res.key())
+
+		// methods are grouped by path, both in the order of registration
+		paths := []string{}
+		methods := map[string][]string{}
+		for _, route := range res.routes {
+			if _, ok := methods[route.path]; !ok {
+				paths = append(paths, route.path)
+			}
+			methods[route.path] = append(methods[route.path], route.method)
+		}
+
+		prefixes := []string{}
+		for prefix := range res.prefixes {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+
+		for _, prefix := range prefixes {
+			if prefix != "" {
+				prefix = "/" + prefix
+			}
+
+			for _, path := range paths {
+				fmt.Fprintf(w, "  %s%s\t%s\n", prefix, path, strings.Join(methods[path], " "))
+			}
+		}
+	}
+
+	w.Flush()
+	return buffer.String()
+}
@@ -0,0 +1,72 @@
+package api2go
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resource descriptions", func() {
+	var api *API
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		api.AddResource(Post{}, searchableSource{&fixtureSource{map[string]*Post{}, false}})
+		api.AddResource(User{}, &userSource{})
+	})
+
+	It("describes a resource with relationships", func() {
+		description, ok := api.Describe("posts")
+		Expect(ok).To(BeTrue())
+		Expect(description).To(Equal(ResourceDescription{
+			Name:          "posts",
+			Interfaces:    []string{"CRUD", "FindAll", "PaginatedFindAll", "Searchable"},
+			Relationships: []string{"author", "comments", "bananas"},
+			Methods:       []string{"DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST"},
+		}))
+	})
+
+	It("describes a resource without relationships", func() {
+		description, ok := api.Describe("users")
+		Expect(ok).To(BeTrue())
+		Expect(description.Relationships).To(BeEmpty())
+		Expect(description.Methods).To(ContainElement("GET"))
+	})
+
+	It("returns false for unknown resources", func() {
+		_, ok := api.Describe("unicorns")
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("RouteTree", func() {
+	var api *API
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false})
+		api.AddSubResource(Post{}, User{}, &userSource{})
+	})
+
+	It("lists the routes of all resources", func() {
+		Expect(api.RouteTree()).To(Equal(`posts
+  /v1/posts                               OPTIONS GET POST
+  /v1/posts/:id                           OPTIONS GET HEAD DELETE PATCH
+  /v1/posts/:id/relationships/author      GET PATCH
+  /v1/posts/:id/relationships/comments    GET PATCH POST DELETE
+  /v1/posts/:id/relationships/bananas     GET PATCH POST DELETE
+posts/users
+  /v1/posts/:id/users             OPTIONS GET POST
+  /v1/posts/:id/users/:childID    OPTIONS GET HEAD DELETE PATCH
+`))
+	})
+
+	It("lists the routes below every base path", func() {
+		api.SetBasePath("v2")
+		Expect(api.RouteTree()).To(ContainSubstring("  /v1/posts/:id/users/:childID    OPTIONS GET HEAD DELETE PATCH\n"))
+		Expect(api.RouteTree()).To(ContainSubstring("  /v2/posts/:id/users/:childID    OPTIONS GET HEAD DELETE PATCH\n"))
+	})
+
+	It("is empty without resources", func() {
+		Expect(NewAPI("v1").RouteTree()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,223 @@
+package api2go
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+// EnableEmbedding enables the `embed` query parameter as an alternative to `include`.
+// Related resources requested with e.g. `embed=author,comments.author` are loaded from the
+// EmbedProvider of their resources and inlined into the attributes of the resources that
+// reference them, as object for to-one and as array for to-many relationships.
+func (api *API) EnableEmbedding() {
+	api.embedding = true
+}
+
+// embeddingEnabled checks if the api that serves the request has embedding enabled
+func embeddingEnabled(r *http.Request) bool {
+	api, ok := r.Context().Value(api_api).(*API)
+	return ok && api.embedding
+}
+
+// embeddedResponder adds the objects that have been loaded for the `embed`
+// query parameter to a Responder
+type embeddedResponder struct {
+	Responder
+	tree     IncludeTree
+	embedded []jsonapi.MarshalIdentifier
+}
+
+// withEmbeds loads the related resources requested with the `embed` query parameter
+// from the EmbedProvider of their resources. Relationships of resources without an
+// EmbedProvider are skipped.
+func (res *resource) withEmbeds(c context.Context, response Responder, req Request) (Responder, error) {
+	api, ok := c.Value(api_api).(*API)
+	if !ok || len(req.Embeds) == 0 || response.Result() == nil {
+		return response, nil
+	}
+
+	// empty relationships are inlined too, even if nothing has been loaded
+	embedded, err := api.loadEmbeds(identifiers(response.Result()), req.Embeds, req)
+	if err != nil {
+		return response, err
+	}
+
+	return embeddedResponder{Responder: response, tree: req.Embeds, embedded: embedded}, nil
+}
+
+// loadEmbeds loads the relationships in `tree` of all `objs`, and recursively the
+// relationships of the loaded objects. Unlike includes, an object is loaded again for
+// every path it is embedded in, because the nested relationships can differ per path.
+func (api *API) loadEmbeds(objs []jsonapi.MarshalIdentifier, tree IncludeTree, req Request) ([]jsonapi.MarshalIdentifier, error) {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var embedded []jsonapi.MarshalIdentifier
+	for _, name := range names {
+		ids := map[string][]string{}
+		seen := map[string]bool{}
+		for _, obj := range objs {
+			linked, ok := obj.(jsonapi.MarshalLinkedRelations)
+			if !ok {
+				continue
+			}
+
+			for _, reference := range linked.GetReferencedIDs() {
+				key := reference.Type + "/" + reference.ID
+				if reference.Name != name || reference.ID == "" || seen[key] {
+					continue
+				}
+
+				seen[key] = true
+				ids[reference.Type] = append(ids[reference.Type], reference.ID)
+			}
+		}
+
+		types := make([]string, 0, len(ids))
+		for t := range ids {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+
+		for _, t := range types {
+			related := api.resource(t)
+			if related == nil {
+				continue
+			}
+
+			provider, ok := related.source.(EmbedProvider)
+			if !ok {
+				continue
+			}
+
+			response, err := provider.FindEmbedded(ids[t], req)
+			if err != nil {
+				return nil, err
+			}
+			if response == nil || response.Result() == nil {
+				continue
+			}
+
+			loaded := identifiers(related.maskFields(response, req).Result())
+			embedded = append(embedded, loaded...)
+
+			nested, err := api.loadEmbeds(loaded, tree[name], req)
+			if err != nil {
+				return nil, err
+			}
+			embedded = append(embedded, nested...)
+		}
+	}
+
+	return embedded, nil
+}
+
+// mergeEmbedded inlines the embedded objects of an embeddedResponder into the
+// attributes of the marshaled document
+func mergeEmbedded(document map[string]interface{}, obj Responder, info Information) error {
+	// includes are loaded after the embeds and wrap them
+	if included, ok := obj.(includedResponder); ok {
+		obj = included.Responder
+	}
+
+	embeds, ok := obj.(embeddedResponder)
+	if !ok {
+		return nil
+	}
+
+	entries := map[string]map[string]interface{}{}
+	for _, obj := range embeds.embedded {
+		marshaled, err := jsonapi.MarshalWithURLs(obj, info)
+		if err != nil {
+			return err
+		}
+
+		if entry, ok := marshaled["data"].(map[string]interface{}); ok {
+			entries[embedKey(entry)] = entry
+		}
+	}
+
+	switch data := document["data"].(type) {
+	case map[string]interface{}:
+		embedInto(data, embeds.tree, entries)
+	case []map[string]interface{}:
+		for _, entry := range data {
+			embedInto(entry, embeds.tree, entries)
+		}
+	}
+
+	return nil
+}
+
+// embedInto sets the attributes named after the relationships in `tree` to the
+// entries they reference. Entries that have not been loaded are left out.
+func embedInto(entry map[string]interface{}, tree IncludeTree, entries map[string]map[string]interface{}) {
+	attributes, _ := entry["attributes"].(map[string]interface{})
+	relationships, _ := entry["relationships"].(map[string]map[string]interface{})
+	if attributes == nil {
+		return
+	}
+
+	// the same entry can be embedded with different nested relationships
+	embedded := func(linkage map[string]interface{}, tree IncludeTree) (map[string]interface{}, bool) {
+		related, ok := entries[embedKey(linkage)]
+		if !ok {
+			return nil, false
+		}
+
+		copied := make(map[string]interface{}, len(related))
+		for key, value := range related {
+			copied[key] = value
+		}
+		if relatedAttributes, ok := related["attributes"].(map[string]interface{}); ok {
+			copiedAttributes := make(map[string]interface{}, len(relatedAttributes))
+			for key, value := range relatedAttributes {
+				copiedAttributes[key] = value
+			}
+			copied["attributes"] = copiedAttributes
+		}
+
+		embedInto(copied, tree, entries)
+		return copied, true
+	}
+
+	for name, nested := range tree {
+		relationship, ok := relationships[name]
+		if !ok {
+			continue
+		}
+
+		switch linkage := relationship["data"].(type) {
+		case map[string]interface{}:
+			if related, ok := embedded(linkage, nested); ok {
+				attributes[name] = related
+			}
+		case []map[string]interface{}:
+			list := []map[string]interface{}{}
+			for _, reference := range linkage {
+				if related, ok := embedded(reference, nested); ok {
+					list = append(list, related)
+				}
+			}
+			attributes[name] = list
+		case []interface{}:
+			attributes[name] = []map[string]interface{}{}
+		case nil:
+			if _, ok := relationship["data"]; ok {
+				attributes[name] = nil
+			}
+		}
+	}
+}
+
+// embedKey identifies a marshaled resource object or resource identifier
+func embedKey(entry map[string]interface{}) string {
+	return fmt.Sprintf("%v/%v", entry["type"], entry["id"])
+}
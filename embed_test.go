@@ -0,0 +1,171 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// embedSource additionally implements EmbedProvider and records the ids requested for embedding
+type embedSource struct {
+	includeSource
+	embedded [][]string
+}
+
+func (s *embedSource) FindEmbedded(ids []string, req Request) (Responder, error) {
+	s.embedded = append(s.embedded, ids)
+
+	result := []jsonapi.MarshalIdentifier{}
+	for _, id := range ids {
+		for _, obj := range s.objs {
+			if obj.GetID() == id {
+				result = append(result, obj)
+			}
+		}
+	}
+
+	return &Response{Res: result}, nil
+}
+
+var _ = Describe("Embedding", func() {
+	var (
+		api     *API
+		writers *embedSource
+		rec     *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		api.EnableEmbedding()
+		api.AddResource(Book{}, &plainIncludeSource{objs: []jsonapi.MarshalIdentifier{
+			Book{ID: "1", Title: "First", AuthorID: "1", ReviewIDs: []string{"1", "2"}},
+			Book{ID: "2", Title: "Second", AuthorID: "1"},
+			Book{ID: "3", Title: "Anonymous"},
+		}})
+		writers = &embedSource{includeSource: includeSource{plainIncludeSource: plainIncludeSource{objs: []jsonapi.MarshalIdentifier{
+			Writer{ID: "1", Name: "Ada", AgentID: "1"},
+		}}}}
+		api.AddResource(Writer{}, writers)
+		api.AddResource(Agent{}, &embedSource{includeSource: includeSource{plainIncludeSource: plainIncludeSource{objs: []jsonapi.MarshalIdentifier{
+			Agent{ID: "1", Name: "Bob"},
+		}}}})
+		api.AddResource(Review{}, &embedSource{includeSource: includeSource{plainIncludeSource: plainIncludeSource{objs: []jsonapi.MarshalIdentifier{
+			Review{ID: "1", Text: "Great"},
+			Review{ID: "2", Text: "Boring"},
+		}}}})
+		rec = httptest.NewRecorder()
+	})
+
+	type document struct {
+		Data     json.RawMessage          `json:"data"`
+		Included []map[string]interface{} `json:"included"`
+	}
+
+	get := func(path string) document {
+		req, err := http.NewRequest("GET", path, nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var result document
+		Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+		return result
+	}
+
+	attributes := func(data json.RawMessage) map[string]interface{} {
+		var entry struct {
+			Attributes map[string]interface{} `json:"attributes"`
+		}
+		Expect(json.Unmarshal(data, &entry)).To(Succeed())
+		return entry.Attributes
+	}
+
+	It("inlines to-one relationships into the attributes", func() {
+		result := get("/v1/books/1?embed=author")
+		author := attributes(result.Data)["author"].(map[string]interface{})
+		Expect(author["id"]).To(Equal("1"))
+		Expect(author["type"]).To(Equal("writers"))
+		Expect(author["attributes"]).To(Equal(map[string]interface{}{"name": "Ada"}))
+		Expect(result.Included).To(BeEmpty())
+	})
+
+	It("inlines to-many relationships as array", func() {
+		result := get("/v1/books/1?embed=reviews")
+		reviews := attributes(result.Data)["reviews"].([]interface{})
+		Expect(reviews).To(HaveLen(2))
+		Expect(reviews[0].(map[string]interface{})["attributes"]).To(Equal(map[string]interface{}{"text": "Great"}))
+		Expect(reviews[1].(map[string]interface{})["attributes"]).To(Equal(map[string]interface{}{"text": "Boring"}))
+	})
+
+	It("inlines empty relationships", func() {
+		result := get("/v1/books/3?embed=author,reviews")
+		Expect(attributes(result.Data)).To(HaveKeyWithValue("author", BeNil()))
+		Expect(attributes(result.Data)).To(HaveKeyWithValue("reviews", BeEmpty()))
+	})
+
+	It("loads every related entry once for collections", func() {
+		var collection []json.RawMessage
+		Expect(json.Unmarshal(get("/v1/books?embed=author").Data, &collection)).To(Succeed())
+		Expect(collection).To(HaveLen(3))
+		Expect(attributes(collection[1])["author"]).To(HaveKeyWithValue("id", "1"))
+		Expect(writers.embedded).To(Equal([][]string{{"1"}}))
+	})
+
+	It("inlines nested relationships", func() {
+		result := get("/v1/books/1?embed=author.agent")
+		author := attributes(result.Data)["author"].(map[string]interface{})
+		agent := author["attributes"].(map[string]interface{})["agent"].(map[string]interface{})
+		Expect(agent["attributes"]).To(Equal(map[string]interface{}{"name": "Bob"}))
+	})
+
+	It("can be combined with includes", func() {
+		result := get("/v1/books/1?embed=author&include=author")
+		Expect(attributes(result.Data)["author"]).To(HaveKeyWithValue("id", "1"))
+		Expect(result.Included).To(HaveLen(1))
+		Expect(result.Included[0]).To(HaveKeyWithValue("type", "writers"))
+		Expect(writers.requested).To(Equal([][]string{{"1"}}))
+		Expect(writers.embedded).To(Equal([][]string{{"1"}}))
+	})
+
+	It("skips resources without EmbedProvider and unknown relationships", func() {
+		api = NewAPI("v1")
+		api.EnableEmbedding()
+		api.AddResource(Book{}, &plainIncludeSource{objs: []jsonapi.MarshalIdentifier{
+			Book{ID: "1", Title: "First", AuthorID: "1"},
+		}})
+		api.AddResource(Writer{}, &plainIncludeSource{objs: []jsonapi.MarshalIdentifier{
+			Writer{ID: "1", Name: "Ada"},
+		}})
+
+		result := get("/v1/books/1?embed=author,unicorns")
+		Expect(attributes(result.Data)).To(Equal(map[string]interface{}{"title": "First"}))
+	})
+
+	It("ignores the embed parameter unless enabled", func() {
+		api = NewAPI("v1")
+		api.AddResource(Book{}, &plainIncludeSource{objs: []jsonapi.MarshalIdentifier{
+			Book{ID: "1", Title: "First", AuthorID: "1"},
+		}})
+		api.AddResource(Writer{}, writers)
+
+		result := get("/v1/books/1?embed=author")
+		Expect(attributes(result.Data)).To(Equal(map[string]interface{}{"title": "First"}))
+		Expect(writers.embedded).To(BeEmpty())
+	})
+
+	It("accepts the embed parameter with strict query params", func() {
+		api.EnableStrictQueryParams()
+		result := get("/v1/books/1?embed=author")
+		Expect(attributes(result.Data)["author"]).To(HaveKeyWithValue("id", "1"))
+	})
+
+	It("announces the interface", func() {
+		description, ok := api.Describe("writers")
+		Expect(ok).To(BeTrue())
+		Expect(description.Interfaces).To(ContainElement("EmbedProvider"))
+	})
+})
@@ -1,9 +1,13 @@
 package api2go
 
 import (
+	"crypto/rand"
 	"fmt"
 	"log"
+	"math"
+	"net/http"
 	"strconv"
+	"time"
 )
 
 // HTTPError is used for errors
@@ -12,6 +16,8 @@ type HTTPError struct {
 	msg    string
 	status int
 	Errors []Error `json:"errors,omitempty"`
+	// RetryAfter is sent as `Retry-After` header in seconds if it is greater than zero
+	RetryAfter time.Duration `json:"-"`
 }
 
 // Error can be used for all kind of application errors
@@ -90,6 +96,62 @@ func NewHTTPError(err error, msg string, status int) HTTPError {
 	return HTTPError{err: err, msg: msg, status: status}
 }
 
+// NewHTTPErrorWithID creates an error like NewHTTPError, but with a single error object
+// that has the given `id`, e.g. to correlate the error with a support ticket. A random
+// UUID is used if `id` is empty.
+func NewHTTPErrorWithID(id string, err error, msg string, status int) HTTPError {
+	if id == "" {
+		id = newErrorID()
+	}
+
+	httpError := NewHTTPError(err, msg, status)
+	httpError.Errors = append(httpError.Errors, Error{
+		ID:     id,
+		Status: strconv.Itoa(status),
+		Title:  msg,
+	})
+
+	return httpError
+}
+
+// newErrorID returns a random (version 4) UUID
+func newErrorID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		log.Println(err)
+		return ""
+	}
+
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
+
+// NewUnavailableError creates a 503 error that tells clients to retry the request
+// after `retryAfter`, e.g. if a backend service is unavailable.
+func NewUnavailableError(msg string, retryAfter time.Duration) HTTPError {
+	httpError := NewHTTPError(nil, msg, http.StatusServiceUnavailable)
+	httpError.RetryAfter = retryAfter
+
+	return httpError
+}
+
+// newDocumentError creates an HTTPError with a JSON pointer (RFC 6901) to the part
+// of the request document that caused the error, e.g. `/data/attributes/title`
+func newDocumentError(err error, msg string, status int, pointer string) HTTPError {
+	httpError := NewHTTPError(err, msg, status)
+	httpError.Errors = append(httpError.Errors, Error{
+		Status: strconv.Itoa(status),
+		Title:  msg,
+		Source: &ErrorSource{
+			Pointer: pointer,
+		},
+	})
+
+	return httpError
+}
+
 // Error returns a nice string represenation including the status
 func (e HTTPError) Error() string {
 	msg := fmt.Sprintf("http error (%d) %s and %d more errors", e.status, e.msg, len(e.Errors))
@@ -99,3 +161,8 @@ func (e HTTPError) Error() string {
 
 	return msg
 }
+
+// retryAfterSeconds returns the value of the Retry-After header, partial seconds are rounded up
+func (e HTTPError) retryAfterSeconds() string {
+	return strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds())))
+}
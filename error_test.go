@@ -2,6 +2,9 @@ package api2go
 
 import (
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -85,5 +88,66 @@ var _ = Describe("Errors test", func() {
 			expected := `{"errors":[{"id":"001","status":"500","code":"001","title":"Title must not be empty","detail":"Never occures in real life","meta":{"creator":"api2go"}}]}`
 			Expect(result).To(Equal(expected))
 		})
+
+		It("will be marshalled correctly with an about link only", func() {
+			httpErr := NewHTTPError(errors.New("Bad Request"), "Bad Request", 400)
+			httpErr.Errors = append(httpErr.Errors, Error{
+				Status: "400",
+				Title:  "Title must not be empty",
+				Links: &ErrorLinks{
+					About: "https://example.com/docs/errors#title",
+				},
+			})
+
+			m := JSONContentMarshaler{}
+			result := m.MarshalError(httpErr)
+			expected := `{"errors":[{"links":{"about":"https://example.com/docs/errors#title"},"status":"400","title":"Title must not be empty"}]}`
+			Expect(result).To(Equal(expected))
+		})
+	})
+
+	Context("error ids", func() {
+		It("uses the given id", func() {
+			httpErr := NewHTTPErrorWithID("ticket-42", errors.New("timeout"), "Service failed", http.StatusBadGateway)
+			m := JSONContentMarshaler{}
+			Expect(m.MarshalError(httpErr)).To(MatchJSON(`{"errors":[{"id":"ticket-42","status":"502","title":"Service failed"}]}`))
+		})
+
+		It("generates a UUID without id", func() {
+			first := NewHTTPErrorWithID("", nil, "Service failed", http.StatusBadGateway)
+			second := NewHTTPErrorWithID("", nil, "Service failed", http.StatusBadGateway)
+			Expect(first.Errors[0].ID).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+			Expect(first.Errors[0].ID).ToNot(Equal(second.Errors[0].ID))
+		})
+	})
+
+	Context("retry after", func() {
+		var rec *httptest.ResponseRecorder
+
+		BeforeEach(func() {
+			rec = httptest.NewRecorder()
+		})
+
+		handle := func(err error) {
+			req, _ := http.NewRequest("GET", "/v1/posts", nil)
+			HandleError(err, rec, req, DefaultContentMarshalers)
+		}
+
+		It("sets the Retry-After header of unavailable errors", func() {
+			handle(NewUnavailableError("database is unavailable", 30*time.Second))
+			Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(rec.Header().Get("Retry-After")).To(Equal("30"))
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"503","title":"database is unavailable"}]}`))
+		})
+
+		It("rounds partial seconds up", func() {
+			handle(NewUnavailableError("database is unavailable", 1500*time.Millisecond))
+			Expect(rec.Header().Get("Retry-After")).To(Equal("2"))
+		})
+
+		It("does not set the header without a duration", func() {
+			handle(NewHTTPError(nil, "database is unavailable", http.StatusServiceUnavailable))
+			Expect(rec.Header().Get("Retry-After")).To(BeEmpty())
+		})
 	})
 })
@@ -0,0 +1,91 @@
+package api2go
+
+import (
+	"context"
+	"sync"
+)
+
+// The actions of the events published after successful requests. The type of an
+// event is the resource name followed by the action, e.g. `posts.created`.
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+	EventDeleted = "deleted"
+)
+
+// ResourceEvent is published after a resource was created, updated or deleted
+type ResourceEvent struct {
+	// Type is the resource name followed by the action, e.g. `posts.created`
+	Type     string
+	Resource string
+	ID       string
+	// Object is the created or updated object, nil for deletions, or the []Filter of a
+	// deletion by FilterDeleter, which has no ID
+	Object  interface{}
+	Request Request
+}
+
+// EventHandler is called with every published event of the subscribed type
+type EventHandler func(event ResourceEvent) error
+
+// The EventBus interface is used to publish events to other resources, e.g. to update
+// the inventory after an order was created. Set it with API.SetEventBus.
+type EventBus interface {
+	Publish(event ResourceEvent) error
+	Subscribe(eventType string, handler EventHandler)
+}
+
+// SetEventBus sets the bus that events are published to after successful create,
+// update and delete requests. Events are published after the source was called,
+// but before the response is written, an error of the bus is answered instead
+// of the result.
+func (api *API) SetEventBus(bus EventBus) {
+	api.eventBus = bus
+}
+
+type eventBus struct {
+	mutex    sync.RWMutex
+	handlers map[string][]EventHandler
+}
+
+// NewEventBus returns an EventBus that calls the handlers of an event synchronously
+// in the order they were subscribed. Publish stops at the first error.
+func NewEventBus() EventBus {
+	return &eventBus{handlers: map[string][]EventHandler{}}
+}
+
+func (b *eventBus) Publish(event ResourceEvent) error {
+	b.mutex.RLock()
+	handlers := b.handlers[event.Type]
+	b.mutex.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *eventBus) Subscribe(eventType string, handler EventHandler) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// publish sends an event to the event bus of the api, if there is one
+func (res *resource) publish(c context.Context, action, id string, obj interface{}, req Request) error {
+	api, ok := c.Value(api_api).(*API)
+	if !ok || api.eventBus == nil {
+		return nil
+	}
+
+	return api.eventBus.Publish(ResourceEvent{
+		Type:     res.name + "." + action,
+		Resource: res.name,
+		ID:       id,
+		Object:   obj,
+		Request:  req,
+	})
+}
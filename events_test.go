@@ -0,0 +1,130 @@
+package api2go
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Events", func() {
+	Context("NewEventBus", func() {
+		It("calls the handlers of the event type in order", func() {
+			bus := NewEventBus()
+			called := []string{}
+			bus.Subscribe("posts.created", func(event ResourceEvent) error {
+				called = append(called, "first "+event.ID)
+				return nil
+			})
+			bus.Subscribe("posts.created", func(event ResourceEvent) error {
+				called = append(called, "second "+event.ID)
+				return nil
+			})
+			bus.Subscribe("posts.deleted", func(event ResourceEvent) error {
+				called = append(called, "deleted")
+				return nil
+			})
+
+			Expect(bus.Publish(ResourceEvent{Type: "posts.created", ID: "1"})).To(Succeed())
+			Expect(called).To(Equal([]string{"first 1", "second 1"}))
+		})
+
+		It("stops at the first error", func() {
+			bus := NewEventBus()
+			called := false
+			bus.Subscribe("posts.created", func(event ResourceEvent) error {
+				return errors.New("out of stock")
+			})
+			bus.Subscribe("posts.created", func(event ResourceEvent) error {
+				called = true
+				return nil
+			})
+
+			Expect(bus.Publish(ResourceEvent{Type: "posts.created"})).To(MatchError("out of stock"))
+			Expect(called).To(BeFalse())
+		})
+	})
+
+	Context("publishing", func() {
+		var (
+			api    *API
+			bus    EventBus
+			events []ResourceEvent
+			rec    *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			api = NewAPI("v1")
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}, false})
+			events = []ResourceEvent{}
+			bus = NewEventBus()
+			record := func(event ResourceEvent) error {
+				events = append(events, event)
+				return nil
+			}
+			bus.Subscribe("posts."+EventCreated, record)
+			bus.Subscribe("posts."+EventUpdated, record)
+			bus.Subscribe("posts."+EventDeleted, record)
+			api.SetEventBus(bus)
+			rec = httptest.NewRecorder()
+		})
+
+		doRequest := func(method, URL, body string) {
+			req, err := http.NewRequest(method, URL, strings.NewReader(body))
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+		}
+
+		It("publishes created resources", func() {
+			doRequest("POST", "/v1/posts", `{"data": {"type": "posts", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Type).To(Equal("posts.created"))
+			Expect(events[0].Resource).To(Equal("posts"))
+			Expect(events[0].ID).To(Equal("2"))
+			Expect(events[0].Object).To(Equal(&Post{ID: "2", Title: "New"}))
+		})
+
+		It("publishes updated resources", func() {
+			doRequest("PATCH", "/v1/posts/1", `{"data": {"id": "1", "type": "posts", "attributes": {"title": "Updated"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Type).To(Equal("posts.updated"))
+			Expect(events[0].ID).To(Equal("1"))
+		})
+
+		It("publishes deleted resources", func() {
+			doRequest("DELETE", "/v1/posts/1", "")
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Type).To(Equal("posts.deleted"))
+			Expect(events[0].Object).To(BeNil())
+		})
+
+		It("does not publish failed requests", func() {
+			doRequest("POST", "/v1/posts", `{"data": {"type": "posts", "attributes": {"title": ""}}}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(events).To(BeEmpty())
+		})
+
+		It("does not set Location if publishing a created resource fails", func() {
+			bus.Subscribe("posts.created", func(event ResourceEvent) error {
+				return NewHTTPError(nil, "out of stock", http.StatusConflict)
+			})
+			doRequest("POST", "/v1/posts", `{"data": {"type": "posts", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+			Expect(rec.Header().Get("Location")).To(BeEmpty())
+		})
+
+		It("answers with the error of the bus", func() {
+			bus.Subscribe("posts.deleted", func(event ResourceEvent) error {
+				return NewHTTPError(nil, "post is still referenced", http.StatusConflict)
+			})
+			doRequest("DELETE", "/v1/posts/1", "")
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+		})
+	})
+})
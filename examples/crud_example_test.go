@@ -44,6 +44,9 @@ var _ = Describe("CrudExample", func() {
 		Expect(rec.Code).To(Equal(http.StatusCreated))
 		Expect(rec.Body.String()).To(MatchJSON(`
 		{
+			"links": {
+				"self": "http://localhost:31415/v0/users"
+			},
 			"meta": {
 				"author": "The api2go examples crew",
 				"license": "wtfpl",
@@ -91,6 +94,9 @@ var _ = Describe("CrudExample", func() {
 		Expect(rec.Code).To(Equal(http.StatusCreated))
 		Expect(rec.Body.String()).To(MatchJSON(`
 		{
+			"links": {
+				"self": "http://localhost:31415/v0/chocolates"
+			},
 			"meta": {
 				"author": "The api2go examples crew",
 				"license": "wtfpl",
@@ -136,6 +142,9 @@ var _ = Describe("CrudExample", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(rec.Body.String()).To(MatchJSON(`
 		{
+			"links": {
+				"self": "http://localhost:31415/v0/users/1"
+			},
 			"meta": {
 				"author": "The api2go examples crew",
 				"license": "wtfpl",
@@ -210,6 +219,9 @@ var _ = Describe("CrudExample", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(rec.Body.String()).To(MatchJSON(`
 		{
+			"links": {
+				"self": "http://localhost:31415/v0/users/1"
+			},
 			"meta": {
 				"author": "The api2go examples crew",
 				"license": "wtfpl",
@@ -262,6 +274,9 @@ var _ = Describe("CrudExample", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(rec.Body.String()).To(MatchJSON(`
 		{
+			"links": {
+				"self": "http://localhost:31415/v0/users/1"
+			},
 			"meta": {
 				"author": "The api2go examples crew",
 				"license": "wtfpl",
@@ -326,6 +341,9 @@ var _ = Describe("CrudExample", func() {
 			Expect(rec.Code).To(Equal(http.StatusCreated))
 			Expect(rec.Body.String()).To(MatchJSON(`
 			{
+				"links": {
+					"self": "http://localhost:31415/v0/chocolates"
+				},
 				"meta": {
 					"author": "The api2go examples crew",
 					"license": "wtfpl",
@@ -352,6 +370,9 @@ var _ = Describe("CrudExample", func() {
 			Expect(rec.Code).To(Equal(http.StatusOK))
 			Expect(rec.Body.String()).To(MatchJSON(`
 			{
+				"links": {
+					"self": "http://localhost:31415/v0/chocolates"
+				},
 				"meta": {
 					"author": "The api2go examples crew",
 					"license": "wtfpl",
@@ -386,6 +407,9 @@ var _ = Describe("CrudExample", func() {
 			Expect(rec.Code).To(Equal(http.StatusOK))
 			Expect(rec.Body.String()).To(MatchJSON(`
 			{
+				"links": {
+					"self": "http://localhost:31415/v0/users/1"
+				},
 				"meta": {
 					"author": "The api2go examples crew",
 					"license": "wtfpl",
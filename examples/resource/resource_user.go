@@ -39,7 +39,7 @@ func (s UserResource) FindAll(r api2go.Request) (api2go.Responder, error) {
 }
 
 // PaginatedFindAll can be used to load users in chunks
-func (s UserResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
+func (s UserResource) PaginatedFindAll(r api2go.Request) (int64, api2go.Responder, error) {
 	var (
 		result                      []model.User
 		number, size, offset, limit string
@@ -111,7 +111,7 @@ func (s UserResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder
 		}
 	}
 
-	return uint(len(users)), &Response{Res: result}, nil
+	return int64(len(users)), &Response{Res: result}, nil
 }
 
 // FindOne to satisfy `api2go.DataSource` interface
@@ -0,0 +1,91 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+// AddExternalResource registers a resource that is served by another service, e.g. a
+// microservice behind this API. The routes of the resource are registered like for
+// AddResource, but all requests are forwarded to `targetURL` with the prefix of the API
+// stripped, e.g. `GET /v1/posts/1` to `GET <targetURL>/posts/1`. The middlewares of the API
+// and the resource options, e.g. WithAuthorizer, are applied before forwarding, and all
+// request headers including the ones added by middlewares like `Authorization` are passed on.
+// It panics if `targetURL` is not an absolute url.
+func (api *API) AddExternalResource(prototype jsonapi.MarshalIdentifier, targetURL string, options ...ResourceOption) {
+	target, err := url.Parse(targetURL)
+	if err != nil || !target.IsAbs() {
+		panic(fmt.Sprintf("invalid target url '%s' for external resource", targetURL))
+	}
+
+	resourceType := reflect.TypeOf(prototype)
+	if resourceType.Kind() != reflect.Struct && resourceType.Kind() != reflect.Ptr {
+		panic("pass an empty resource struct or a struct pointer to AddExternalResource!")
+	}
+
+	res := api.newResource(prototype, api.marshalers, options)
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		HandleError(NewHTTPError(err, http.StatusText(http.StatusBadGateway), http.StatusBadGateway), w, r, api.marshalers)
+	}
+
+	forward := func(w http.ResponseWriter, r *http.Request) {
+		prefix, _ := r.Context().Value(api_prefix).(string)
+		forwarded := r.Clone(r.Context())
+		if prefix != "" {
+			forwarded.URL.Path = strings.TrimPrefix(r.URL.Path, "/"+prefix)
+			forwarded.URL.RawPath = ""
+		}
+
+		proxy.ServeHTTP(w, forwarded)
+	}
+
+	baseURL := "/" + res.name
+	idURL := baseURL + "/:id"
+	handle := func(method, route string) {
+		handler := http.HandlerFunc(forward)
+		if res.readOnly && !readMethods[method] {
+			handler = readOnlyHandler(route, baseURL, idURL, api.marshalers)
+		} else {
+			res.methods[method] = true
+		}
+
+		res.routes = append(res.routes, resourceRoute{method: method, path: route, handler: res.serve(handler)})
+	}
+
+	// the routes are the same as for AddResource with all optional interfaces, because the
+	// interfaces of the other service are unknown. As there, HEAD on the collection is
+	// answered by the router with the GET route.
+	for _, method := range []string{"OPTIONS", "GET", "POST", "PATCH", "DELETE"} {
+		handle(method, baseURL)
+	}
+	for _, method := range []string{"OPTIONS", "GET", "HEAD", "PATCH", "DELETE"} {
+		handle(method, idURL)
+	}
+
+	if casted, ok := prototype.(jsonapi.MarshalReferences); ok {
+		res.references = casted.GetReferences()
+		for _, relation := range res.references {
+			res.relationships = append(res.relationships, relation.Name)
+			for _, method := range []string{"GET", "PATCH", "POST", "DELETE"} {
+				handle(method, idURL+"/relationships/"+relation.Name)
+			}
+			handle("GET", idURL+"/"+relation.Name)
+		}
+	}
+
+	api.resources = append(api.resources, res)
+	res.register(api.router, api.info.prefix)
+}
+
+// external checks if the resource is served by another service
+func (res *resource) external() bool {
+	return res.source == nil
+}
@@ -0,0 +1,137 @@
+package api2go
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// forwardedRequest is a request received by the upstream service
+type forwardedRequest struct {
+	method        string
+	uri           string
+	authorization string
+	body          string
+}
+
+var _ = Describe("External resources", func() {
+	var (
+		api       *API
+		upstream  *httptest.Server
+		forwarded []forwardedRequest
+		rec       *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		forwarded = nil
+		upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			forwarded = append(forwarded, forwardedRequest{
+				method:        r.Method,
+				uri:           r.URL.RequestURI(),
+				authorization: r.Header.Get("Authorization"),
+				body:          string(body),
+			})
+
+			w.Header().Set("Content-Type", defaultContentTypeHeader)
+			w.WriteHeader(http.StatusTeapot)
+			w.Write([]byte(`{"data": null}`))
+		}))
+
+		api = NewAPI("v1")
+	})
+
+	AfterEach(func() {
+		upstream.Close()
+	})
+
+	doRequest := func(method, URL, body string) {
+		req := newTestRequest(method, URL, body)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec = serve(api, req)
+	}
+
+	It("forwards requests without the api prefix", func() {
+		api.AddExternalResource(Post{}, upstream.URL)
+		doRequest("GET", "/v1/posts/1?include=author", "")
+		Expect(rec.Code).To(Equal(http.StatusTeapot))
+		Expect(rec.Body.String()).To(Equal(`{"data": null}`))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(defaultContentTypeHeader))
+		Expect(forwarded).To(Equal([]forwardedRequest{
+			{method: "GET", uri: "/posts/1?include=author", authorization: "Bearer secret"},
+		}))
+	})
+
+	It("forwards the routes of collections and relationships", func() {
+		api.AddExternalResource(Post{}, upstream.URL+"/api")
+		doRequest("POST", "/v1/posts", `{"data": {"type": "posts"}}`)
+		doRequest("PATCH", "/v1/posts/1/relationships/comments", `{"data": []}`)
+		doRequest("GET", "/v1/posts/1/author", "")
+		Expect(forwarded).To(Equal([]forwardedRequest{
+			{method: "POST", uri: "/api/posts", authorization: "Bearer secret", body: `{"data": {"type": "posts"}}`},
+			{method: "PATCH", uri: "/api/posts/1/relationships/comments", authorization: "Bearer secret", body: `{"data": []}`},
+			{method: "GET", uri: "/api/posts/1/author", authorization: "Bearer secret"},
+		}))
+	})
+
+	It("applies the resource options before forwarding", func() {
+		api.AddExternalResource(Post{}, upstream.URL, WithAuthorizer(headerAuthorizer{token: "Bearer other", err: errors.New("no access")}))
+		doRequest("GET", "/v1/posts", "")
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(forwarded).To(BeEmpty())
+
+		api = NewAPI("v1")
+		api.AddExternalResource(Post{}, upstream.URL, WithReadOnly())
+		doRequest("DELETE", "/v1/posts/1", "")
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(forwarded).To(BeEmpty())
+	})
+
+	It("answers with 502 if the service is not available", func() {
+		api.AddExternalResource(Post{}, upstream.URL)
+		upstream.Close()
+		doRequest("GET", "/v1/posts", "")
+		Expect(rec.Code).To(Equal(http.StatusBadGateway))
+		Expect(rec.Body.String()).To(ContainSubstring(`"title":"Bad Gateway"`))
+	})
+
+	It("is described without interfaces", func() {
+		api.AddExternalResource(Post{}, upstream.URL)
+		description, ok := api.Describe("posts")
+		Expect(ok).To(BeTrue())
+		Expect(description.Interfaces).To(BeEmpty())
+		Expect(description.Relationships).To(Equal([]string{"author", "comments", "bananas"}))
+		Expect(description.Methods).To(Equal([]string{"DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST"}))
+	})
+
+	It("has no source", func() {
+		api.AddExternalResource(Post{}, upstream.URL)
+		source, ok := api.Source("posts")
+		Expect(ok).To(BeFalse())
+		Expect(source).To(BeNil())
+		Expect(api.HasResource("posts")).To(BeTrue())
+	})
+
+	It("answers HEAD requests like AddResource", func() {
+		api.AddExternalResource(Post{}, upstream.URL)
+		doRequest("HEAD", "/v1/posts", "")
+		doRequest("HEAD", "/v1/posts/1", "")
+		Expect(forwarded).To(Equal([]forwardedRequest{
+			{method: "HEAD", uri: "/posts", authorization: "Bearer secret"},
+			{method: "HEAD", uri: "/posts/1", authorization: "Bearer secret"},
+		}))
+	})
+
+	It("panics for invalid target urls", func() {
+		Expect(func() { api.AddExternalResource(Post{}, "/posts") }).To(PanicWith("invalid target url '/posts' for external resource"))
+	})
+
+	It("panics if the resource is already registered", func() {
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false})
+		Expect(func() { api.AddExternalResource(Post{}, upstream.URL) }).To(PanicWith("resource 'posts' already registered"))
+	})
+})
@@ -0,0 +1,138 @@
+package api2go
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FilterOperator is the comparison of a filter, given in bracket notation
+// as in `filter[age][gte]=18`
+type FilterOperator string
+
+// The supported filter operators. A filter without operator, such as
+// `filter[name]=api2go`, uses Eq.
+const (
+	Eq   FilterOperator = "eq"
+	Ne   FilterOperator = "ne"
+	Gt   FilterOperator = "gt"
+	Gte  FilterOperator = "gte"
+	Lt   FilterOperator = "lt"
+	Lte  FilterOperator = "lte"
+	Like FilterOperator = "like"
+	In   FilterOperator = "in"
+	// From and To are the inclusive bounds of a time range, see ParseTimeRangeFilter
+	From FilterOperator = "from"
+	To   FilterOperator = "to"
+)
+
+// FilterLogic combines the children of a compound Filter
+type FilterLogic string
+
+// The supported logical operators of compound filters
+const (
+	FilterAnd FilterLogic = "and"
+	FilterOr  FilterLogic = "or"
+)
+
+var filterOperators = map[FilterOperator]bool{
+	Eq:   true,
+	Ne:   true,
+	Gt:   true,
+	Gte:  true,
+	Lt:   true,
+	Lte:  true,
+	Like: true,
+	In:   true,
+	From: true,
+	To:   true,
+}
+
+var filterRegex = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+// Filter is a single parsed `filter` query parameter, or a compound filter
+// if Op is set. Compound filters only contain Op and Children.
+type Filter struct {
+	Field    string
+	Operator FilterOperator
+	// Values contains the comma separated values for In, and exactly one value
+	// for all other operators
+	Values []string
+
+	Op       FilterLogic
+	Children []Filter
+}
+
+// ParseFilters parses all `filter[field]` and `filter[field][op]` query parameters.
+// An error is returned if an operator is not one of the FilterOperator constants.
+// `filter[q]` is not included, it is passed as Request.Search instead, and neither
+// are the geo filters, which are passed as Request.GeoFilter.
+func ParseFilters(query url.Values) ([]Filter, error) {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	filters := []Filter{}
+	for _, key := range keys {
+		matches := filterRegex.FindStringSubmatch(key)
+		if matches == nil || key == "filter[q]" || geoFilterKeys[key] {
+			continue
+		}
+
+		operator := Eq
+		if matches[2] != "" {
+			operator = FilterOperator(matches[2])
+		}
+
+		if !filterOperators[operator] {
+			return nil, fmt.Errorf("unknown filter operator \"%s\" in %s", operator, key)
+		}
+
+		for _, value := range query[key] {
+			values := []string{value}
+			if operator == In {
+				values = strings.Split(value, ",")
+			}
+
+			filters = append(filters, Filter{
+				Field:    matches[1],
+				Operator: operator,
+				Values:   values,
+			})
+		}
+	}
+
+	return filters, nil
+}
+
+// ParseFilterTree parses the same query parameters as ParseFilters into a filter tree.
+// Repeated parameters, e.g. `filter[status]=active&filter[status]=pending`, are
+// combined with FilterOr, the resulting filters are combined with FilterAnd. The root is
+// always a FilterAnd filter, which has no children if there are no filters.
+func ParseFilterTree(query url.Values) (Filter, error) {
+	filters, err := ParseFilters(query)
+	if err != nil {
+		return Filter{}, err
+	}
+
+	root := Filter{Op: FilterAnd, Children: []Filter{}}
+	for i := 0; i < len(filters); {
+		j := i + 1
+		for j < len(filters) && filters[j].Field == filters[i].Field && filters[j].Operator == filters[i].Operator {
+			j++
+		}
+
+		if j-i == 1 {
+			root.Children = append(root.Children, filters[i])
+		} else {
+			root.Children = append(root.Children, Filter{Op: FilterOr, Children: filters[i:j]})
+		}
+		i = j
+	}
+
+	return root, nil
+}
@@ -0,0 +1,173 @@
+package api2go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// The FilterDeleter interface can be optionally implemented by a source to delete all
+// entries that match the filters in the body of a `DELETE /resource` request, e.g.
+//
+//	{"filter": {"status": "archived", "updated": {"lt": "2020-01-01"}, "id": {"in": ["1", "2"]}}}
+//
+// The filters are parsed like the `filter` query parameters, so the example above is the
+// same as `filter[status]=archived&filter[updated][lt]=2020-01-01&filter[id][in]=1,2`.
+// Requests without filters are rejected, so that a resource can not be deleted completely
+// by accident. The returned status code is handled as for Delete. One `<resource>.deleted`
+// event without ID is published, its Object are the filters.
+type FilterDeleter interface {
+	DeleteWhere(filters []Filter, req Request) (Responder, error)
+}
+
+func (res *resource) handleDeleteWhere(c context.Context, w http.ResponseWriter, r *http.Request) error {
+	source, ok := res.source.(FilterDeleter)
+	if !ok {
+		return NewHTTPError(nil, "Resource does not implement the FilterDeleter interface", http.StatusMethodNotAllowed)
+	}
+
+	inc, err := unmarshalRequest(r, res.marshalers)
+	if err != nil {
+		return err
+	}
+
+	filters, err := parseFilterBody(inc["filter"])
+	if err != nil {
+		return err
+	}
+
+	req := BuildRequest(c, r)
+	response, err := source.DeleteWhere(filters, req)
+	if err != nil {
+		return err
+	}
+
+	// the deleted ids are unknown, so one event without id is published for the deletion
+	if err := res.publish(c, EventDeleted, "", filters, req); err != nil {
+		return err
+	}
+
+	switch response.StatusCode() {
+	case http.StatusOK:
+		data := map[string]interface{}{
+			"meta": response.Metadata(),
+		}
+
+		return marshalResponse(c, data, w, http.StatusOK, r, res.marshalers)
+	case http.StatusAccepted:
+		w.WriteHeader(http.StatusAccepted)
+		return nil
+	case http.StatusNoContent:
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	default:
+		return fmt.Errorf("invalid status code %d from resource %s for method DeleteWhere", response.StatusCode(), res.name)
+	}
+}
+
+// parseFilterBody converts the `filter` member of a request body into query parameters
+// and parses them with ParseFilters. A field maps to a value, a list of values, or an
+// object with the operators as keys.
+func parseFilterBody(body interface{}) ([]Filter, error) {
+	members, ok := body.(map[string]interface{})
+	if !ok || len(members) == 0 {
+		return nil, newFilterBodyError("/filter", "The request body must contain a filter object with at least one filter")
+	}
+
+	query := url.Values{}
+	for field, value := range members {
+		operators, ok := value.(map[string]interface{})
+		if !ok {
+			operators = map[string]interface{}{"": value}
+		}
+
+		for operator, value := range operators {
+			key := "filter[" + field + "]"
+			pointer := "/filter/" + field
+			if operator != "" {
+				key += "[" + operator + "]"
+				pointer += "/" + operator
+			}
+
+			values, err := filterBodyValues(value, FilterOperator(operator) == In)
+			if err != nil {
+				return nil, newFilterBodyError(pointer, err.Error())
+			}
+
+			// ParseFilters skips search, geo and malformed keys, which would otherwise
+			// silently widen the deletion
+			parsed, err := ParseFilters(url.Values{key: values})
+			if err != nil {
+				return nil, newFilterBodyError(pointer, err.Error())
+			}
+			if len(parsed) == 0 {
+				return nil, newFilterBodyError(pointer, fmt.Sprintf("%s is not a valid filter", key))
+			}
+			query[key] = values
+		}
+	}
+
+	filters, err := ParseFilters(query)
+	if err != nil {
+		return nil, newFilterBodyError("/filter", err.Error())
+	}
+
+	if len(filters) == 0 {
+		return nil, newFilterBodyError("/filter", "The request body must contain a filter object with at least one filter")
+	}
+
+	return filters, nil
+}
+
+// filterBodyValues returns the query parameter values of a filter value. The values
+// of a list are joined with commas for In and result in one filter each otherwise.
+func filterBodyValues(value interface{}, in bool) ([]string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		list = []interface{}{value}
+	}
+
+	values := make([]string, 0, len(list))
+	for _, entry := range list {
+		switch entry := entry.(type) {
+		case string:
+			values = append(values, entry)
+		case bool:
+			values = append(values, strconv.FormatBool(entry))
+		case fmt.Stringer:
+			// json.Number
+			values = append(values, entry.String())
+		case float64:
+			values = append(values, strconv.FormatFloat(entry, 'f', -1, 64))
+		default:
+			return nil, errors.New("Filter values must be strings, numbers or booleans")
+		}
+	}
+
+	if len(values) == 0 {
+		return nil, errors.New("Filter values must not be empty")
+	}
+
+	if in {
+		return []string{strings.Join(values, ",")}, nil
+	}
+
+	return values, nil
+}
+
+// newFilterBodyError returns a 400 error pointing to the invalid part of the filter body
+func newFilterBodyError(pointer, detail string) HTTPError {
+	httpError := NewHTTPError(nil, detail, http.StatusBadRequest)
+	httpError.Errors = []Error{{
+		Status: strconv.Itoa(http.StatusBadRequest),
+		Title:  "Invalid filter",
+		Detail: detail,
+		Source: &ErrorSource{Pointer: pointer},
+	}}
+
+	return httpError
+}
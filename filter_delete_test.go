@@ -0,0 +1,119 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// filterDeleteSource records the filters of DeleteWhere
+type filterDeleteSource struct {
+	*fixtureSource
+	filters []Filter
+	code    int
+}
+
+func (s *filterDeleteSource) DeleteWhere(filters []Filter, req Request) (Responder, error) {
+	s.filters = filters
+	return &Response{Code: s.code, Meta: map[string]interface{}{"deleted": 2}}, nil
+}
+
+var _ = Describe("FilterDeleter", func() {
+	var (
+		api    *API
+		source *filterDeleteSource
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		source = &filterDeleteSource{fixtureSource: &fixtureSource{map[string]*Post{}, false}, code: http.StatusNoContent}
+		api = newPostAPI(source)
+	})
+
+	It("passes the filters of the body to DeleteWhere", func() {
+		rec = sendRequest(api, "DELETE", "/v1/posts", `{"filter": {"title": "Hello", "value": {"lt": 13.37}, "id": {"in": ["1", "2"]}, "draft": true}}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.filters).To(Equal([]Filter{
+			{Field: "draft", Operator: Eq, Values: []string{"true"}},
+			{Field: "id", Operator: In, Values: []string{"1", "2"}},
+			{Field: "title", Operator: Eq, Values: []string{"Hello"}},
+			{Field: "value", Operator: Lt, Values: []string{"13.37"}},
+		}))
+	})
+
+	It("publishes one deleted event with the filters", func() {
+		events := []ResourceEvent{}
+		bus := NewEventBus()
+		bus.Subscribe("posts."+EventDeleted, func(event ResourceEvent) error {
+			events = append(events, event)
+			return nil
+		})
+		api.SetEventBus(bus)
+
+		rec = sendRequest(api, "DELETE", "/v1/posts", `{"filter": {"title": "Hello"}}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].ID).To(BeEmpty())
+		Expect(events[0].Object).To(Equal([]Filter{{Field: "title", Operator: Eq, Values: []string{"Hello"}}}))
+	})
+
+	It("returns the meta data for 200", func() {
+		source.code = http.StatusOK
+		rec = sendRequest(api, "DELETE", "/v1/posts", `{"filter": {"title": ["Hello", "World"]}}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{"meta": {"deleted": 2}}`))
+		Expect(source.filters).To(HaveLen(2))
+	})
+
+	It("rejects requests without filters", func() {
+		for _, body := range []string{`{}`, `{"filter": {}}`, `{"filter": "title"}`} {
+			rec = sendRequest(api, "DELETE", "/v1/posts", body)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest), body)
+		}
+		Expect(source.filters).To(BeNil())
+	})
+
+	It("points to invalid filters", func() {
+		rec = sendRequest(api, "DELETE", "/v1/posts", `{"filter": {"title": {"eq": {"nested": true}}}}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+
+		var document struct {
+			Errors []Error `json:"errors"`
+		}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+		Expect(document.Errors).To(HaveLen(1))
+		Expect(document.Errors[0].Source.Pointer).To(Equal("/filter/title/eq"))
+	})
+
+	It("rejects filters that are not used for deletion", func() {
+		for _, body := range []string{`{"filter": {"q": "x"}}`, `{"filter": {"geo": {"within": "1,2,3"}}}`, `{"filter": {"bad]": "x"}}`, `{"filter": {"title": "Hello", "q": "x"}}`} {
+			rec = sendRequest(api, "DELETE", "/v1/posts", body)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest), body)
+		}
+		Expect(source.filters).To(BeNil())
+	})
+
+	It("rejects unknown operators", func() {
+		rec = sendRequest(api, "DELETE", "/v1/posts", `{"filter": {"title": {"near": "Hello"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(source.filters).To(BeNil())
+	})
+
+	It("announces the route", func() {
+		rec = sendRequest(api, "OPTIONS", "/v1/posts", "")
+		Expect(rec.Header().Get("Allow")).To(Equal("GET,POST,PATCH,DELETE,OPTIONS"))
+
+		description, ok := api.Describe("posts")
+		Expect(ok).To(BeTrue())
+		Expect(description.Interfaces).To(ContainElement("FilterDeleter"))
+	})
+
+	It("is not registered for other sources", func() {
+		api = newPostAPI(source.fixtureSource)
+		rec = sendRequest(api, "DELETE", "/v1/posts", `{"filter": {"title": "Hello"}}`)
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
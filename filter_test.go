@@ -0,0 +1,213 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"sort"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type filterableSource struct {
+	*fixtureSource
+	filter Filter
+}
+
+func (s *filterableSource) FilterAll(filter Filter, req Request) (Responder, error) {
+	s.filter = filter
+	ids := []string{}
+	for id := range s.posts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	result := []Post{}
+	for _, id := range ids {
+		if matchesFilter(*s.posts[id], filter) {
+			result = append(result, *s.posts[id])
+		}
+	}
+
+	return &Response{Res: result}, nil
+}
+
+// matchesFilter only supports eq filters on the title
+func matchesFilter(post Post, filter Filter) bool {
+	switch filter.Op {
+	case FilterAnd:
+		for _, child := range filter.Children {
+			if !matchesFilter(post, child) {
+				return false
+			}
+		}
+		return true
+	case FilterOr:
+		for _, child := range filter.Children {
+			if matchesFilter(post, child) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return post.Title == filter.Values[0]
+}
+
+var _ = Describe("Filters", func() {
+	Context("ParseFilters", func() {
+		parse := func(rawQuery string) ([]Filter, error) {
+			query, err := url.ParseQuery(rawQuery)
+			Expect(err).ToNot(HaveOccurred())
+			return ParseFilters(query)
+		}
+
+		It("uses eq without operator", func() {
+			filters, err := parse("filter[name]=api2go")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filters).To(Equal([]Filter{{Field: "name", Operator: Eq, Values: []string{"api2go"}}}))
+		})
+
+		It("parses operators", func() {
+			filters, err := parse("filter[age][gte]=18&filter[name][like]=api%2C2go&filter[age][ne]=21")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filters).To(Equal([]Filter{
+				{Field: "age", Operator: Gte, Values: []string{"18"}},
+				{Field: "age", Operator: Ne, Values: []string{"21"}},
+				{Field: "name", Operator: Like, Values: []string{"api,2go"}},
+			}))
+		})
+
+		It("splits the values of in", func() {
+			filters, err := parse("filter[id][in]=1,2,3")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filters).To(Equal([]Filter{{Field: "id", Operator: In, Values: []string{"1", "2", "3"}}}))
+		})
+
+		It("ignores other query params and the search filter", func() {
+			filters, err := parse("filter[q]=term&sort=name&page[size]=1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filters).To(BeEmpty())
+		})
+
+		It("rejects unknown operators", func() {
+			_, err := parse("filter[age][between]=1")
+			Expect(err).To(MatchError(`unknown filter operator "between" in filter[age][between]`))
+		})
+	})
+
+	Context("ParseFilterTree", func() {
+		parse := func(rawQuery string) (Filter, error) {
+			query, err := url.ParseQuery(rawQuery)
+			Expect(err).ToNot(HaveOccurred())
+			return ParseFilterTree(query)
+		}
+
+		It("returns an empty and filter without filters", func() {
+			filter, err := parse("sort=name")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filter).To(Equal(Filter{Op: FilterAnd, Children: []Filter{}}))
+		})
+
+		It("combines repeated parameters with or", func() {
+			filter, err := parse("filter[status]=active&filter[status]=pending&filter[age][gte]=18")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filter).To(Equal(Filter{Op: FilterAnd, Children: []Filter{
+				{Field: "age", Operator: Gte, Values: []string{"18"}},
+				{Op: FilterOr, Children: []Filter{
+					{Field: "status", Operator: Eq, Values: []string{"active"}},
+					{Field: "status", Operator: Eq, Values: []string{"pending"}},
+				}},
+			}}))
+		})
+
+		It("keeps different operators on the same field apart", func() {
+			filter, err := parse("filter[age][gte]=18&filter[age][lt]=65")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filter.Children).To(Equal([]Filter{
+				{Field: "age", Operator: Gte, Values: []string{"18"}},
+				{Field: "age", Operator: Lt, Values: []string{"65"}},
+			}))
+		})
+
+		It("rejects unknown operators", func() {
+			_, err := parse("filter[age][between]=1")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Filterable", func() {
+		var (
+			api    *API
+			source *filterableSource
+			rec    *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			source = &filterableSource{fixtureSource: &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "active"},
+				"2": {ID: "2", Title: "pending"},
+				"3": {ID: "3", Title: "closed"},
+			}, false}}
+			api = NewAPI("v1")
+			api.AddResource(Post{}, source)
+			rec = httptest.NewRecorder()
+		})
+
+		doRequest := func(URL string) {
+			req, err := http.NewRequest("GET", URL, nil)
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+		}
+
+		It("passes the filter tree to FilterAll", func() {
+			doRequest("/v1/posts?filter[title]=active&filter[title]=pending")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(source.filter.Children).To(HaveLen(1))
+			Expect(source.filter.Children[0].Op).To(Equal(FilterOr))
+			Expect(rec.Body.String()).To(ContainSubstring(`"id":"1"`))
+			Expect(rec.Body.String()).To(ContainSubstring(`"id":"2"`))
+			Expect(rec.Body.String()).ToNot(ContainSubstring(`"id":"3"`))
+		})
+
+		It("calls FindAll without filters", func() {
+			doRequest("/v1/posts")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(source.filter.Op).To(BeEmpty())
+			Expect(rec.Body.String()).To(ContainSubstring(`"id":"3"`))
+		})
+	})
+
+	Context("FindAll", func() {
+		var (
+			api    *API
+			source *fixtureSource
+			rec    *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			source = &fixtureSource{map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}, false}
+			api = NewAPI("v1")
+			api.AddResource(Post{}, source)
+			rec = httptest.NewRecorder()
+		})
+
+		It("answers with 400 for unknown operators", func() {
+			req, err := http.NewRequest("GET", "/v1/posts?filter[title][matches]=Hello", nil)
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"400","title":"unknown filter operator \"matches\" in filter[title][matches]"}]}`))
+		})
+
+		It("passes the parsed filters to the request", func() {
+			req, err := http.NewRequest("GET", "/v1/posts?filter[title][like]=Hello", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(BuildRequest(req.Context(), req).Filters).To(Equal([]Filter{{Field: "title", Operator: Like, Values: []string{"Hello"}}}))
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+	})
+})
@@ -0,0 +1,72 @@
+package api2go
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// GeoFilter is the parsed `filter[geo][within]=lat,lng,radius` or
+// `filter[geo][near]=lat,lng[,radius]` query parameter
+type GeoFilter struct {
+	Lat          float64
+	Lng          float64
+	RadiusMeters float64
+	// Near is true for `filter[geo][near]`, the results should be ordered by distance
+	// and are not limited to a radius if RadiusMeters is zero
+	Near bool
+}
+
+var geoFilterKeys = map[string]bool{
+	"filter[geo][within]": true,
+	"filter[geo][near]":   true,
+}
+
+// ParseGeoFilter parses the `filter[geo][within]` and `filter[geo][near]` query parameters.
+// It returns nil if there is none of them, and an error if both are given or the
+// coordinates are invalid.
+func ParseGeoFilter(query url.Values) (*GeoFilter, error) {
+	within, near := query.Get("filter[geo][within]"), query.Get("filter[geo][near]")
+	if within == "" && near == "" {
+		return nil, nil
+	}
+
+	if within != "" && near != "" {
+		return nil, fmt.Errorf("filter[geo][within] and filter[geo][near] can not be combined")
+	}
+
+	key, value := "filter[geo][within]", within
+	if near != "" {
+		key, value = "filter[geo][near]", near
+	}
+
+	parts := strings.Split(value, ",")
+	if len(parts) != 3 && !(near != "" && len(parts) == 2) {
+		return nil, fmt.Errorf("%s must be given as lat,lng,radius", key)
+	}
+
+	numbers := make([]float64, len(parts))
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s contains the invalid number %q", key, part)
+		}
+		numbers[i] = number
+	}
+
+	filter := &GeoFilter{Lat: numbers[0], Lng: numbers[1], Near: near != ""}
+	if len(numbers) == 3 {
+		filter.RadiusMeters = numbers[2]
+	}
+
+	if filter.Lat < -90 || filter.Lat > 90 || filter.Lng < -180 || filter.Lng > 180 {
+		return nil, fmt.Errorf("%s contains invalid coordinates", key)
+	}
+
+	if filter.RadiusMeters < 0 {
+		return nil, fmt.Errorf("%s must not have a negative radius", key)
+	}
+
+	return filter, nil
+}
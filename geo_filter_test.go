@@ -0,0 +1,119 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type geoSource struct {
+	*fixtureSource
+	filter *GeoFilter
+}
+
+func (s *geoSource) FindAllWithGeo(req Request) (Responder, error) {
+	s.filter = req.GeoFilter
+	return &Response{Res: []Post{*s.posts["1"]}}, nil
+}
+
+var _ = Describe("Geo filters", func() {
+	Context("ParseGeoFilter", func() {
+		parse := func(rawQuery string) (*GeoFilter, error) {
+			query, err := url.ParseQuery(rawQuery)
+			Expect(err).ToNot(HaveOccurred())
+			return ParseGeoFilter(query)
+		}
+
+		It("returns nil without geo filter", func() {
+			filter, err := parse("filter[title]=Hello")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filter).To(BeNil())
+		})
+
+		It("parses within", func() {
+			filter, err := parse("filter[geo][within]=52.52,13.405,1500")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filter).To(Equal(&GeoFilter{Lat: 52.52, Lng: 13.405, RadiusMeters: 1500}))
+		})
+
+		It("parses near with and without radius", func() {
+			filter, err := parse("filter[geo][near]=52.52,13.405")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filter).To(Equal(&GeoFilter{Lat: 52.52, Lng: 13.405, Near: true}))
+
+			filter, err = parse("filter[geo][near]=-33.86,151.2,200")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filter).To(Equal(&GeoFilter{Lat: -33.86, Lng: 151.2, RadiusMeters: 200, Near: true}))
+		})
+
+		It("rejects invalid filters", func() {
+			for _, query := range []string{
+				"filter[geo][within]=52.52,13.405",
+				"filter[geo][within]=52.52,east,100",
+				"filter[geo][within]=91,13.405,100",
+				"filter[geo][within]=52.52,181,100",
+				"filter[geo][within]=52.52,13.405,-1",
+				"filter[geo][within]=52.52,13.405,1&filter[geo][near]=52.52,13.405",
+			} {
+				_, err := parse(query)
+				Expect(err).To(HaveOccurred(), query)
+			}
+		})
+
+		It("is not part of the other filters", func() {
+			query, err := url.ParseQuery("filter[geo][within]=52.52,13.405,1500&filter[title]=Hello")
+			Expect(err).ToNot(HaveOccurred())
+			filters, err := ParseFilters(query)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filters).To(Equal([]Filter{{Field: "title", Operator: Eq, Values: []string{"Hello"}}}))
+		})
+	})
+
+	Context("GeoFilterable", func() {
+		var (
+			api    *API
+			source *geoSource
+			rec    *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			source = &geoSource{fixtureSource: &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Nearby"},
+				"2": {ID: "2", Title: "Far away"},
+			}, false}}
+			api = NewAPI("v1")
+			api.AddResource(Post{}, source)
+			rec = httptest.NewRecorder()
+		})
+
+		doRequest := func(URL string) {
+			req, err := http.NewRequest("GET", URL, nil)
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+		}
+
+		It("calls FindAllWithGeo with the geo filter", func() {
+			doRequest("/v1/posts?filter[geo][within]=52.52,13.405,1500")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(source.filter).To(Equal(&GeoFilter{Lat: 52.52, Lng: 13.405, RadiusMeters: 1500}))
+			Expect(rec.Body.String()).To(ContainSubstring("Nearby"))
+			Expect(rec.Body.String()).ToNot(ContainSubstring("Far away"))
+		})
+
+		It("calls FindAll without geo filter", func() {
+			doRequest("/v1/posts")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(source.filter).To(BeNil())
+			Expect(rec.Body.String()).To(ContainSubstring("Far away"))
+		})
+
+		It("answers with 400 for invalid geo filters", func() {
+			doRequest("/v1/posts?filter[geo][within]=52.52,13.405")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"400","title":"filter[geo][within] must be given as lat,lng,radius"}]}`))
+		})
+	})
+})
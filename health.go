@@ -0,0 +1,137 @@
+package api2go
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HealthCheckTimeout is the maximum duration all health checks can take
+var HealthCheckTimeout = 5 * time.Second
+
+// The HealthChecker interface must be implemented by everything that
+// should be checked by the health endpoint. Sources can implement it to
+// report their own readiness, see WireSourceHealthChecks.
+type HealthChecker interface {
+	Check(ctx context.Context) error
+}
+
+// The HealthCheckNamer interface can be optionally implemented by a HealthChecker
+// to name it in the health response, otherwise its position is used
+type HealthCheckNamer interface {
+	Name() string
+}
+
+// sourceHealthCheck names the HealthChecker of a source after its resource
+type sourceHealthCheck struct {
+	HealthChecker
+	name string
+}
+
+func (c sourceHealthCheck) Name() string {
+	return c.name
+}
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// AddHealthCheck registers a GET handler at `path` that runs all checks concurrently.
+// If all checks pass, it answers with 200 and `{"status": "ok", "checks": {...}}`,
+// otherwise with 503 and the errors of the failing checks.
+// The response is always plain JSON and does not use the content marshalers.
+// The checks of sources added with WireSourceHealthChecks are run as well.
+func (api *API) AddHealthCheck(path string, checks ...HealthChecker) {
+	api.handle("GET", path, func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), HealthCheckTimeout)
+		defer cancel()
+
+		all := append([]HealthChecker{}, checks...)
+		result := runHealthChecks(ctx, append(all, api.sourceHealthChecks...))
+		status := http.StatusOK
+		if result.Status != "ok" {
+			status = http.StatusServiceUnavailable
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeResult(w, data, status, "application/json")
+	})
+}
+
+// WireSourceHealthChecks adds the sources of all registered resources that implement
+// HealthChecker to the checks of the health endpoints. They are reported under the name
+// of their resource, e.g. `posts` or `v2/posts` for versioned resources. It should be
+// called after all resources have been added, calling it again replaces the checks.
+func (api *API) WireSourceHealthChecks() {
+	api.sourceHealthChecks = nil
+	for _, res := range api.resources {
+		if check, ok := res.source.(HealthChecker); ok {
+			api.sourceHealthChecks = append(api.sourceHealthChecks, sourceHealthCheck{HealthChecker: check, name: res.key()})
+		}
+	}
+}
+
+var pingResponse = []byte(`{"data":null,"meta":{"pong":true}}`)
+
+// AddPing registers `GET /<prefix>/ping` as a liveness endpoint for load balancers.
+// It always answers with 200 and a constant plain JSON document without calling any source.
+func (api *API) AddPing() {
+	api.handlePrefixed("GET", "/ping", func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, pingResponse, http.StatusOK, "application/json")
+	})
+}
+
+func runHealthChecks(ctx context.Context, checks []HealthChecker) healthResponse {
+	result := healthResponse{Status: "ok", Checks: map[string]string{}}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+
+	for i, check := range checks {
+		name := strconv.Itoa(i)
+		if namer, ok := check.(HealthCheckNamer); ok {
+			name = namer.Name()
+		}
+
+		wg.Add(1)
+		go func(name string, check HealthChecker) {
+			defer wg.Done()
+
+			done := make(chan error, 1)
+			go func() {
+				done <- check.Check(ctx)
+			}()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result.Status = "error"
+				result.Checks[name] = err.Error()
+			} else {
+				result.Checks[name] = "ok"
+			}
+		}(name, check)
+	}
+
+	wg.Wait()
+
+	return result
+}
@@ -0,0 +1,160 @@
+package api2go
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type namedCheck struct {
+	name string
+	err  error
+}
+
+func (c namedCheck) Name() string {
+	return c.name
+}
+
+func (c namedCheck) Check(ctx context.Context) error {
+	return c.err
+}
+
+// healthySource reports its readiness with the given error
+type healthySource struct {
+	*userSource
+	err error
+}
+
+func (s healthySource) Check(ctx context.Context) error {
+	return s.err
+}
+
+type blockingCheck struct{}
+
+func (c blockingCheck) Check(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+var _ = Describe("Health checks", func() {
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func() {
+		req, err := http.NewRequest("GET", "/health", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("answers with ok if all checks pass", func() {
+		api.AddHealthCheck("/health", namedCheck{name: "db"}, namedCheck{name: "cache"})
+		doRequest()
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(rec.Body.String()).To(MatchJSON(`{"status": "ok", "checks": {"db": "ok", "cache": "ok"}}`))
+	})
+
+	It("answers with 503 if a check fails", func() {
+		api.AddHealthCheck("/health", namedCheck{name: "db", err: errors.New("connection refused")}, namedCheck{name: "cache"})
+		doRequest()
+		Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(rec.Body.String()).To(MatchJSON(`{"status": "error", "checks": {"db": "connection refused", "cache": "ok"}}`))
+	})
+
+	It("fails checks that exceed the timeout", func() {
+		timeout := HealthCheckTimeout
+		HealthCheckTimeout = 10 * time.Millisecond
+		defer func() {
+			HealthCheckTimeout = timeout
+		}()
+
+		api.AddHealthCheck("/health", blockingCheck{})
+		doRequest()
+		Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(rec.Body.String()).To(MatchJSON(`{"status": "error", "checks": {"0": "context deadline exceeded"}}`))
+	})
+
+	Context("sources", func() {
+		BeforeEach(func() {
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false})
+			api.AddResource(User{}, healthySource{userSource: &userSource{}})
+			api.AddVersionedResource("v2", User{}, healthySource{userSource: &userSource{}, err: errors.New("not ready")})
+		})
+
+		It("reports the checks of sources under their resource name", func() {
+			api.AddHealthCheck("/health", namedCheck{name: "db"})
+			api.WireSourceHealthChecks()
+			doRequest()
+			Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(rec.Body.String()).To(MatchJSON(`{"status": "error", "checks": {"db": "ok", "users": "ok", "v2/users": "not ready"}}`))
+
+			description, ok := api.Describe("users")
+			Expect(ok).To(BeTrue())
+			Expect(description.Interfaces).To(ContainElement("HealthChecker"))
+		})
+
+		It("does not report sources before they are wired", func() {
+			api.AddHealthCheck("/health")
+			doRequest()
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(MatchJSON(`{"status": "ok", "checks": {}}`))
+		})
+
+		It("replaces the checks when wired again", func() {
+			api.WireSourceHealthChecks()
+			api.WireSourceHealthChecks()
+			Expect(api.sourceHealthChecks).To(HaveLen(2))
+		})
+	})
+
+	Context("ping", func() {
+		It("answers with pong", func() {
+			api.AddPing()
+			req, err := http.NewRequest("GET", "/v1/ping", nil)
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Accept", "application/vnd.api+json")
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+			Expect(rec.Body.String()).To(MatchJSON(`{"data": null, "meta": {"pong": true}}`))
+		})
+
+		It("works without prefix", func() {
+			api = NewAPI("")
+			api.AddPing()
+			req, err := http.NewRequest("GET", "/ping", nil)
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+	})
+})
+
+func BenchmarkPing(b *testing.B) {
+	api := NewAPI("v1")
+	api.AddPing()
+	handler := api.Handler()
+	req, err := http.NewRequest("GET", "/v1/ping", nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
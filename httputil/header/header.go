@@ -201,6 +201,60 @@ loop:
 	return
 }
 
+// LinkSpec describes a link of a Link header.
+type LinkSpec struct {
+	URL    string
+	Params map[string]string
+}
+
+// ParseLink parses Link headers as defined in RFC 8288, e.g.
+// `<https://example.com/a,b>; rel="next"`. Commas inside of the url and of
+// quoted parameter values do not separate links. Parameter names are lower
+// cased. It returns false if a header is malformed.
+func ParseLink(header http.Header, key string) (links []LinkSpec, ok bool) {
+	for _, s := range header[key] {
+		for {
+			s = skipSpace(s)
+			if !strings.HasPrefix(s, "<") {
+				return nil, false
+			}
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				return nil, false
+			}
+			link := LinkSpec{URL: s[1:end], Params: make(map[string]string)}
+			s = skipSpace(s[end+1:])
+			for strings.HasPrefix(s, ";") {
+				var pkey string
+				pkey, s = expectToken(skipSpace(s[1:]))
+				if pkey == "" {
+					return nil, false
+				}
+				s = skipSpace(s)
+				if !strings.HasPrefix(s, "=") {
+					return nil, false
+				}
+				var pvalue string
+				pvalue, s = expectTokenOrQuoted(skipSpace(s[1:]))
+				if pvalue == "" {
+					return nil, false
+				}
+				link.Params[strings.ToLower(pkey)] = pvalue
+				s = skipSpace(s)
+			}
+			links = append(links, link)
+			if s == "" {
+				break
+			}
+			if !strings.HasPrefix(s, ",") {
+				return nil, false
+			}
+			s = s[1:]
+		}
+	}
+	return links, true
+}
+
 func skipSpace(s string) (rest string) {
 	i := 0
 	for ; i < len(s); i++ {
@@ -136,3 +136,33 @@ func TestParseAccept(t *testing.T) {
 		}
 	}
 }
+
+var parseLinkTests = []struct {
+	s        string
+	expected []LinkSpec
+	ok       bool
+}{
+	{`</a>; rel="next"`, []LinkSpec{{"/a", map[string]string{"rel": "next"}}}, true},
+	{`</a,b>; rel=next, <http://example.com/c>; REL="prev"; title="x, y"`, []LinkSpec{
+		{"/a,b", map[string]string{"rel": "next"}},
+		{"http://example.com/c", map[string]string{"rel": "prev", "title": "x, y"}},
+	}, true},
+	{` </a> `, []LinkSpec{{"/a", map[string]string{}}}, true},
+	// bad cases
+	{``, nil, false},
+	{`/a; rel="next"`, nil, false},
+	{`</a; rel="next"`, nil, false},
+	{`</a>; rel`, nil, false},
+	{`</a>; rel="next" </b>`, nil, false},
+	{`</a>; rel="next",`, nil, false},
+}
+
+func TestParseLink(t *testing.T) {
+	for _, tt := range parseLinkTests {
+		header := http.Header{"Link": {tt.s}}
+		actual, ok := ParseLink(header, "Link")
+		if ok != tt.ok || !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("ParseLink(h, %q)=%v, %v, want %v, %v", tt.s, actual, ok, tt.expected, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package api2go
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+// IncludeTree is the parsed `include` query parameter. Every key is the name of a
+// relationship, the value contains the relationships that should be included for
+// the related resources, e.g. `include=author.profile,comments` results in
+// {"author": {"profile": {}}, "comments": {}}
+type IncludeTree map[string]IncludeTree
+
+// ParseIncludes parses the comma separated relationship paths of the `include` query parameter
+func ParseIncludes(query url.Values) IncludeTree {
+	return parseRelationshipPaths(query.Get("include"))
+}
+
+// parseRelationshipPaths parses comma separated paths of relationship names into a tree
+func parseRelationshipPaths(paths string) IncludeTree {
+	tree := IncludeTree{}
+	for _, path := range strings.Split(paths, ",") {
+		node := tree
+		for _, name := range strings.Split(strings.TrimSpace(path), ".") {
+			if name == "" {
+				break
+			}
+
+			if node[name] == nil {
+				node[name] = IncludeTree{}
+			}
+			node = node[name]
+		}
+	}
+
+	return tree
+}
+
+// includedResponder adds the objects that have been loaded for the `include`
+// query parameter to a Responder
+type includedResponder struct {
+	Responder
+	included []includedObject
+}
+
+// includedObject is an object that has been loaded for the `include` query parameter,
+// together with the meta data of the Responder of the IncludeProvider
+type includedObject struct {
+	obj  jsonapi.MarshalIdentifier
+	meta map[string]interface{}
+}
+
+// withIncludes loads the related resources requested with the `include` query parameter
+// from the IncludeProvider of their resources. Relationships of resources without an
+// IncludeProvider are skipped.
+func (res *resource) withIncludes(c context.Context, response Responder, req Request) (Responder, error) {
+	api, ok := c.Value(api_api).(*API)
+	if !ok || len(req.Includes) == 0 || response.Result() == nil {
+		return response, nil
+	}
+
+	// the primary data must not be included again
+	seen := map[string]map[string]bool{res.name: {}}
+	objs := identifiers(response.Result())
+	for _, obj := range objs {
+		seen[res.name][obj.GetID()] = true
+	}
+
+	included, err := api.loadIncludes(objs, req.Includes, req, seen)
+	if err != nil || len(included) == 0 {
+		return response, err
+	}
+
+	return includedResponder{Responder: response, included: included}, nil
+}
+
+// loadIncludes loads the relationships in `tree` of all `objs`, and recursively
+// the relationships of the loaded objects. `seen` contains the ids per type that
+// have already been loaded.
+func (api *API) loadIncludes(objs []jsonapi.MarshalIdentifier, tree IncludeTree, req Request, seen map[string]map[string]bool) ([]includedObject, error) {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var included []includedObject
+	for _, name := range names {
+		ids := map[string][]string{}
+		for _, obj := range objs {
+			linked, ok := obj.(jsonapi.MarshalLinkedRelations)
+			if !ok {
+				continue
+			}
+
+			for _, reference := range linked.GetReferencedIDs() {
+				// empty to-one relationships have no id and nothing to include
+				if reference.Name != name || reference.ID == "" || seen[reference.Type][reference.ID] {
+					continue
+				}
+
+				if seen[reference.Type] == nil {
+					seen[reference.Type] = map[string]bool{}
+				}
+				seen[reference.Type][reference.ID] = true
+				ids[reference.Type] = append(ids[reference.Type], reference.ID)
+			}
+		}
+
+		types := make([]string, 0, len(ids))
+		for t := range ids {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+
+		for _, t := range types {
+			related := api.resource(t)
+			if related == nil {
+				continue
+			}
+
+			provider, ok := related.source.(IncludeProvider)
+			if !ok {
+				continue
+			}
+
+			response, err := provider.FindIncluded(ids[t], req)
+			if err != nil {
+				return nil, err
+			}
+			if response == nil || response.Result() == nil {
+				continue
+			}
+
+			loaded := identifiers(related.maskFields(response, req).Result())
+			for _, obj := range loaded {
+				included = append(included, includedObject{obj: obj, meta: response.Metadata()})
+			}
+
+			nested, err := api.loadIncludes(loaded, tree[name], req, seen)
+			if err != nil {
+				return nil, err
+			}
+			included = append(included, nested...)
+		}
+	}
+
+	return included, nil
+}
+
+// resource returns the registered resource with the given name, or nil
+func (api *API) resource(name string) *resource {
+	for _, res := range api.resources {
+		if res.name == name {
+			return res
+		}
+	}
+
+	return nil
+}
+
+// identifiers returns the result of a Responder, which is a struct or a slice, as list
+func identifiers(result interface{}) []jsonapi.MarshalIdentifier {
+	value := reflect.ValueOf(result)
+	if value.Kind() != reflect.Slice {
+		if obj, ok := result.(jsonapi.MarshalIdentifier); ok {
+			return []jsonapi.MarshalIdentifier{obj}
+		}
+
+		return nil
+	}
+
+	objs := make([]jsonapi.MarshalIdentifier, 0, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		if obj, ok := value.Index(i).Interface().(jsonapi.MarshalIdentifier); ok {
+			objs = append(objs, obj)
+		}
+	}
+
+	return objs
+}
+
+// mergeIncluded adds the included objects of an includedResponder to the marshaled
+// document, with the meta data of the Responder they have been loaded with as `meta`.
+// Objects that are already part of `data` or `included` are skipped.
+func mergeIncluded(document map[string]interface{}, obj Responder, info Information) error {
+	includes, ok := obj.(includedResponder)
+	if !ok {
+		return nil
+	}
+
+	key := func(entry map[string]interface{}) string {
+		return fmt.Sprintf("%v/%v", entry["type"], entry["id"])
+	}
+
+	seen := map[string]bool{}
+	switch data := document["data"].(type) {
+	case map[string]interface{}:
+		seen[key(data)] = true
+	case []map[string]interface{}:
+		for _, entry := range data {
+			seen[key(entry)] = true
+		}
+	}
+
+	included, _ := document["included"].([]map[string]interface{})
+	for _, entry := range included {
+		seen[key(entry)] = true
+	}
+
+	for _, object := range includes.included {
+		marshaled, err := jsonapi.MarshalWithURLs(object.obj, info)
+		if err != nil {
+			return err
+		}
+
+		entry, ok := marshaled["data"].(map[string]interface{})
+		if !ok || seen[key(entry)] {
+			continue
+		}
+
+		if len(object.meta) > 0 {
+			entry["meta"] = object.meta
+		}
+
+		seen[key(entry)] = true
+		included = append(included, entry)
+	}
+
+	if len(included) > 0 {
+		document["included"] = included
+	}
+
+	return nil
+}
@@ -0,0 +1,260 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Book struct {
+	ID        string `jsonapi:"-"`
+	Title     string
+	AuthorID  string   `jsonapi:"-"`
+	ReviewIDs []string `jsonapi:"-"`
+}
+
+func (b Book) GetID() string {
+	return b.ID
+}
+
+func (b Book) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{
+		{Type: "writers", Name: "author"},
+		{Type: "reviews", Name: "reviews"},
+	}
+}
+
+func (b Book) GetReferencedIDs() []jsonapi.ReferenceID {
+	result := []jsonapi.ReferenceID{{ID: b.AuthorID, Type: "writers", Name: "author"}}
+	for _, id := range b.ReviewIDs {
+		result = append(result, jsonapi.ReferenceID{ID: id, Type: "reviews", Name: "reviews"})
+	}
+
+	return result
+}
+
+type Writer struct {
+	ID      string `jsonapi:"-"`
+	Name    string
+	AgentID string `jsonapi:"-"`
+}
+
+func (w Writer) GetID() string {
+	return w.ID
+}
+
+func (w Writer) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{{Type: "agents", Name: "agent"}}
+}
+
+func (w Writer) GetReferencedIDs() []jsonapi.ReferenceID {
+	return []jsonapi.ReferenceID{{ID: w.AgentID, Type: "agents", Name: "agent"}}
+}
+
+type Agent struct {
+	ID   string `jsonapi:"-"`
+	Name string
+}
+
+func (a Agent) GetID() string {
+	return a.ID
+}
+
+type Review struct {
+	ID   string `jsonapi:"-"`
+	Text string
+}
+
+func (r Review) GetID() string {
+	return r.ID
+}
+
+// plainIncludeSource serves a fixed list of objects
+type plainIncludeSource struct {
+	objs []jsonapi.MarshalIdentifier
+}
+
+func (s *plainIncludeSource) FindAll(req Request) (Responder, error) {
+	return &Response{Res: s.objs}, nil
+}
+
+func (s *plainIncludeSource) FindOne(id string, req Request) (Responder, error) {
+	for _, obj := range s.objs {
+		if obj.GetID() == id {
+			return &Response{Res: obj}, nil
+		}
+	}
+
+	return nil, NewHTTPError(nil, "not found", http.StatusNotFound)
+}
+
+func (s *plainIncludeSource) Create(obj interface{}, req Request) (Responder, error) {
+	return &Response{Code: http.StatusCreated, Res: obj}, nil
+}
+
+func (s *plainIncludeSource) Delete(id string, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *plainIncludeSource) Update(obj interface{}, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+// includeSource additionally implements IncludeProvider and records the requested ids
+type includeSource struct {
+	plainIncludeSource
+	requested [][]string
+	meta      map[string]interface{}
+}
+
+func (s *includeSource) FindIncluded(ids []string, req Request) (Responder, error) {
+	s.requested = append(s.requested, ids)
+
+	result := []jsonapi.MarshalIdentifier{}
+	for _, id := range ids {
+		for _, obj := range s.objs {
+			if obj.GetID() == id {
+				result = append(result, obj)
+			}
+		}
+	}
+
+	return &Response{Res: result, Meta: s.meta}, nil
+}
+
+var _ = Describe("Includes", func() {
+	Context("ParseIncludes", func() {
+		parse := func(rawQuery string) IncludeTree {
+			query, err := url.ParseQuery(rawQuery)
+			Expect(err).ToNot(HaveOccurred())
+			return ParseIncludes(query)
+		}
+
+		It("returns an empty tree without include", func() {
+			Expect(parse("sort=title")).To(BeEmpty())
+		})
+
+		It("parses relationship paths into a tree", func() {
+			Expect(parse("include=author.agent,reviews,author.books")).To(Equal(IncludeTree{
+				"author": IncludeTree{
+					"agent": IncludeTree{},
+					"books": IncludeTree{},
+				},
+				"reviews": IncludeTree{},
+			}))
+		})
+
+		It("ignores empty paths", func() {
+			Expect(parse("include=,author,")).To(Equal(IncludeTree{"author": IncludeTree{}}))
+		})
+	})
+
+	Context("IncludeProvider", func() {
+		var (
+			api     *API
+			writers *includeSource
+			agents  *includeSource
+			rec     *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			api = NewAPI("v1")
+			api.AddResource(Book{}, &plainIncludeSource{objs: []jsonapi.MarshalIdentifier{
+				Book{ID: "1", Title: "First", AuthorID: "1", ReviewIDs: []string{"1"}},
+				Book{ID: "2", Title: "Second", AuthorID: "1"},
+				Book{ID: "3", Title: "Third", AuthorID: "2"},
+				Book{ID: "4", Title: "Anonymous"},
+			}})
+			writers = &includeSource{plainIncludeSource: plainIncludeSource{objs: []jsonapi.MarshalIdentifier{
+				Writer{ID: "1", Name: "Ada", AgentID: "1"},
+				Writer{ID: "2", Name: "Grace", AgentID: "1"},
+			}}}
+			api.AddResource(Writer{}, writers)
+			agents = &includeSource{plainIncludeSource: plainIncludeSource{objs: []jsonapi.MarshalIdentifier{
+				Agent{ID: "1", Name: "Bob"},
+			}}}
+			api.AddResource(Agent{}, agents)
+			api.AddResource(Review{}, &plainIncludeSource{objs: []jsonapi.MarshalIdentifier{
+				Review{ID: "1", Text: "Great"},
+			}})
+			rec = httptest.NewRecorder()
+		})
+
+		included := func(path string) []map[string]interface{} {
+			req, err := http.NewRequest("GET", path, nil)
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+
+			var document struct {
+				Included []map[string]interface{} `json:"included"`
+			}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+			return document.Included
+		}
+
+		It("does not include anything without the include parameter", func() {
+			Expect(included("/v1/books")).To(BeEmpty())
+			Expect(writers.requested).To(BeEmpty())
+		})
+
+		It("includes the related resources of a single resource", func() {
+			result := included("/v1/books/3?include=author")
+			Expect(result).To(HaveLen(1))
+			Expect(result[0]["type"]).To(Equal("writers"))
+			Expect(result[0]["id"]).To(Equal("2"))
+			Expect(result[0]["attributes"]).To(Equal(map[string]interface{}{"name": "Grace"}))
+		})
+
+		It("loads every related resource only once", func() {
+			result := included("/v1/books?include=author")
+			Expect(result).To(HaveLen(2))
+			Expect(writers.requested).To(Equal([][]string{{"1", "2"}}))
+		})
+
+		It("includes nested relationships", func() {
+			result := included("/v1/books?include=author.agent")
+			Expect(result).To(HaveLen(3))
+			Expect(result[2]["type"]).To(Equal("agents"))
+			Expect(result[2]["id"]).To(Equal("1"))
+			Expect(agents.requested).To(Equal([][]string{{"1"}}))
+		})
+
+		It("does not load empty to-one relationships", func() {
+			Expect(included("/v1/books/4?include=author")).To(BeEmpty())
+			Expect(writers.requested).To(BeEmpty())
+
+			var document struct {
+				Data struct {
+					Relationships map[string]map[string]interface{} `json:"relationships"`
+				} `json:"data"`
+			}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+			Expect(document.Data.Relationships["author"]).To(HaveKeyWithValue("data", BeNil()))
+		})
+
+		It("skips resources without IncludeProvider", func() {
+			result := included("/v1/books/1?include=reviews,author")
+			Expect(result).To(HaveLen(1))
+			Expect(result[0]["type"]).To(Equal("writers"))
+		})
+
+		It("adds the meta data of the IncludeProvider to the included objects", func() {
+			writers.meta = map[string]interface{}{"source": "cache"}
+			result := included("/v1/books?include=author.agent")
+			Expect(result).To(HaveLen(3))
+			for _, entry := range result {
+				if entry["type"] == "writers" {
+					Expect(entry["meta"]).To(Equal(map[string]interface{}{"source": "cache"}))
+				} else {
+					Expect(entry).ToNot(HaveKey("meta"))
+				}
+			}
+		})
+	})
+})
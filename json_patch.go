@@ -0,0 +1,102 @@
+package api2go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// jsonPatchContentType is the media type of JSON Patch documents (RFC 6902)
+const jsonPatchContentType = "application/json-patch+json"
+
+// JSONPatchOperation is a single operation of a JSON Patch document (RFC 6902), e.g.
+// `{"op": "replace", "path": "/title", "value": "Hello"}`. `From` is only set for
+// move and copy, `Value` only for add, replace and test. A `null` value is passed as nil,
+// operations that require a value but do not have one are rejected.
+type JSONPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// jsonPatchOperations are the valid values of JSONPatchOperation.Op
+var jsonPatchOperations = map[string]bool{
+	"add":     true,
+	"remove":  true,
+	"replace": true,
+	"move":    true,
+	"copy":    true,
+	"test":    true,
+}
+
+// The JSONPatchUpdater interface can be optionally implemented by a source to apply
+// `PATCH /:id` requests with `Content-Type: application/json-patch+json`. The operations
+// are validated, but applying them, e.g. in a single database update, is up to the source.
+// The returned status code is handled as for Update. Other PATCH requests still call Update.
+type JSONPatchUpdater interface {
+	ApplyPatch(id string, patch []JSONPatchOperation, req Request) (Responder, error)
+}
+
+// isJSONPatch returns true if the request body is a JSON Patch document
+func isJSONPatch(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == jsonPatchContentType
+}
+
+func (res *resource) handleJSONPatch(c context.Context, w http.ResponseWriter, r *http.Request, id string, updater JSONPatchUpdater) error {
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+
+	var patch []JSONPatchOperation
+	if err := decodeJSONWithNumbers(body, &patch); err != nil {
+		return NewHTTPError(err, "The request body must be a JSON Patch document", http.StatusBadRequest)
+	}
+
+	// a missing value and `null` can not be told apart in JSONPatchOperation
+	var values []struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(body, &values); err != nil {
+		return NewHTTPError(err, "The request body must be a JSON Patch document", http.StatusBadRequest)
+	}
+
+	for i, operation := range patch {
+		if err := validateJSONPatchOperation(operation, values[i].Value != nil); err != nil {
+			return newDocumentError(err, err.Error(), http.StatusBadRequest, "/"+strconv.Itoa(i))
+		}
+	}
+
+	req := BuildRequest(c, r)
+	response, err := updater.ApplyPatch(id, patch, req)
+	if err != nil {
+		return err
+	}
+
+	if err := res.publish(c, EventUpdated, id, response.Result(), req); err != nil {
+		return err
+	}
+
+	return res.respondUpdated(c, w, r, id, response)
+}
+
+// validateJSONPatchOperation checks that an operation has a known op and the members it requires
+func validateJSONPatchOperation(operation JSONPatchOperation, hasValue bool) error {
+	switch {
+	case !jsonPatchOperations[operation.Op]:
+		return fmt.Errorf("unknown JSON Patch operation \"%s\"", operation.Op)
+	case operation.Path == "" || operation.Path[0] != '/':
+		return fmt.Errorf("JSON Patch operation %s requires a path starting with /", operation.Op)
+	case (operation.Op == "move" || operation.Op == "copy") && operation.From == "":
+		return fmt.Errorf("JSON Patch operation %s requires from", operation.Op)
+	case (operation.Op == "add" || operation.Op == "replace" || operation.Op == "test") && !hasValue:
+		return fmt.Errorf("JSON Patch operation %s requires value", operation.Op)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// jsonPatchSource applies replace operations of /title to the posts
+type jsonPatchSource struct {
+	*fixtureSource
+	patches [][]JSONPatchOperation
+}
+
+func (s *jsonPatchSource) ApplyPatch(id string, patch []JSONPatchOperation, req Request) (Responder, error) {
+	s.patches = append(s.patches, patch)
+	post, ok := s.posts[id]
+	if !ok {
+		return nil, NewHTTPError(nil, "post not found", http.StatusNotFound)
+	}
+
+	for _, operation := range patch {
+		if operation.Op == "replace" && operation.Path == "/title" {
+			post.Title = operation.Value.(string)
+		}
+	}
+
+	return &Response{Code: http.StatusOK, Res: *post}, nil
+}
+
+var _ = Describe("JSONPatchUpdater", func() {
+	var (
+		api    *API
+		source *jsonPatchSource
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		source = &jsonPatchSource{fixtureSource: &fixtureSource{map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}, false}}
+		api = newPostAPI(source)
+	})
+
+	doPatch := func(contentType, body string) {
+		req := newTestRequest("PATCH", "/v1/posts/1", body)
+		req.Header.Set("Content-Type", contentType)
+		rec = serve(api, req)
+	}
+
+	It("passes JSON Patch documents to ApplyPatch", func() {
+		doPatch("application/json-patch+json", `[
+			{"op": "test", "path": "/title", "value": "Hello, World!"},
+			{"op": "replace", "path": "/title", "value": "Patched"},
+			{"op": "copy", "from": "/title", "path": "/value"}
+		]`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.patches).To(Equal([][]JSONPatchOperation{{
+			{Op: "test", Path: "/title", Value: "Hello, World!"},
+			{Op: "replace", Path: "/title", Value: "Patched"},
+			{Op: "copy", From: "/title", Path: "/value"},
+		}}))
+		Expect(source.posts["1"].Title).To(Equal("Patched"))
+
+		var document struct {
+			Data struct {
+				Attributes map[string]interface{} `json:"attributes"`
+			} `json:"data"`
+		}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+		Expect(document.Data.Attributes["title"]).To(Equal("Patched"))
+	})
+
+	It("still calls Update for jsonapi documents", func() {
+		doPatch("application/vnd.api+json", `{"data": {"type": "posts", "id": "1", "attributes": {"title": "Updated"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.patches).To(BeEmpty())
+		Expect(source.posts["1"].Title).To(Equal("Updated"))
+	})
+
+	It("rejects invalid documents", func() {
+		doPatch("application/json-patch+json", `{"op": "replace", "path": "/title", "value": "Patched"}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(source.patches).To(BeEmpty())
+	})
+
+	It("points to invalid operations", func() {
+		doPatch("application/json-patch+json", `[{"op": "replace", "path": "/title", "value": "Patched"}, {"op": "move", "path": "/title"}]`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{"status": "400", "title": "JSON Patch operation move requires from", "source": {"pointer": "/1"}}]}`))
+		Expect(source.patches).To(BeEmpty())
+	})
+
+	It("requires a value for add, replace and test", func() {
+		for _, op := range []string{"add", "replace", "test"} {
+			doPatch("application/json-patch+json", `[{"op": "`+op+`", "path": "/title"}]`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest), op)
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{"status": "400", "title": "JSON Patch operation ` + op + ` requires value", "source": {"pointer": "/0"}}]}`))
+		}
+		Expect(source.patches).To(BeEmpty())
+	})
+
+	It("accepts null values", func() {
+		doPatch("application/json-patch+json", `[{"op": "replace", "path": "/value", "value": null}, {"op": "remove", "path": "/title"}]`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.patches).To(Equal([][]JSONPatchOperation{{
+			{Op: "replace", Path: "/value"},
+			{Op: "remove", Path: "/title"},
+		}}))
+	})
+
+	It("rejects JSON Patch documents for other sources", func() {
+		api = newPostAPI(source.fixtureSource)
+		doPatch("application/json-patch+json", `[{"op": "replace", "path": "/title", "value": "Patched"}]`)
+		Expect(rec.Code).To(Equal(http.StatusUnsupportedMediaType))
+		Expect(source.posts["1"].Title).To(Equal("Hello, World!"))
+	})
+})
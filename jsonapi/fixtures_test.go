@@ -200,6 +200,19 @@ func (c Post) GetReferencedIDs() []ReferenceID {
 	return result
 }
 
+// RelationshipMetaPost adds meta to the author relationship of a Post
+type RelationshipMetaPost struct {
+	Post
+}
+
+func (c RelationshipMetaPost) GetRelationshipMeta(name string) map[string]interface{} {
+	if name == "author" {
+		return map[string]interface{}{"since": "2020"}
+	}
+
+	return nil
+}
+
 func (c Post) GetReferencedStructs() []MarshalIdentifier {
 	result := []MarshalIdentifier{}
 	if c.Author != nil {
@@ -244,6 +257,29 @@ func (p AnotherPost) GetReferencedIDs() []ReferenceID {
 	return result
 }
 
+// OptionalAuthorPost always references its author, even if the id is empty
+type OptionalAuthorPost struct {
+	ID       string `jsonapi:"-"`
+	AuthorID string `jsonapi:"-"`
+}
+
+func (p OptionalAuthorPost) GetID() string {
+	return p.ID
+}
+
+func (p OptionalAuthorPost) GetReferences() []Reference {
+	return []Reference{
+		{
+			Type: "users",
+			Name: "author",
+		},
+	}
+}
+
+func (p OptionalAuthorPost) GetReferencedIDs() []ReferenceID {
+	return []ReferenceID{{ID: p.AuthorID, Name: "author", Type: "users"}}
+}
+
 type ZeroPost struct {
 	ID    string `jsonapi:"-"`
 	Title string
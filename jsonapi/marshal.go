@@ -57,6 +57,13 @@ type MarshalIncludedRelations interface {
 	GetReferencedStructs() []MarshalIdentifier
 }
 
+// RelationshipMetaProvider can be implemented to add a `meta` object to relationships.
+// GetRelationshipMeta is called with the name of every relationship, no `meta` is added
+// if it returns an empty map.
+type RelationshipMetaProvider interface {
+	GetRelationshipMeta(name string) map[string]interface{}
+}
+
 // ServerInformation can be passed to MarshalWithURLs to generate the `self` and `related` urls inside `links`
 type ServerInformation interface {
 	GetBaseURL() string
@@ -260,6 +267,9 @@ func getStructRelationships(relationer MarshalLinkedRelations, information Serve
 			}
 
 			relationships[name]["data"] = data
+		} else if referenceIDs[0].ID == "" {
+			// an empty id means that the to-one relationship is not set
+			relationships[name]["data"] = nil
 		} else {
 			relationships[name] = map[string]interface{}{
 				"data": map[string]interface{}{
@@ -297,6 +307,14 @@ func getStructRelationships(relationer MarshalLinkedRelations, information Serve
 		}
 	}
 
+	if metaProvider, ok := relationer.(RelationshipMetaProvider); ok {
+		for name := range relationships {
+			if meta := metaProvider.GetRelationshipMeta(name); len(meta) > 0 {
+				relationships[name]["meta"] = meta
+			}
+		}
+	}
+
 	return relationships
 }
 
@@ -745,6 +745,34 @@ var _ = Describe("Marshalling", func() {
 			}))
 		})
 
+		It("Adds meta to relationships", func() {
+			links := getStructRelationships(RelationshipMetaPost{post}, serverInformationNil)
+			Expect(links["author"]).To(Equal(map[string]interface{}{
+				"data": map[string]interface{}{
+					"id":   "1",
+					"type": "users",
+				},
+				"meta": map[string]interface{}{"since": "2020"},
+			}))
+			Expect(links["comments"]).ToNot(HaveKey("meta"))
+		})
+
+		It("Generates null for empty to-one relationships", func() {
+			post.Author = nil
+			links := getStructRelationships(post, serverInformationNil)
+			Expect(links).To(HaveKey("author"))
+			Expect(links["author"]).To(Equal(map[string]interface{}{
+				"data": nil,
+			}))
+		})
+
+		It("Generates null for to-one relationships with an empty id", func() {
+			links := getStructRelationships(OptionalAuthorPost{ID: "1"}, serverInformationNil)
+			Expect(links["author"]).To(Equal(map[string]interface{}{
+				"data": nil,
+			}))
+		})
+
 		It("Generates to-many relationships correctly", func() {
 			links := getStructRelationships(post, serverInformationNil)
 			Expect(links["comments"]).To(Equal(map[string]interface{}{
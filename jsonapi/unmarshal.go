@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -385,6 +386,8 @@ func setFieldValue(field *reflect.Value, value reflect.Value) (err error) {
 		if err != nil {
 			return err
 		}
+	} else if number, ok := value.Interface().(json.Number); ok {
+		return setNumberValue(field, number)
 	} else {
 		switch field.Type().Kind() {
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -392,7 +395,7 @@ func setFieldValue(field *reflect.Value, value reflect.Value) (err error) {
 		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 			field.SetUint(uint64(value.Float()))
 
-		case reflect.Struct:
+		case reflect.Struct, reflect.Map:
 			jsonObj, err := json.Marshal(value.Interface())
 			if err != nil {
 				return err
@@ -448,6 +451,53 @@ func setFieldValue(field *reflect.Value, value reflect.Value) (err error) {
 	return nil
 }
 
+// setNumberValue sets a json.Number into an integer or float field. Integers are parsed
+// directly, so that they do not lose precision by a conversion to float64. Fields of
+// other types receive the json.Number itself, except interface{} fields, which receive
+// a float64 as with encoding/json.
+func setNumberValue(field *reflect.Value, number json.Number) error {
+	switch field.Type().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, err := strconv.ParseInt(number.String(), 10, 64); err == nil {
+			field.SetInt(i)
+			return nil
+		}
+
+		f, err := number.Float64()
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i, err := strconv.ParseUint(number.String(), 10, 64); err == nil {
+			field.SetUint(i)
+			return nil
+		}
+
+		f, err := number.Float64()
+		if err != nil {
+			return err
+		}
+		field.SetUint(uint64(f))
+	case reflect.Float32, reflect.Float64:
+		f, err := number.Float64()
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Interface:
+		f, err := number.Float64()
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(f))
+	default:
+		field.Set(reflect.ValueOf(number))
+	}
+
+	return nil
+}
+
 // UnmarshalRelationshipsData is used by api2go.API to only unmarshal references inside a data object.
 // The target interface must implement UnmarshalToOneRelations or UnmarshalToManyRelations interface.
 // The linksMap is the content of the data object from the json
@@ -492,7 +542,7 @@ func processRelationshipsData(data interface{}, linkName string, target interfac
 			return errors.New("target struct must implement interface UnmarshalToOneRelations")
 		}
 
-		target.SetToOneReferenceID(linkName, hasOneID)
+		return target.SetToOneReferenceID(linkName, hasOneID)
 	} else if data == nil {
 		// this means that a to-one relationship must be deleted
 		target, ok := target.(UnmarshalToOneRelations)
@@ -500,7 +550,7 @@ func processRelationshipsData(data interface{}, linkName string, target interfac
 			return errors.New("target struct must implement interface UnmarshalToOneRelations")
 		}
 
-		target.SetToOneReferenceID(linkName, "")
+		return target.SetToOneReferenceID(linkName, "")
 	} else {
 		hasMany, ok := data.([]interface{})
 		if !ok {
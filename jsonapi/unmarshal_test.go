@@ -422,6 +422,27 @@ var _ = Describe("Unmarshal", func() {
 			Expect(posts).To(Equal([]Post{post}))
 		})
 
+		It("returns the errors of SetToOneReferenceID", func() {
+			for _, relationships := range []map[string]interface{}{
+				{"editor": map[string]interface{}{"data": map[string]interface{}{"id": "1", "type": "users"}}},
+				{"editor": map[string]interface{}{"data": nil}},
+				{"author": map[string]interface{}{"data": map[string]interface{}{"id": "abc", "type": "users"}}},
+			} {
+				postMap := map[string]interface{}{
+					"data": []interface{}{
+						map[string]interface{}{
+							"id":            "1",
+							"type":          "posts",
+							"relationships": relationships,
+						},
+					},
+				}
+				var posts []Post
+				err := Unmarshal(postMap, &posts)
+				Expect(err).To(HaveOccurred(), fmt.Sprint(relationships))
+			}
+		})
+
 		It("unmarshal no linked content", func() {
 			post := Post{ID: 1, Title: "Test"}
 			postMap := map[string]interface{}{
@@ -592,6 +613,47 @@ var _ = Describe("Unmarshal", func() {
 			Expect(len(numberPosts)).To(Equal(1))
 			Expect(numberPosts[0].UnsignedNumber).To(Equal(uint64(1337)))
 		})
+
+		It("keeps the precision of json.Number values", func() {
+			input := map[string]interface{}{
+				"data": map[string]interface{}{
+					"id":   "test",
+					"type": "numberPosts",
+					"attributes": map[string]interface{}{
+						"number":         json.Number("9007199254740993"),
+						"unsignedNumber": json.Number("18446744073709551615"),
+					},
+				},
+			}
+
+			var numberPost NumberPost
+			err := Unmarshal(input, &numberPost)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(numberPost.Number).To(Equal(int64(9007199254740993)))
+			Expect(numberPost.UnsignedNumber).To(Equal(uint64(18446744073709551615)))
+		})
+
+		It("converts json.Number values to floats and interfaces", func() {
+			type measurement struct {
+				ID    string `jsonapi:"-"`
+				Value float64
+				Raw   interface{}
+			}
+			target := &measurement{}
+			val := reflect.ValueOf(target).Elem()
+
+			field := val.FieldByName("Value")
+			Expect(setFieldValue(&field, reflect.ValueOf(json.Number("13.37")))).To(Succeed())
+			field = val.FieldByName("Raw")
+			Expect(setFieldValue(&field, reflect.ValueOf(json.Number("42")))).To(Succeed())
+			Expect(*target).To(Equal(measurement{Value: 13.37, Raw: float64(42)}))
+		})
+
+		It("rejects json.Number values for string fields", func() {
+			var title string
+			field := reflect.ValueOf(&title).Elem()
+			Expect(setFieldValue(&field, reflect.ValueOf(json.Number("42")))).ToNot(Succeed())
+		})
 	})
 
 	Context("SQL Null-Types", func() {
@@ -0,0 +1,41 @@
+package api2go
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// SetPanicHandler sets the function that is called if a resource handler, e.g. a source or
+// a middleware of the resource, panics. The handler must write the response. Without a
+// panic handler, the stack trace is logged and the request is answered with a 500 error.
+// http.ErrAbortHandler is not recovered, it aborts the response as usual. Panics after
+// the response has been started are logged and abort the response as well, the panic
+// handler is not called for them.
+func (api *API) SetPanicHandler(handler func(recovered interface{}, w http.ResponseWriter, r *http.Request)) {
+	api.panicHandler = handler
+}
+
+// recoverPanic answers a request whose handler panicked with the panic handler of the api.
+// If the response has already been started, a second one would corrupt it, so the panic
+// is logged and the response is aborted with http.ErrAbortHandler instead.
+func (res *resource) recoverPanic(recovered interface{}, w *statsResponseWriter, r *http.Request) {
+	if recovered == http.ErrAbortHandler {
+		panic(recovered)
+	}
+
+	if w.status != 0 {
+		log.Printf("panic serving %s %s after the response started: %v\n%s", r.Method, r.URL.Path, recovered, debug.Stack())
+		panic(http.ErrAbortHandler)
+	}
+
+	if api, ok := r.Context().Value(api_api).(*API); ok && api.panicHandler != nil {
+		api.panicHandler(recovered, w, r)
+		return
+	}
+
+	log.Printf("panic serving %s %s: %v\n%s", r.Method, r.URL.Path, recovered, debug.Stack())
+	err := fmt.Errorf("panic: %v", recovered)
+	HandleError(NewHTTPError(err, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError), w, r, res.marshalers)
+}
@@ -0,0 +1,112 @@
+package api2go
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// panicSource panics with `recovered` when an entry is read
+type panicSource struct {
+	*fixtureSource
+	recovered interface{}
+}
+
+func (s panicSource) FindOne(id string, req Request) (Responder, error) {
+	panic(s.recovered)
+}
+
+var _ = Describe("Panic recovery", func() {
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = newPostAPI(panicSource{&fixtureSource{map[string]*Post{}, false}, "storage is gone"})
+	})
+
+	It("logs the stack trace and answers with 500 by default", func() {
+		var output bytes.Buffer
+		log.SetOutput(&output)
+		defer log.SetOutput(os.Stderr)
+
+		rec = sendRequest(api, "GET", "/v1/posts/1", "")
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"500","title":"Internal Server Error"}]}`))
+		Expect(output.String()).To(ContainSubstring("panic serving GET /v1/posts/1: storage is gone"))
+		Expect(output.String()).To(ContainSubstring("panicSource.FindOne"))
+		Expect(api.Stats()["posts"].Errors).To(Equal(map[int]int64{http.StatusInternalServerError: 1}))
+	})
+
+	It("passes the panic to the request logger", func() {
+		logger := &recordingRequestLogger{}
+		api.SetRequestLogger(logger)
+		rec = sendRequest(api, "GET", "/v1/posts/1", "")
+		Expect(logger.requests).To(HaveLen(1))
+		Expect(logger.requests[0].status).To(Equal(http.StatusInternalServerError))
+		Expect(logger.requests[0].err).To(MatchError(ContainSubstring("panic: storage is gone")))
+	})
+
+	It("calls the panic handler", func() {
+		var recovered interface{}
+		api.SetPanicHandler(func(value interface{}, w http.ResponseWriter, r *http.Request) {
+			recovered = value
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+
+		rec = sendRequest(api, "GET", "/v1/posts/1", "")
+		Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(recovered).To(Equal("storage is gone"))
+		Expect(api.Clone().panicHandler).ToNot(BeNil())
+	})
+
+	It("recovers panics of resource middlewares", func() {
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false}, WithName("articles"), WithMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("middleware")
+			})
+		}))
+		api.SetPanicHandler(func(value interface{}, w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+
+		rec = sendRequest(api, "GET", "/v1/articles", "")
+		Expect(rec.Code).To(Equal(http.StatusTeapot))
+	})
+
+	It("aborts responses that have already been started", func() {
+		var output bytes.Buffer
+		log.SetOutput(&output)
+		defer log.SetOutput(os.Stderr)
+
+		called := false
+		api.SetPanicHandler(func(value interface{}, w http.ResponseWriter, r *http.Request) {
+			called = true
+		})
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false}, WithName("articles"), WithMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("partial"))
+				panic("middleware")
+			})
+		}))
+
+		rec = httptest.NewRecorder()
+		Expect(func() { api.Handler().ServeHTTP(rec, newTestRequest("GET", "/v1/articles", "")) }).To(PanicWith(http.ErrAbortHandler))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(Equal("partial"))
+		Expect(output.String()).To(ContainSubstring("panic serving GET /v1/articles after the response started: middleware"))
+		Expect(called).To(BeFalse())
+	})
+
+	It("does not recover http.ErrAbortHandler", func() {
+		api = newPostAPI(panicSource{&fixtureSource{map[string]*Post{}, false}, http.ErrAbortHandler})
+		Expect(func() { sendRequest(api, "GET", "/v1/posts/1", "") }).To(PanicWith(http.ErrAbortHandler))
+	})
+})
@@ -0,0 +1,63 @@
+package api2go
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// RelationshipAction is the kind of change a RelationshipHandler is asked to make
+type RelationshipAction string
+
+// The actions passed to RelationshipHandler
+const (
+	// RelationshipReplace replaces the relationship, `PATCH /:id/relationships/:name`
+	RelationshipReplace RelationshipAction = "replace"
+	// RelationshipAdd adds to a to-many relationship, `POST /:id/relationships/:name`
+	RelationshipAdd RelationshipAction = "add"
+	// RelationshipDelete deletes from a to-many relationship, `DELETE /:id/relationships/:name`
+	RelationshipDelete RelationshipAction = "delete"
+)
+
+// The RelationshipHandler interface can be optionally implemented by a source to change
+// relationships itself, e.g. for many-to-many relationships with a join table that has
+// additional attributes. It completely replaces the default handling, which calls FindOne
+// and Update. `data` is the `data` member of the request, a map with `type` and `id` or
+// nil for to-one relationships and a slice of these maps for to-many relationships.
+// The id of the resource is available as `req.PathParams["id"]`.
+// A nil error is answered with 204 No Content.
+type RelationshipHandler interface {
+	HandleRelationship(relName string, action RelationshipAction, data interface{}, req Request) error
+}
+
+// handleCustomRelation passes a relationship change to the RelationshipHandler of the source
+func (res *resource) handleCustomRelation(c context.Context, w http.ResponseWriter, r *http.Request, params func(context.Context, string) string, handler RelationshipHandler, action RelationshipAction) error {
+	inc, err := unmarshalRequest(r, res.marshalers)
+	if err != nil {
+		return err
+	}
+
+	data, ok := inc["data"]
+	if !ok {
+		return errors.New("Invalid object. Need a \"data\" object")
+	}
+
+	if _, ok := data.([]interface{}); !ok && action != RelationshipReplace {
+		return errors.New("Data must be an array with \"id\" and \"type\" field to change to-many relationships")
+	}
+
+	req := BuildRequest(c, r)
+	pathParams := map[string]string{"id": params(c, "id")}
+	for key, value := range req.PathParams {
+		pathParams[key] = value
+	}
+	req.PathParams = pathParams
+
+	relName := c.Value(api_relation).(string)
+	if err := handler.HandleRelationship(relName, action, data, req); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
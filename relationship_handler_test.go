@@ -0,0 +1,87 @@
+package api2go
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type relationshipCall struct {
+	relName string
+	action  RelationshipAction
+	data    interface{}
+	id      string
+}
+
+// relationshipHandlerSource records the relationship changes instead of updating the posts
+type relationshipHandlerSource struct {
+	*fixtureSource
+	calls []relationshipCall
+	err   error
+}
+
+func (s *relationshipHandlerSource) HandleRelationship(relName string, action RelationshipAction, data interface{}, req Request) error {
+	s.calls = append(s.calls, relationshipCall{relName, action, data, req.PathParams["id"]})
+	return s.err
+}
+
+var _ = Describe("RelationshipHandler", func() {
+	var (
+		api    *API
+		source *relationshipHandlerSource
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		source = &relationshipHandlerSource{fixtureSource: &fixtureSource{map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!", Comments: []Comment{{ID: "1"}}},
+		}, false}}
+		api = newPostAPI(source)
+	})
+
+	It("replaces relationships", func() {
+		rec = sendRequest(api, "PATCH", "/v1/posts/1/relationships/author", `{"data": {"type": "users", "id": "2"}}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.calls).To(Equal([]relationshipCall{
+			{"author", RelationshipReplace, map[string]interface{}{"type": "users", "id": "2"}, "1"},
+		}))
+		Expect(source.posts["1"].Author).To(BeNil())
+	})
+
+	It("adds and deletes to-many relationships", func() {
+		rec = sendRequest(api, "POST", "/v1/posts/1/relationships/comments", `{"data": [{"type": "comments", "id": "2"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+
+		rec = sendRequest(api, "DELETE", "/v1/posts/1/relationships/comments", `{"data": [{"type": "comments", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+
+		Expect(source.calls).To(Equal([]relationshipCall{
+			{"comments", RelationshipAdd, []interface{}{map[string]interface{}{"type": "comments", "id": "2"}}, "1"},
+			{"comments", RelationshipDelete, []interface{}{map[string]interface{}{"type": "comments", "id": "1"}}, "1"},
+		}))
+		Expect(source.posts["1"].Comments).To(Equal([]Comment{{ID: "1"}}))
+	})
+
+	It("rejects to-many changes without an array", func() {
+		rec = sendRequest(api, "POST", "/v1/posts/1/relationships/comments", `{"data": {"type": "comments", "id": "2"}}`)
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		Expect(source.calls).To(BeEmpty())
+	})
+
+	It("returns the error of the handler", func() {
+		source.err = NewHTTPError(errors.New("locked"), "locked", http.StatusConflict)
+		rec = sendRequest(api, "PATCH", "/v1/posts/1/relationships/author", `{"data": null}`)
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(source.calls).To(HaveLen(1))
+		Expect(source.calls[0].data).To(BeNil())
+	})
+
+	It("is listed in the description", func() {
+		description, ok := api.Describe("posts")
+		Expect(ok).To(BeTrue())
+		Expect(description.Interfaces).To(ContainElement("RelationshipHandler"))
+	})
+})
@@ -1,8 +1,13 @@
 package api2go
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
 // Request contains additional information for FindOne and Find Requests
@@ -11,4 +16,88 @@ type Request struct {
 	QueryParams  map[string][]string
 	Header       http.Header
 	Context      context.Context
+	// Search contains the value of the `filter[q]` query parameter
+	Search string
+	// Filters contains the parsed `filter[field][op]` query parameters
+	Filters []Filter
+	// FilterTree contains the same filters as Filters, repeated parameters
+	// are combined with FilterOr
+	FilterTree Filter
+	// GeoFilter contains the parsed `filter[geo][within]` or `filter[geo][near]`
+	// query parameter, it is nil if there is none
+	GeoFilter *GeoFilter
+	// Includes contains the parsed `include` query parameter
+	Includes IncludeTree
+	// Embeds contains the parsed `embed` query parameter, if embedding is enabled
+	Embeds IncludeTree
+	// PathParams contains the `parentID` of resources added with AddSubResource,
+	// and the `id` of the resource for a RelationshipHandler
+	PathParams map[string]string
+}
+
+// GetPaginationParams returns the pagination query parameters of the request, the
+// bool is true if they are present and valid. Without PlainRequest, the pagination
+// parameters are read from QueryParams.
+func (r Request) GetPaginationParams() (PaginationQueryParams, bool) {
+	plain := &http.Request{URL: &url.URL{RawQuery: r.query().Encode()}}
+	pagination := NewPaginationQueryParams(plain)
+	valid, err := pagination.IsValidWithError()
+
+	return pagination, valid && err == nil
+}
+
+// query returns the query parameters of PlainRequest, or QueryParams without PlainRequest
+func (r Request) query() url.Values {
+	if r.PlainRequest != nil && r.PlainRequest.URL != nil {
+		return r.PlainRequest.URL.Query()
+	}
+
+	query := url.Values{}
+	for key, values := range r.QueryParams {
+		query.Set(key, strings.Join(values, ","))
+	}
+
+	return query
+}
+
+// Body returns the body of the request. It can be called any number of times, also
+// after the body has been unmarshaled by api2go.
+func (r Request) Body() ([]byte, error) {
+	if r.PlainRequest == nil {
+		return nil, errors.New("request has no plain request")
+	}
+
+	return readBody(r.PlainRequest)
+}
+
+// cachedBody replaces the body of a request after it has been read
+type cachedBody struct {
+	*bytes.Reader
+	data []byte
+}
+
+func (b *cachedBody) Close() error {
+	return nil
+}
+
+// readBody reads and closes the body of `r` and replaces it with a cachedBody,
+// so that it can be read again
+func readBody(r *http.Request) ([]byte, error) {
+	if cached, ok := r.Body.(*cachedBody); ok {
+		return cached.data, nil
+	}
+
+	if r.Body == nil {
+		return nil, nil
+	}
+
+	defer r.Body.Close()
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Body = &cachedBody{Reader: bytes.NewReader(data), data: data}
+
+	return data, nil
 }
@@ -0,0 +1,69 @@
+package api2go
+
+import (
+	"net/http"
+	"time"
+)
+
+// The RequestLogger interface can be implemented to log every request that is
+// handled by the API, e.g. as structured JSON. `err` is the error that was answered
+// with HandleError, or nil if the request succeeded.
+type RequestLogger interface {
+	LogRequest(req *http.Request, status int, duration time.Duration, err error)
+}
+
+// SetRequestLogger sets the logger that is called after each request. Errors are
+// passed to the logger instead of being written with log.Println.
+func (api *API) SetRequestLogger(logger RequestLogger) {
+	api.requestLogger = logger
+}
+
+// loggingResponseWriter records the status and error of a response for the RequestLogger
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	err    error
+}
+
+func (w *loggingResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *loggingResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// findLoggingResponseWriter returns the loggingResponseWriter that is wrapped by `w`,
+// e.g. by resource middlewares, or nil if there is none
+func findLoggingResponseWriter(w http.ResponseWriter) *loggingResponseWriter {
+	for {
+		switch writer := w.(type) {
+		case *loggingResponseWriter:
+			return writer
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = writer.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// serveLogged calls next and passes the result to the RequestLogger
+func (api *API) serveLogged(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	recorder := &loggingResponseWriter{ResponseWriter: w}
+	next.ServeHTTP(recorder, r)
+
+	status := recorder.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	api.requestLogger.LogRequest(r, status, time.Since(start), recorder.err)
+}
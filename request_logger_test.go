@@ -0,0 +1,65 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type loggedRequest struct {
+	method   string
+	path     string
+	status   int
+	duration time.Duration
+	err      error
+}
+
+type recordingRequestLogger struct {
+	requests []loggedRequest
+}
+
+func (l *recordingRequestLogger) LogRequest(req *http.Request, status int, duration time.Duration, err error) {
+	l.requests = append(l.requests, loggedRequest{req.Method, req.URL.Path, status, duration, err})
+}
+
+var _ = Describe("Request logging", func() {
+	var (
+		api    *API
+		logger *recordingRequestLogger
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = newPostAPI(&fixtureSource{map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}, false})
+		logger = &recordingRequestLogger{}
+		api.SetRequestLogger(logger)
+	})
+
+	It("logs successful requests", func() {
+		rec = sendRequest(api, "GET", "/v1/posts/1", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(logger.requests).To(HaveLen(1))
+		Expect(logger.requests[0].method).To(Equal("GET"))
+		Expect(logger.requests[0].path).To(Equal("/v1/posts/1"))
+		Expect(logger.requests[0].status).To(Equal(http.StatusOK))
+		Expect(logger.requests[0].duration).To(BeNumerically(">", 0))
+		Expect(logger.requests[0].err).ToNot(HaveOccurred())
+	})
+
+	It("logs requests without body", func() {
+		rec = sendRequest(api, "DELETE", "/v1/posts/1", "")
+		Expect(logger.requests).To(HaveLen(1))
+		Expect(logger.requests[0].status).To(Equal(http.StatusNoContent))
+	})
+
+	It("logs failed requests with their error", func() {
+		rec = sendRequest(api, "GET", "/v1/posts/42", "")
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(logger.requests).To(HaveLen(1))
+		Expect(logger.requests[0].status).To(Equal(http.StatusNotFound))
+		Expect(logger.requests[0].err).To(HaveOccurred())
+	})
+})
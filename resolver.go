@@ -1,6 +1,9 @@
 package api2go
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
 type callbackResolver struct {
 	callback func(r http.Request) string
@@ -40,3 +43,42 @@ func (s staticResolver) GetBaseURL() string {
 func NewStaticResolver(baseURL string) URLResolver {
 	return &staticResolver{baseURL: baseURL}
 }
+
+type allowedOriginsResolver struct {
+	resolver       URLResolver
+	allowedOrigins []string
+}
+
+// NewAllowedOriginsResolver wraps a resolver and only accepts base urls that
+// start with one of the allowed origins, e.g. `https://example.com`. This prevents
+// links to arbitrary domains if the base url depends on the request, for example
+// on the Host header. If the base url is not allowed, the first allowed origin is used.
+func NewAllowedOriginsResolver(resolver URLResolver, allowedOrigins ...string) URLResolver {
+	if len(allowedOrigins) == 0 {
+		panic("at least one allowed origin is required")
+	}
+
+	return &allowedOriginsResolver{resolver: resolver, allowedOrigins: allowedOrigins}
+}
+
+// GetBaseURL returns the base url of the wrapped resolver if it is allowed
+// to implement `URLResolver`
+func (a allowedOriginsResolver) GetBaseURL() string {
+	baseURL := a.resolver.GetBaseURL()
+	for _, origin := range a.allowedOrigins {
+		origin = strings.TrimSuffix(origin, "/")
+		if baseURL == origin || strings.HasPrefix(baseURL, origin+"/") {
+			return baseURL
+		}
+	}
+
+	return a.allowedOrigins[0]
+}
+
+// SetRequest passes the request to the wrapped resolver if it
+// implements `RequestAwareURLResolver`
+func (a *allowedOriginsResolver) SetRequest(r http.Request) {
+	if resolver, ok := a.resolver.(RequestAwareURLResolver); ok {
+		resolver.SetRequest(r)
+	}
+}
@@ -30,4 +30,39 @@ var _ = Describe("Resolver test", func() {
 			Expect(requestResolver.GetBaseURL()).To(Equal("funny"))
 		})
 	})
+
+	Context("allowed origins resolver", func() {
+		var resolver URLResolver
+
+		BeforeEach(func() {
+			callback := func(r http.Request) string {
+				return "https://" + r.Host
+			}
+			resolver = NewAllowedOriginsResolver(NewCallbackResolver(callback), "https://api.example.com", "https://example.com/")
+		})
+
+		resolve := func(host string) string {
+			req, err := http.NewRequest("GET", "/v1/posts", nil)
+			Expect(err).To(BeNil())
+			req.Host = host
+			resolver.(RequestAwareURLResolver).SetRequest(*req)
+			return resolver.GetBaseURL()
+		}
+
+		It("accepts allowed origins", func() {
+			Expect(resolve("api.example.com")).To(Equal("https://api.example.com"))
+			Expect(resolve("example.com")).To(Equal("https://example.com"))
+		})
+
+		It("uses the first allowed origin otherwise", func() {
+			Expect(resolve("evil.com")).To(Equal("https://api.example.com"))
+			Expect(resolve("api.example.com.evil.com")).To(Equal("https://api.example.com"))
+		})
+
+		It("requires an allowed origin", func() {
+			Expect(func() {
+				NewAllowedOriginsResolver(NewStaticResolver("https://example.com"))
+			}).To(Panic())
+		})
+	})
 })
@@ -0,0 +1,71 @@
+package api2go
+
+import (
+	"net/http"
+	"time"
+)
+
+// ResourceOption configures a resource that is registered with AddResource
+type ResourceOption func(*resource)
+
+// WithName overrides the name of the resource, which is used in its URLs.
+// By default the name of the EntityNamer interface or the pluralized struct name is used.
+func WithName(name string) ResourceOption {
+	return func(res *resource) {
+		res.name = name
+	}
+}
+
+// WithMiddleware adds middlewares that only wrap the handlers of the resource.
+// They are called after the middlewares of the API.
+func WithMiddleware(middleware ...func(http.Handler) http.Handler) ResourceOption {
+	return func(res *resource) {
+		res.middlewares = append(res.middlewares, middleware...)
+	}
+}
+
+// WithCache allows clients to cache GET and HEAD responses of the resource
+// for `ttl` by sending a `Cache-Control: max-age` header.
+func WithCache(ttl time.Duration) ResourceOption {
+	return func(res *resource) {
+		res.cacheTTL = ttl
+	}
+}
+
+// WithTimeout limits the duration of `GET /<resource>` and `GET /<resource>/<id>` requests,
+// e.g. to allow more time for heavy reports than for simple lists. The context of the Request
+// passed to the source expires after `timeout`, errors caused by the expired context are
+// answered with 504 Gateway Timeout.
+func WithTimeout(timeout time.Duration) ResourceOption {
+	return func(res *resource) {
+		res.timeout = timeout
+	}
+}
+
+// WithAuthorizer restricts the access to the resource. All authorizers are
+// called in order before a request is handled.
+func WithAuthorizer(authorizers ...Authorizer) ResourceOption {
+	return func(res *resource) {
+		res.authorizers = append(res.authorizers, authorizers...)
+	}
+}
+
+// WithLinkMethods registers `LINK /:id` and `UNLINK /:id` routes, which add and delete
+// the to-many relationships referenced in the `Link` header of the request, e.g.
+// `Link: </v1/comments/1>; rel="comments"`. The second to last path segment of the
+// link url must be the type of the relationship. The resource must implement
+// jsonapi.EditToManyRelations, otherwise no routes are registered.
+func WithLinkMethods() ResourceOption {
+	return func(res *resource) {
+		res.linkMethods = true
+	}
+}
+
+// WithReadOnly only registers the routes to read the resource and its relationships.
+// Requests with POST, PATCH or DELETE are answered with 405 Method Not Allowed
+// without calling the source.
+func WithReadOnly() ResourceOption {
+	return func(res *resource) {
+		res.readOnly = true
+	}
+}
@@ -0,0 +1,321 @@
+package api2go
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type headerAuthorizer struct {
+	token string
+	err   error
+}
+
+func (a headerAuthorizer) Authorize(r *http.Request) error {
+	if r.Header.Get("Authorization") != a.token {
+		return a.err
+	}
+
+	return nil
+}
+
+// middlewareSource adds a ResourceMiddleware to a fixtureSource
+type middlewareSource struct {
+	*fixtureSource
+	middleware func(http.Handler) http.Handler
+}
+
+func (s middlewareSource) Middleware() func(http.Handler) http.Handler {
+	return s.middleware
+}
+
+// slowSource records the deadline of the request and takes `delay` to answer
+type slowSource struct {
+	*fixtureSource
+	delay    time.Duration
+	deadline time.Time
+}
+
+func (s *slowSource) wait(req Request) error {
+	s.deadline, _ = req.Context.Deadline()
+	select {
+	case <-req.Context.Done():
+		return req.Context.Err()
+	case <-time.After(s.delay):
+		return nil
+	}
+}
+
+func (s *slowSource) FindAll(req Request) (Responder, error) {
+	if err := s.wait(req); err != nil {
+		return nil, err
+	}
+
+	return s.fixtureSource.FindAll(req)
+}
+
+func (s *slowSource) FindOne(id string, req Request) (Responder, error) {
+	if err := s.wait(req); err != nil {
+		return nil, fmt.Errorf("loading post %s: %w", id, err)
+	}
+
+	return s.fixtureSource.FindOne(id, req)
+}
+
+var _ = Describe("Resource options", func() {
+	var (
+		api    *API
+		source *fixtureSource
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		source = &fixtureSource{map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}, false}
+		api = NewAPI("v1")
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(method, URL string, header http.Header) {
+		req, err := http.NewRequest(method, URL, nil)
+		Expect(err).ToNot(HaveOccurred())
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("keeps the default behavior without options", func() {
+		api.AddResource(Post{}, source)
+		doRequest("GET", "/v1/posts/1", nil)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Cache-Control")).To(BeEmpty())
+	})
+
+	It("uses a custom name", func() {
+		api.AddResource(Post{}, source, WithName("articles"))
+		doRequest("GET", "/v1/articles/1", nil)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		rec = httptest.NewRecorder()
+		doRequest("GET", "/v1/posts/1", nil)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("calls resource middlewares in order", func() {
+		called := []string{}
+		middleware := func(name string) func(http.Handler) http.Handler {
+			return func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					called = append(called, name)
+					next.ServeHTTP(w, r)
+				})
+			}
+		}
+
+		api.AddResource(Post{}, source, WithMiddleware(middleware("first"), middleware("second")))
+		doRequest("GET", "/v1/posts/1", nil)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(called).To(Equal([]string{"first", "second"}))
+	})
+
+	It("calls the middleware of a ResourceMiddleware source after the resource middlewares", func() {
+		called := []string{}
+		middleware := func(name string) func(http.Handler) http.Handler {
+			return func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					called = append(called, name)
+					next.ServeHTTP(w, r)
+				})
+			}
+		}
+
+		api.AddResource(Post{}, middlewareSource{source, middleware("source")}, WithMiddleware(middleware("option")))
+		api.AddResource(Post{}, source, WithName("articles"))
+		doRequest("GET", "/v1/posts/1", nil)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(called).To(Equal([]string{"option", "source"}))
+
+		rec = httptest.NewRecorder()
+		doRequest("GET", "/v1/articles/1", nil)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(called).To(Equal([]string{"option", "source"}))
+	})
+
+	It("sets the cache header on reads only", func() {
+		api.AddResource(Post{}, source, WithCache(90*time.Second))
+		doRequest("GET", "/v1/posts/1", nil)
+		Expect(rec.Header().Get("Cache-Control")).To(Equal("max-age=90"))
+
+		rec = httptest.NewRecorder()
+		doRequest("DELETE", "/v1/posts/1", nil)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(rec.Header().Get("Cache-Control")).To(BeEmpty())
+	})
+
+	Context("read-only", func() {
+		BeforeEach(func() {
+			api.AddResource(Post{}, source, WithReadOnly(), WithLinkMethods())
+		})
+
+		It("reads the resource and its relationships", func() {
+			doRequest("GET", "/v1/posts/1", nil)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+
+			rec = httptest.NewRecorder()
+			doRequest("GET", "/v1/posts", nil)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+
+			rec = httptest.NewRecorder()
+			doRequest("GET", "/v1/posts/1/relationships/comments", nil)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+
+		It("answers changes with 405", func() {
+			for _, request := range []struct{ method, URL, allow string }{
+				{"POST", "/v1/posts", "GET,OPTIONS"},
+				{"PATCH", "/v1/posts/1", "GET,HEAD,OPTIONS"},
+				{"DELETE", "/v1/posts/1", "GET,HEAD,OPTIONS"},
+				{"PATCH", "/v1/posts/1/relationships/author", "GET"},
+			} {
+				rec = httptest.NewRecorder()
+				doRequest(request.method, request.URL, nil)
+				Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+				Expect(rec.Header().Get("Allow")).To(Equal(request.allow))
+			}
+
+			Expect(source.posts).To(HaveKey("1"))
+		})
+
+		It("announces and describes the read methods only", func() {
+			doRequest("OPTIONS", "/v1/posts/1", nil)
+			Expect(rec.Header().Get("Allow")).To(Equal("GET,HEAD,OPTIONS"))
+
+			description, ok := api.Describe("posts")
+			Expect(ok).To(BeTrue())
+			Expect(description.Methods).To(Equal([]string{"GET", "HEAD", "OPTIONS"}))
+		})
+	})
+
+	Context("with link methods", func() {
+		BeforeEach(func() {
+			source.posts["1"].Comments = []Comment{{ID: "1"}}
+			api.AddResource(Post{}, source, WithLinkMethods())
+		})
+
+		It("announces the methods", func() {
+			doRequest("OPTIONS", "/v1/posts/1", nil)
+			Expect(rec.Header().Get("Allow")).To(Equal("GET,HEAD,PATCH,DELETE,OPTIONS,LINK,UNLINK"))
+		})
+
+		It("adds to-many relationships with LINK", func() {
+			doRequest("LINK", "/v1/posts/1", http.Header{"Link": {`</v1/comments/2>; rel="comments", </v1/comments/3>; rel="comments"`}})
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(source.posts["1"].Comments).To(Equal([]Comment{{ID: "1"}, {ID: "2"}, {ID: "3"}}))
+		})
+
+		It("deletes to-many relationships with UNLINK", func() {
+			doRequest("UNLINK", "/v1/posts/1", http.Header{"Link": {`<http://example.com/v1/comments/1>; rel=comments`}})
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(source.posts["1"].Comments).To(BeEmpty())
+		})
+
+		It("rejects unknown relationships", func() {
+			doRequest("LINK", "/v1/posts/1", http.Header{"Link": {`</v1/users/2>; rel="author"`}})
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"400","title":"There is no to-many relationship with the name author"}]}`))
+		})
+
+		It("keeps commas inside of the link url", func() {
+			doRequest("LINK", "/v1/posts/1", http.Header{"Link": {`</v1/comments/2,3>; rel="comments"`}})
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(source.posts["1"].Comments).To(Equal([]Comment{{ID: "1"}, {ID: "2,3"}}))
+		})
+
+		It("rejects links to other types", func() {
+			doRequest("LINK", "/v1/posts/1", http.Header{"Link": {`</v1/users/2>; rel="comments"`}})
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"400","title":"Link /v1/users/2 does not reference comments"}]}`))
+			Expect(source.posts["1"].Comments).To(HaveLen(1))
+		})
+
+		It("rejects malformed Link headers", func() {
+			doRequest("LINK", "/v1/posts/1", http.Header{"Link": {`/v1/comments/2; rel="comments"`}})
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(source.posts["1"].Comments).To(HaveLen(1))
+		})
+
+		It("rejects requests without Link header", func() {
+			doRequest("LINK", "/v1/posts/1", nil)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(source.posts["1"].Comments).To(HaveLen(1))
+		})
+	})
+
+	It("does not register link methods by default", func() {
+		api.AddResource(Post{}, source)
+		doRequest("LINK", "/v1/posts/1", http.Header{"Link": {`</v1/comments/2>; rel="comments"`}})
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	Context("with timeout", func() {
+		It("answers with 504 if the timeout expires", func() {
+			api.AddResource(Post{}, &slowSource{fixtureSource: source, delay: time.Hour}, WithTimeout(10*time.Millisecond))
+			doRequest("GET", "/v1/posts", nil)
+			Expect(rec.Code).To(Equal(http.StatusGatewayTimeout))
+
+			rec = httptest.NewRecorder()
+			doRequest("GET", "/v1/posts/1", nil)
+			Expect(rec.Code).To(Equal(http.StatusGatewayTimeout))
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"504","title":"Gateway Timeout"}]}`))
+		})
+
+		It("only applies to the resource", func() {
+			reports := &slowSource{fixtureSource: source}
+			posts := &slowSource{fixtureSource: source}
+			api.AddResource(Post{}, reports, WithName("reports"), WithTimeout(time.Hour))
+			api.AddResource(Post{}, posts)
+
+			doRequest("GET", "/v1/reports/1", nil)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(reports.deadline).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+
+			rec = httptest.NewRecorder()
+			doRequest("GET", "/v1/posts", nil)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(posts.deadline).To(BeZero())
+		})
+
+		It("keeps other errors", func() {
+			api.AddResource(Post{}, source, WithTimeout(time.Hour))
+			doRequest("GET", "/v1/posts/2", nil)
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Context("with authorizers", func() {
+		It("answers with 403 for plain errors", func() {
+			api.AddResource(Post{}, source, WithAuthorizer(headerAuthorizer{token: "secret", err: errors.New("no access")}))
+			doRequest("GET", "/v1/posts/1", nil)
+			Expect(rec.Code).To(Equal(http.StatusForbidden))
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"403","title":"no access"}]}`))
+		})
+
+		It("uses the status of an HTTPError", func() {
+			httpErr := NewHTTPError(nil, "Unauthorized", http.StatusUnauthorized)
+			api.AddResource(Post{}, source, WithAuthorizer(headerAuthorizer{token: "secret", err: httpErr}))
+			doRequest("GET", "/v1/posts/1", nil)
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("handles authorized requests", func() {
+			api.AddResource(Post{}, source, WithAuthorizer(headerAuthorizer{token: "secret", err: errors.New("no access")}))
+			doRequest("GET", "/v1/posts/1", http.Header{"Authorization": {"secret"}})
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+	})
+})
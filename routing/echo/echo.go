@@ -0,0 +1,77 @@
+// Package echo provides a routing.Routeable adapter for the echo framework.
+package echo
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo"
+	"github.com/manyminds/api2go/routing"
+)
+
+type contextKey struct{}
+
+// echoContextKey is used to pass the echo context to api2go handlers
+var echoContextKey = contextKey{}
+
+// EchoRouter wraps an echo instance to be used as router for api2go
+type EchoRouter struct {
+	echo *echo.Echo
+}
+
+// Handler returns the echo instance
+func (e EchoRouter) Handler() http.Handler {
+	return e.echo
+}
+
+// Handle registers the handler to echo
+func (e EchoRouter) Handle(protocol, route string, handler http.HandlerFunc) {
+	e.echo.Add(protocol, route, func(c echo.Context) error {
+		handler(c.Response(), c.Request())
+		return nil
+	})
+}
+
+// Param returns the value of a route param using the echo context
+func (e EchoRouter) Param(ctx context.Context, name string) string {
+	c, ok := ctx.Value(echoContextKey).(echo.Context)
+	if !ok {
+		return ""
+	}
+
+	return c.Param(name)
+}
+
+// SetParam sets the value of a route param in the echo context
+func (e EchoRouter) SetParam(ctx context.Context, name, value string) {
+	c, ok := ctx.Value(echoContextKey).(echo.Context)
+	if !ok {
+		return
+	}
+
+	names := c.ParamNames()
+	values := c.ParamValues()
+	for i, paramName := range names {
+		if paramName == name && i < len(values) {
+			values[i] = value
+			c.SetParamValues(values...)
+			return
+		}
+	}
+}
+
+// New returns a new router for api2go using the given echo instance.
+// Echo manages its own context, so a middleware is registered that
+// passes the echo context along with the values api2go stored in the
+// request context to the api2go handlers.
+func New(e *echo.Echo) routing.Routeable {
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			r := c.Request()
+			c.SetRequest(r.WithContext(context.WithValue(r.Context(), echoContextKey, c)))
+			return next(c)
+		}
+	})
+
+	return &EchoRouter{echo: e}
+}
@@ -0,0 +1,13 @@
+package echo_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestEcho(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Echo Suite")
+}
@@ -0,0 +1,23 @@
+package echo_test
+
+import (
+	"context"
+
+	"github.com/labstack/echo"
+	"github.com/manyminds/api2go/routing"
+	echorouter "github.com/manyminds/api2go/routing/echo"
+	"github.com/manyminds/api2go/routing/routingtest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EchoRouter", func() {
+	routingtest.ItServesResources(func() routing.Routeable {
+		return echorouter.New(echo.New())
+	})
+
+	It("returns empty params without route variables", func() {
+		router := echorouter.New(echo.New())
+		Expect(router.Param(context.Background(), "id")).To(Equal(""))
+	})
+})
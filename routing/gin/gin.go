@@ -0,0 +1,53 @@
+// Package gin provides a routing.Routeable adapter for the gin framework.
+package gin
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/manyminds/api2go/routing"
+)
+
+type contextKey struct{}
+
+// paramsKey is used to pass gin's route params to api2go handlers
+var paramsKey = contextKey{}
+
+// GinRouter wraps a gin engine to be used as router for api2go
+type GinRouter struct {
+	engine *gin.Engine
+}
+
+// Handler returns the gin engine
+func (g GinRouter) Handler() http.Handler {
+	return g.engine
+}
+
+// Handle registers the handler to gin and passes all route params
+// in the request context, so they can be read with Param
+func (g GinRouter) Handle(protocol, route string, handler http.HandlerFunc) {
+	g.engine.Handle(protocol, route, func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), paramsKey, c.Params)
+		handler(c.Writer, c.Request.WithContext(ctx))
+	})
+}
+
+// Param returns the value of a route param
+func (g GinRouter) Param(ctx context.Context, name string) string {
+	params, ok := ctx.Value(paramsKey).(gin.Params)
+	if !ok {
+		return ""
+	}
+
+	return params.ByName(name)
+}
+
+// SetParam is not supported by gin, route params are read only
+func (g GinRouter) SetParam(ctx context.Context, name, value string) {
+}
+
+// New returns a new router for api2go using the given gin engine
+func New(engine *gin.Engine) routing.Routeable {
+	return &GinRouter{engine: engine}
+}
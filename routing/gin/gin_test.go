@@ -0,0 +1,16 @@
+package gin_test
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/manyminds/api2go/routing"
+	ginrouter "github.com/manyminds/api2go/routing/gin"
+	"github.com/manyminds/api2go/routing/routingtest"
+	. "github.com/onsi/ginkgo"
+)
+
+var _ = Describe("GinRouter", func() {
+	routingtest.ItServesResources(func() routing.Routeable {
+		gin.SetMode(gin.ReleaseMode)
+		return ginrouter.New(gin.New())
+	})
+})
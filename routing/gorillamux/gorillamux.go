@@ -0,0 +1,64 @@
+// Package gorillamux provides a routing.Routeable adapter for gorilla/mux.
+package gorillamux
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/gorilla/mux"
+	"github.com/manyminds/api2go/routing"
+)
+
+type contextKey struct{}
+
+// varsKey is used to pass mux's route variables to api2go handlers
+var varsKey = contextKey{}
+
+// routeParamRegex matches api2go's `:id` style route params
+var routeParamRegex = regexp.MustCompile(`:(\w+)`)
+
+// MuxRouter wraps a gorilla router to be used as router for api2go
+type MuxRouter struct {
+	router *mux.Router
+}
+
+// Handler returns the gorilla router
+func (m MuxRouter) Handler() http.Handler {
+	return m.router
+}
+
+// Handle registers the handler to gorilla, `:id` style params are converted
+// to `{id}` style variables. All variables are passed in the request context,
+// so they can be read with Param
+func (m MuxRouter) Handle(protocol, route string, handler http.HandlerFunc) {
+	muxRoute := routeParamRegex.ReplaceAllString(route, "{$1}")
+	m.router.HandleFunc(muxRoute, func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		if vars == nil {
+			vars = map[string]string{}
+		}
+
+		ctx := context.WithValue(r.Context(), varsKey, vars)
+		handler(w, r.WithContext(ctx))
+	}).Methods(protocol)
+}
+
+// Param returns the value of a route variable
+func (m MuxRouter) Param(ctx context.Context, name string) string {
+	vars, ok := ctx.Value(varsKey).(map[string]string)
+	if !ok {
+		return ""
+	}
+
+	return vars[name]
+}
+
+// SetParam is not supported by gorilla, route variables are read only
+func (m MuxRouter) SetParam(ctx context.Context, name, value string) {
+}
+
+// New returns a new router for api2go using the given gorilla router
+func New(router *mux.Router) routing.Routeable {
+	return &MuxRouter{router: router}
+}
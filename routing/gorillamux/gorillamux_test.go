@@ -0,0 +1,23 @@
+package gorillamux_test
+
+import (
+	"context"
+
+	"github.com/gorilla/mux"
+	"github.com/manyminds/api2go/routing"
+	"github.com/manyminds/api2go/routing/gorillamux"
+	"github.com/manyminds/api2go/routing/routingtest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MuxRouter", func() {
+	routingtest.ItServesResources(func() routing.Routeable {
+		return gorillamux.New(mux.NewRouter())
+	})
+
+	It("returns empty params without route variables", func() {
+		router := gorillamux.New(mux.NewRouter())
+		Expect(router.Param(context.Background(), "id")).To(Equal(""))
+	})
+})
@@ -14,31 +14,51 @@ type HTTPRouter struct {
 }
 
 // Handle each method like before and wrap them into julienschmidt handler func style
-func (h HTTPRouter) Handle(protocol, route string, handler http.HandlerFunc) {
+func (h *HTTPRouter) Handle(protocol, route string, handler http.HandlerFunc) {
 	h.group.Handle(protocol, route, handler)
 }
 
-// Handler returns the router
-func (h HTTPRouter) Handler() http.Handler {
-	return h.router
+// Handler returns the router. The returned handler keeps working after Reset.
+func (h *HTTPRouter) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.router.ServeHTTP(w, r)
+	})
 }
 
-func (h HTTPRouter) Param(ctx context.Context, name string) string {
+// Reset removes all registered routes, the handlers and redirect settings are kept.
+// It must not be called while requests are served.
+func (h *HTTPRouter) Reset() {
+	router := httptreemux.New()
+	router.NotFoundHandler = h.router.NotFoundHandler
+	router.MethodNotAllowedHandler = h.router.MethodNotAllowedHandler
+	router.RedirectTrailingSlash = h.router.RedirectTrailingSlash
+	router.RedirectBehavior = h.router.RedirectBehavior
+	h.router = router
+	h.group = router.UsingContext()
+}
+
+func (h *HTTPRouter) Param(ctx context.Context, name string) string {
 	params := httptreemux.ContextParams(ctx)
 	return params[name]
 }
 
-func (h HTTPRouter) SetParam(ctx context.Context, name, value string) {
+func (h *HTTPRouter) SetParam(ctx context.Context, name, value string) {
 
 }
 
 // SetRedirectTrailingSlash wraps this internal functionality of
 // the julienschmidt router.
-func (h HTTPRouter) SetRedirectTrailingSlash(enabled bool) {
+func (h *HTTPRouter) SetRedirectTrailingSlash(enabled bool) {
 	h.router.RedirectTrailingSlash = enabled
 	h.router.RedirectBehavior = httptreemux.Redirect307
 }
 
+// SetNotFoundHandler sets the handler that answers requests
+// to routes which are not registered
+func (h *HTTPRouter) SetNotFoundHandler(handler http.Handler) {
+	h.router.NotFoundHandler = handler.ServeHTTP
+}
+
 // NewHTTPRouter returns a new instance of julienschmidt/httprouter
 // this is the default router when using api2go
 func NewHTTPRouter(prefix string, notAllowedHandler http.Handler) Routeable {
@@ -25,3 +25,9 @@ type Routeable interface {
 	Param(context.Context, string) string
 	SetParam(context.Context, string, string)
 }
+
+// Resettable can be optionally implemented by a Routeable to remove all registered
+// routes, it is required by api.ClearResources.
+type Resettable interface {
+	Reset()
+}
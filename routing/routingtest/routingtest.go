@@ -0,0 +1,94 @@
+// Package routingtest contains the specs that every routing.Routeable adapter must pass.
+package routingtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/manyminds/api2go"
+	"github.com/manyminds/api2go/examples/model"
+	"github.com/manyminds/api2go/examples/resource"
+	"github.com/manyminds/api2go/examples/storage"
+	"github.com/manyminds/api2go/routing"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// request is sent to the api, the response must have `status` and contain `body`
+type request struct {
+	method   string
+	url      string
+	payload  string
+	status   int
+	body     string
+	location string
+}
+
+var createUser = request{"POST", "/api/users", `{"data": {"type": "users", "attributes": {"user-name": "marvin"}}}`, http.StatusCreated, "", "/api/users/1"}
+
+var specs = []struct {
+	name     string
+	requests []request
+}{
+	{"creates a user", []request{createUser}},
+	{"finds all users", []request{
+		createUser,
+		{"GET", "/api/users", "", http.StatusOK, `"user-name":"marvin"`, ""},
+	}},
+	{"finds one user", []request{
+		createUser,
+		{"GET", "/api/users/1", "", http.StatusOK, `"id":"1"`, ""},
+	}},
+	{"returns 404 for unknown users", []request{
+		{"GET", "/api/users/1", "", http.StatusNotFound, "", ""},
+	}},
+	{"updates a user", []request{
+		createUser,
+		{"PATCH", "/api/users/1", `{"data": {"type": "users", "id": "1", "attributes": {"user-name": "better marvin"}}}`, http.StatusNoContent, "", ""},
+		{"GET", "/api/users/1", "", http.StatusOK, `"user-name":"better marvin"`, ""},
+	}},
+	{"deletes a user", []request{
+		createUser,
+		{"DELETE", "/api/users/1", "", http.StatusNoContent, "", ""},
+		{"GET", "/api/users/1", "", http.StatusNotFound, "", ""},
+	}},
+}
+
+// ItServesResources adds specs to the current container, which serve the example resources
+// with the router returned by `newRouter`
+func ItServesResources(newRouter func() routing.Routeable) {
+	var api *api2go.API
+
+	BeforeEach(func() {
+		api = api2go.NewAPIWithRouting(
+			"api",
+			api2go.NewStaticResolver("/"),
+			api2go.DefaultContentMarshalers,
+			newRouter(),
+		)
+
+		userStorage := storage.NewUserStorage()
+		chocStorage := storage.NewChocolateStorage()
+		api.AddResource(model.User{}, resource.UserResource{ChocStorage: chocStorage, UserStorage: userStorage})
+		api.AddResource(model.Chocolate{}, resource.ChocolateResource{ChocStorage: chocStorage, UserStorage: userStorage})
+	})
+
+	for _, spec := range specs {
+		spec := spec
+		It(spec.name, func() {
+			for _, request := range spec.requests {
+				rec := httptest.NewRecorder()
+				req, err := http.NewRequest(request.method, request.url, strings.NewReader(request.payload))
+				Expect(err).ToNot(HaveOccurred())
+				api.Handler().ServeHTTP(rec, req)
+
+				Expect(rec.Code).To(Equal(request.status), request.method+" "+request.url)
+				Expect(rec.Body.String()).To(ContainSubstring(request.body))
+				if request.location != "" {
+					Expect(rec.Header().Get("Location")).To(Equal(request.location))
+				}
+			}
+		})
+	}
+}
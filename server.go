@@ -0,0 +1,50 @@
+package api2go
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// serverState keeps track of the http.Server an API is served by
+type serverState struct {
+	sync.Mutex
+	server *http.Server
+}
+
+// ListenAndServe starts an http.Server for the API on the given address.
+// The server can be stopped gracefully with Shutdown, in which case
+// http.ErrServerClosed is returned.
+func (api *API) ListenAndServe(addr string) error {
+	server := &http.Server{Addr: addr}
+	api.SetServer(server)
+	return server.ListenAndServe()
+}
+
+// SetServer registers a server that is managed by the caller, so that
+// Shutdown can stop it gracefully. If the server has no handler yet,
+// the API handler is used.
+func (api *API) SetServer(server *http.Server) {
+	if server.Handler == nil {
+		server.Handler = api.Handler()
+	}
+
+	api.state.Lock()
+	defer api.state.Unlock()
+	api.state.server = server
+}
+
+// Shutdown stops accepting new requests and waits for in-flight requests
+// to finish until the deadline of `ctx` is exceeded.
+// It is a no-op if the API is not served by a registered server.
+func (api *API) Shutdown(ctx context.Context) error {
+	api.state.Lock()
+	server := api.state.server
+	api.state.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	return server.Shutdown(ctx)
+}
@@ -0,0 +1,64 @@
+package api2go
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server lifecycle", func() {
+	var (
+		api      *API
+		listener net.Listener
+		server   *http.Server
+	)
+
+	BeforeEach(func() {
+		var err error
+		api = NewAPI("v1")
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+		}, false})
+
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		server = &http.Server{}
+		api.SetServer(server)
+	})
+
+	AfterEach(func() {
+		listener.Close()
+	})
+
+	It("uses the api handler for the registered server", func() {
+		Expect(server.Handler).ToNot(BeNil())
+	})
+
+	It("stops accepting requests after Shutdown", func() {
+		done := make(chan error)
+		go func() {
+			done <- server.Serve(listener)
+		}()
+
+		resp, err := http.Get("http://" + listener.Addr().String() + "/v1/posts/1")
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		Expect(api.Shutdown(ctx)).To(Succeed())
+		Expect(<-done).To(Equal(http.ErrServerClosed))
+
+		_, err = http.Get("http://" + listener.Addr().String() + "/v1/posts/1")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("is a no-op without a server", func() {
+		Expect(NewAPI("v1").Shutdown(context.Background())).To(Succeed())
+	})
+})
@@ -0,0 +1,73 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// StaticFilesMaxAge is the duration clients may cache files served by ServeFiles.
+// index.html is never cached, so that new versions of a frontend are picked up.
+var StaticFilesMaxAge = time.Hour
+
+// ServeFiles serves the files of `fs` below `prefix`, e.g. `/static/app.js` is looked
+// up as `/app.js`. Unknown paths are answered with `/index.html` to support client
+// side routing of single page applications. Resource routes take precedence, so
+// files can even be served at `/`.
+func (api *API) ServeFiles(prefix string, fs http.FileSystem) {
+	base := strings.TrimSuffix(prefix, "/")
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		name := resolveFile(fs, strings.TrimPrefix(r.URL.Path, base))
+		file, err := fs.Open(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+
+		if info.Name() == "index.html" {
+			w.Header().Set("Cache-Control", "no-cache")
+		} else {
+			w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(StaticFilesMaxAge.Seconds())))
+		}
+
+		http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+	}
+
+	api.handle("GET", base+"/*filepath", handler)
+	api.handle("HEAD", base+"/*filepath", handler)
+}
+
+// resolveFile returns the name of the file that is served for a request path.
+// Directories are served with their index.html, unknown paths with /index.html.
+func resolveFile(fs http.FileSystem, name string) string {
+	name = path.Clean("/" + name)
+	if isFile(fs, name) {
+		return name
+	}
+
+	if index := path.Join(name, "index.html"); isFile(fs, index) {
+		return index
+	}
+
+	return "/index.html"
+}
+
+func isFile(fs http.FileSystem, name string) bool {
+	file, err := fs.Open(name)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	return err == nil && !info.IsDir()
+}
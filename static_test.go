@@ -0,0 +1,94 @@
+package api2go
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Static files", func() {
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+		dir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "api2go-static")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.Mkdir(filepath.Join(dir, "docs"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>app</html>"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log('app')"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "docs", "index.html"), []byte("<html>docs</html>"), 0644)).To(Succeed())
+
+		api = NewAPI("v1")
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}, false})
+		rec = httptest.NewRecorder()
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	doRequest := func(URL string) {
+		req, err := http.NewRequest("GET", URL, nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	Context("below a prefix", func() {
+		BeforeEach(func() {
+			api.ServeFiles("/static/", http.Dir(dir))
+		})
+
+		It("serves files without the prefix", func() {
+			doRequest("/static/app.js")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(Equal("console.log('app')"))
+			Expect(rec.Header().Get("Cache-Control")).To(Equal("public, max-age=3600"))
+		})
+
+		It("serves the index.html of directories", func() {
+			doRequest("/static/docs/")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(Equal("<html>docs</html>"))
+			Expect(rec.Header().Get("Cache-Control")).To(Equal("no-cache"))
+		})
+
+		It("falls back to index.html for unknown paths", func() {
+			doRequest("/static/users/1/settings")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(Equal("<html>app</html>"))
+			Expect(rec.Header().Get("Cache-Control")).To(Equal("no-cache"))
+		})
+
+		It("does not serve files outside of the directory", func() {
+			doRequest("/static/../static_test.go")
+			Expect(rec.Body.String()).ToNot(ContainSubstring("package api2go"))
+		})
+	})
+
+	Context("at the root", func() {
+		BeforeEach(func() {
+			api.ServeFiles("/", http.Dir(dir))
+		})
+
+		It("serves files", func() {
+			doRequest("/app.js")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(Equal("console.log('app')"))
+		})
+
+		It("keeps the resource routes", func() {
+			doRequest("/v1/posts/1")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring("Hello, World!"))
+		})
+	})
+})
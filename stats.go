@@ -0,0 +1,126 @@
+package api2go
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// statsSmoothing is the number of requests over which the average response time is
+// smoothed, every request changes the exponential moving average by 1/statsSmoothing
+const statsSmoothing = 10
+
+// ResourceStats contains runtime statistics of all resources of an API, by the name
+// of the resource. Versioned and nested resources are prefixed with their version
+// and parent, e.g. `v2/posts` or `users/posts`.
+type ResourceStats map[string]ResourceStat
+
+// ResourceStat contains runtime statistics of a single resource
+type ResourceStat struct {
+	// Requests is the number of requests that have been answered
+	Requests int64
+	// Errors contains the number of 4xx and 5xx responses by their status code
+	Errors map[int]int64
+	// AverageResponseTime is the exponential moving average of the response times
+	AverageResponseTime time.Duration
+	// InFlight is the number of requests that are currently handled
+	InFlight int64
+}
+
+// Stats returns the runtime statistics of all registered resources since they have been
+// added. It can be called concurrently to serving requests.
+func (api *API) Stats() ResourceStats {
+	stats := ResourceStats{}
+	for _, res := range api.resources {
+		stats[res.key()] = res.counters.stat()
+	}
+
+	return stats
+}
+
+// resourceCounters are updated atomically for every request of a resource
+type resourceCounters struct {
+	requests int64
+	inFlight int64
+	// averageNanos is the exponential moving average of the response times
+	averageNanos int64
+	// errors are the counts of the status codes 400 to 599
+	errors [200]int64
+}
+
+// begin counts a request as in flight and returns a writer that records its status
+func (c *resourceCounters) begin(w http.ResponseWriter) *statsResponseWriter {
+	atomic.AddInt64(&c.inFlight, 1)
+	return &statsResponseWriter{ResponseWriter: w, start: time.Now()}
+}
+
+// end counts a request as answered with the status recorded by `w`
+func (c *resourceCounters) end(w *statsResponseWriter) {
+	duration := int64(time.Since(w.start))
+
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status >= 400 && status < 600 {
+		atomic.AddInt64(&c.errors[status-400], 1)
+	}
+
+	for {
+		average := atomic.LoadInt64(&c.averageNanos)
+		next := duration
+		if atomic.LoadInt64(&c.requests) > 0 {
+			next = average + (duration-average)/statsSmoothing
+		}
+
+		if atomic.CompareAndSwapInt64(&c.averageNanos, average, next) {
+			break
+		}
+	}
+
+	atomic.AddInt64(&c.requests, 1)
+	atomic.AddInt64(&c.inFlight, -1)
+}
+
+func (c *resourceCounters) stat() ResourceStat {
+	stat := ResourceStat{
+		Requests:            atomic.LoadInt64(&c.requests),
+		Errors:              map[int]int64{},
+		AverageResponseTime: time.Duration(atomic.LoadInt64(&c.averageNanos)),
+		InFlight:            atomic.LoadInt64(&c.inFlight),
+	}
+
+	for i := range c.errors {
+		if count := atomic.LoadInt64(&c.errors[i]); count > 0 {
+			stat.Errors[400+i] = count
+		}
+	}
+
+	return stat
+}
+
+// statsResponseWriter records the status of a response for the resource statistics
+type statsResponseWriter struct {
+	http.ResponseWriter
+	status int
+	start  time.Time
+}
+
+func (w *statsResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statsResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap returns the original ResponseWriter
+func (w *statsResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,95 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stats", func() {
+	var api *API
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+		}, false})
+		api.AddVersionedResource("v2", Post{}, &fixtureSource{map[string]*Post{}, false})
+	})
+
+	doRequest := func(method, URL, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req, err := http.NewRequest(method, URL, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		if body != "" {
+			req.Header.Set("Content-Type", "text/plain")
+		}
+		api.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("is empty before any request", func() {
+		Expect(api.Stats()).To(Equal(ResourceStats{
+			"posts":    {Errors: map[int]int64{}},
+			"v2/posts": {Errors: map[int]int64{}},
+		}))
+	})
+
+	It("counts the requests and errors of every resource", func() {
+		Expect(doRequest("GET", "/v1/posts/1", "").Code).To(Equal(http.StatusOK))
+		Expect(doRequest("GET", "/v1/posts", "").Code).To(Equal(http.StatusOK))
+		Expect(doRequest("GET", "/v1/posts/2", "").Code).To(Equal(http.StatusNotFound))
+		Expect(doRequest("POST", "/v1/posts", "title").Code).To(Equal(http.StatusUnsupportedMediaType))
+		Expect(doRequest("GET", "/v1/v2/posts/1", "").Code).To(Equal(http.StatusNotFound))
+
+		stats := api.Stats()
+		Expect(stats["posts"].Requests).To(Equal(int64(4)))
+		Expect(stats["posts"].Errors).To(Equal(map[int]int64{
+			http.StatusNotFound:             1,
+			http.StatusUnsupportedMediaType: 1,
+		}))
+		Expect(stats["posts"].AverageResponseTime).To(BeNumerically(">", 0))
+		Expect(stats["posts"].InFlight).To(BeZero())
+		Expect(stats["v2/posts"].Requests).To(Equal(int64(1)))
+		Expect(stats["v2/posts"].Errors).To(Equal(map[int]int64{http.StatusNotFound: 1}))
+	})
+
+	It("counts requests that are in flight", func() {
+		counters := api.resources[0].counters
+		w := counters.begin(httptest.NewRecorder())
+		Expect(api.Stats()["posts"].InFlight).To(Equal(int64(1)))
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+		counters.end(w)
+		Expect(api.Stats()["posts"].InFlight).To(BeZero())
+		Expect(api.Stats()["posts"].Errors).To(Equal(map[int]int64{http.StatusServiceUnavailable: 1}))
+	})
+
+	It("smoothes the average response time", func() {
+		counters := &resourceCounters{}
+		observe := func(duration time.Duration) {
+			w := counters.begin(httptest.NewRecorder())
+			w.start = time.Now().Add(-duration)
+			counters.end(w)
+		}
+
+		observe(100 * time.Millisecond)
+		Expect(counters.stat().AverageResponseTime).To(BeNumerically("~", 100*time.Millisecond, time.Millisecond))
+
+		observe(0)
+		Expect(counters.stat().AverageResponseTime).To(BeNumerically("~", 90*time.Millisecond, time.Millisecond))
+	})
+
+	It("keeps passing errors to the request logger", func() {
+		logger := &recordingRequestLogger{}
+		api.SetRequestLogger(logger)
+		doRequest("GET", "/v1/posts/2", "")
+		Expect(logger.requests).To(HaveLen(1))
+		Expect(logger.requests[0].status).To(Equal(http.StatusNotFound))
+		Expect(logger.requests[0].err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,61 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTimeRangeFilter parses the `filter[<field>][from]` and `filter[<field>][to]` query
+// parameters of a request as RFC 3339 timestamps. Both bounds are optional and nil if
+// they are not given. If a timestamp is invalid or `from` is after `to`, a 400 HTTPError
+// is returned, whose error object names the offending query parameter as source.
+func ParseTimeRangeFilter(req Request, field string) (from *time.Time, to *time.Time, err error) {
+	query := req.query()
+
+	fromKey := fmt.Sprintf("filter[%s][%s]", field, From)
+	if from, err = parseFilterTime(fromKey, query.Get(fromKey)); err != nil {
+		return nil, nil, err
+	}
+
+	toKey := fmt.Sprintf("filter[%s][%s]", field, To)
+	if to, err = parseFilterTime(toKey, query.Get(toKey)); err != nil {
+		return nil, nil, err
+	}
+
+	if from != nil && to != nil && from.After(*to) {
+		return nil, nil, newFilterParameterError(toKey, fmt.Sprintf("%s must not be before %s", toKey, fromKey))
+	}
+
+	return from, to, nil
+}
+
+// parseFilterTime parses an RFC 3339 timestamp, it returns nil for an empty value
+func parseFilterTime(key, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	// an unescaped + of the time zone offset is decoded as space in query strings
+	t, err := time.Parse(time.RFC3339, strings.Replace(value, " ", "+", 1))
+	if err != nil {
+		return nil, newFilterParameterError(key, fmt.Sprintf("%s must be an RFC 3339 timestamp", key))
+	}
+
+	return &t, nil
+}
+
+// newFilterParameterError returns a 400 error that points to the query parameter `key`
+func newFilterParameterError(key, detail string) HTTPError {
+	httpError := NewHTTPError(nil, detail, http.StatusBadRequest)
+	httpError.Errors = []Error{{
+		Status: strconv.Itoa(http.StatusBadRequest),
+		Title:  "Invalid filter",
+		Detail: detail,
+		Source: &ErrorSource{Parameter: key},
+	}}
+
+	return httpError
+}
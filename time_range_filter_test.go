@@ -0,0 +1,119 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// timeRangeSource filters posts by the time range of `created`
+type timeRangeSource struct {
+	*fixtureSource
+	from, to *time.Time
+}
+
+func (s *timeRangeSource) FindAll(req Request) (Responder, error) {
+	from, to, err := ParseTimeRangeFilter(req, "created")
+	if err != nil {
+		return nil, err
+	}
+
+	s.from, s.to = from, to
+	return s.fixtureSource.FindAll(req)
+}
+
+var _ = Describe("Time range filters", func() {
+	parse := func(URL string) (*time.Time, *time.Time, error) {
+		plain, err := http.NewRequest("GET", URL, nil)
+		Expect(err).ToNot(HaveOccurred())
+		return ParseTimeRangeFilter(BuildRequest(plain.Context(), plain), "created_at")
+	}
+
+	It("returns nil without time range", func() {
+		from, to, err := parse("/v1/posts?filter[title]=Hello")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(from).To(BeNil())
+		Expect(to).To(BeNil())
+	})
+
+	It("parses both bounds", func() {
+		from, to, err := parse("/v1/posts?filter[created_at][from]=2016-01-01T00:00:00Z&filter[created_at][to]=2016-02-01T12:30:00%2B02:00")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*from).To(BeTemporally("==", time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)))
+		Expect(*to).To(BeTemporally("==", time.Date(2016, 2, 1, 10, 30, 0, 0, time.UTC)))
+	})
+
+	It("accepts an unescaped + in the time zone offset", func() {
+		from, to, err := parse("/v1/posts?filter[created_at][from]=2016-01-01T02:00:00+02:00")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*from).To(BeTemporally("==", time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)))
+		Expect(to).To(BeNil())
+	})
+
+	It("uses the query params without plain request", func() {
+		req := Request{QueryParams: map[string][]string{"filter[created_at][to]": {"2016-01-01T00:00:00Z"}}}
+		from, to, err := ParseTimeRangeFilter(req, "created_at")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(from).To(BeNil())
+		Expect(*to).To(BeTemporally("==", time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)))
+	})
+
+	It("points to invalid timestamps", func() {
+		_, _, err := parse("/v1/posts?filter[created_at][from]=yesterday")
+		Expect(err).To(HaveOccurred())
+		httpErr, ok := err.(HTTPError)
+		Expect(ok).To(BeTrue())
+		Expect(httpErr.status).To(Equal(http.StatusBadRequest))
+		Expect(httpErr.Errors[0].Source).To(Equal(&ErrorSource{Parameter: "filter[created_at][from]"}))
+	})
+
+	It("rejects ranges that end before they start", func() {
+		_, _, err := parse("/v1/posts?filter[created_at][from]=2016-02-01T00:00:00Z&filter[created_at][to]=2016-01-01T00:00:00Z")
+		Expect(err).To(HaveOccurred())
+		Expect(err.(HTTPError).Errors[0].Source).To(Equal(&ErrorSource{Parameter: "filter[created_at][to]"}))
+	})
+
+	It("is accepted by ParseFilters", func() {
+		plain, err := http.NewRequest("GET", "/v1/posts?filter[created_at][from]=2016-01-01T00:00:00Z", nil)
+		Expect(err).ToNot(HaveOccurred())
+		filters, err := ParseFilters(plain.URL.Query())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filters).To(Equal([]Filter{{Field: "created_at", Operator: From, Values: []string{"2016-01-01T00:00:00Z"}}}))
+	})
+
+	Context("in a source", func() {
+		var (
+			api    *API
+			source *timeRangeSource
+			rec    *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			source = &timeRangeSource{fixtureSource: &fixtureSource{map[string]*Post{
+				"1": {ID: "1", Title: "Hello, World!"},
+			}, false}}
+			api = NewAPI("v1")
+			api.AddResource(Post{}, source)
+			rec = httptest.NewRecorder()
+		})
+
+		It("passes the parsed range", func() {
+			req, err := http.NewRequest("GET", "/v1/posts?filter[created][from]=2016-01-01T00:00:00Z", nil)
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(*source.from).To(BeTemporally("==", time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)))
+		})
+
+		It("answers invalid timestamps with 400", func() {
+			req, err := http.NewRequest("GET", "/v1/posts?filter[created][to]=tomorrow", nil)
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"400","title":"Invalid filter","detail":"filter[created][to] must be an RFC 3339 timestamp","source":{"parameter":"filter[created][to]"}}]}`))
+		})
+	})
+})
@@ -0,0 +1,50 @@
+package api2go
+
+import (
+	"reflect"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+// TypeRegistry maps jsonapi type names to the go types that are instantiated
+// for them. It allows a resource to accept subtypes on creation: if the `type`
+// of a POSTed object is registered, an instance of the registered type is passed
+// to Create instead of the type the resource was added with.
+type TypeRegistry struct {
+	types map[string]reflect.Type
+}
+
+// NewTypeRegistry returns an empty TypeRegistry
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{types: map[string]reflect.Type{}}
+}
+
+// Register adds the type of `prototype` under its jsonapi type name.
+// Just like with AddResource, `prototype` should be either an empty struct
+// instance or a pointer to a struct. The same kind will be instantiated.
+func (t *TypeRegistry) Register(prototype jsonapi.MarshalIdentifier) {
+	prototypeType := reflect.TypeOf(prototype)
+	if prototypeType.Kind() != reflect.Struct && prototypeType.Kind() != reflect.Ptr {
+		panic("pass an empty resource struct or a struct pointer to Register!")
+	}
+
+	t.types[resourceName(prototype)] = prototypeType
+}
+
+// Lookup returns the type that has been registered for the jsonapi type `name`
+func (t *TypeRegistry) Lookup(name string) (reflect.Type, bool) {
+	registered, ok := t.types[name]
+	return registered, ok
+}
+
+// requestedType returns the `type` of the primary data of a request document,
+// or an empty string if there is none
+func requestedType(ctx map[string]interface{}) string {
+	data, ok := ctx["data"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	name, _ := data["type"].(string)
+	return name
+}
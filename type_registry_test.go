@@ -0,0 +1,140 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Pet struct {
+	ID   string `jsonapi:"-"`
+	Name string
+}
+
+func (p Pet) GetID() string {
+	return p.ID
+}
+
+func (p *Pet) SetID(ID string) error {
+	p.ID = ID
+	return nil
+}
+
+type Dog struct {
+	ID    string `jsonapi:"-"`
+	Name  string
+	Barks bool
+}
+
+func (d Dog) GetID() string {
+	return d.ID
+}
+
+func (d *Dog) SetID(ID string) error {
+	d.ID = ID
+	return nil
+}
+
+type petSource struct {
+	created interface{}
+}
+
+func (s *petSource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: Pet{ID: ID}}, nil
+}
+
+func (s *petSource) Create(obj interface{}, req Request) (Responder, error) {
+	s.created = obj
+	switch pet := obj.(type) {
+	case Pet:
+		pet.ID = "1"
+		return &Response{Res: pet, Code: http.StatusCreated}, nil
+	case *Dog:
+		pet.ID = "2"
+		return &Response{Res: pet, Code: http.StatusCreated}, nil
+	}
+
+	return &Response{}, NewHTTPError(nil, "unexpected type", http.StatusBadRequest)
+}
+
+func (s *petSource) Delete(ID string, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *petSource) Update(obj interface{}, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+var _ = Describe("TypeRegistry", func() {
+	var (
+		api    *API
+		source *petSource
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		source = &petSource{}
+		api.AddResource(Pet{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	doCreate := func(body string) {
+		req, err := http.NewRequest("POST", "/v1/pets", strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("looks up registered types by their jsonapi name", func() {
+		registry := NewTypeRegistry()
+		registry.Register(&Dog{})
+		dogType, ok := registry.Lookup("dogs")
+		Expect(ok).To(BeTrue())
+		Expect(dogType.String()).To(Equal("*api2go.Dog"))
+		_, ok = registry.Lookup("cats")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("creates the resource type without a registry", func() {
+		doCreate(`{"data": {"type": "pets", "attributes": {"name": "Bello"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(source.created).To(Equal(Pet{Name: "Bello"}))
+	})
+
+	It("rejects subtypes without a registry", func() {
+		doCreate(`{"data": {"type": "dogs", "attributes": {"name": "Bello", "barks": true}}}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(source.created).To(BeNil())
+	})
+
+	Context("with a registry", func() {
+		BeforeEach(func() {
+			registry := NewTypeRegistry()
+			registry.Register(&Dog{})
+			api.SetTypeRegistry("pets", registry)
+		})
+
+		It("creates the registered type", func() {
+			doCreate(`{"data": {"type": "dogs", "attributes": {"name": "Bello", "barks": true}}}`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(source.created).To(Equal(&Dog{ID: "2", Name: "Bello", Barks: true}))
+			Expect(rec.Header().Get("Location")).To(Equal("/v1/pets/2"))
+			Expect(rec.Body.String()).To(MatchJSON(`{"data": {"type": "dogs", "id": "2", "attributes": {"name": "Bello", "barks": true}}, "links": {"self": "/v1/pets"}}`))
+		})
+
+		It("still creates the resource type", func() {
+			doCreate(`{"data": {"type": "pets", "attributes": {"name": "Bello"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(source.created).To(Equal(Pet{Name: "Bello"}))
+		})
+	})
+
+	It("panics for unknown resources", func() {
+		Expect(func() {
+			api.SetTypeRegistry("unicorns", NewTypeRegistry())
+		}).To(Panic())
+	})
+})
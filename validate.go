@@ -0,0 +1,40 @@
+package api2go
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validate checks the registered resources for common configuration mistakes and
+// should be called after all resources have been added, before serving requests.
+// It reports:
+//   - relationships with a type that is not registered as resource
+//   - relationships named like a registered resource of another type
+//   - sources that implement PaginatedFindAll, but not FindAll for requests without pagination
+func (api *API) Validate() error {
+	problems := []string{}
+
+	for _, res := range api.resources {
+		for _, reference := range res.references {
+			if api.resource(reference.Type) == nil {
+				problems = append(problems, fmt.Sprintf("relationship %s of resource %s has the unknown type %s", reference.Name, res.name, reference.Type))
+			}
+
+			if reference.Name != reference.Type && api.resource(reference.Name) != nil {
+				problems = append(problems, fmt.Sprintf("relationship %s of resource %s has the type %s, but is named like the resource %s", reference.Name, res.name, reference.Type, reference.Name))
+			}
+		}
+
+		_, paginated := res.source.(PaginatedFindAll)
+		_, findAll := res.source.(FindAll)
+		if paginated && !findAll {
+			problems = append(problems, fmt.Sprintf("source of resource %s implements PaginatedFindAll, but not FindAll", res.name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid api configuration: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package api2go
+
+import (
+	"net/http"
+
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// paginatedOnlySource implements PaginatedFindAll, but not FindAll
+type paginatedOnlySource struct{}
+
+func (s paginatedOnlySource) PaginatedFindAll(req Request) (int64, Responder, error) {
+	return 0, &Response{Res: []Agent{}}, nil
+}
+
+func (s paginatedOnlySource) FindOne(id string, req Request) (Responder, error) {
+	return &Response{Res: Agent{ID: id}}, nil
+}
+
+func (s paginatedOnlySource) Create(obj interface{}, req Request) (Responder, error) {
+	return &Response{Code: http.StatusCreated, Res: obj}, nil
+}
+
+func (s paginatedOnlySource) Delete(id string, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s paginatedOnlySource) Update(obj interface{}, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+var _ = Describe("Validate", func() {
+	var api *API
+
+	source := func() *plainIncludeSource {
+		return &plainIncludeSource{objs: []jsonapi.MarshalIdentifier{}}
+	}
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+	})
+
+	It("accepts a valid configuration", func() {
+		api.AddResource(Book{}, source())
+		api.AddResource(Writer{}, source())
+		api.AddResource(Agent{}, source())
+		api.AddResource(Review{}, source())
+		Expect(api.Validate()).To(Succeed())
+	})
+
+	It("reports relationships with unknown types", func() {
+		api.AddResource(Book{}, source())
+		api.AddResource(Writer{}, source())
+		api.AddResource(Agent{}, source())
+		Expect(api.Validate()).To(MatchError("invalid api configuration: relationship reviews of resource books has the unknown type reviews"))
+	})
+
+	It("reports relationships named like a resource of another type", func() {
+		api.AddResource(Writer{}, source())
+		api.AddResource(Agent{}, source())
+		api.AddResource(Review{}, source(), WithName("author"))
+		api.AddResource(Book{}, source())
+		err := api.Validate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("relationship author of resource books has the type writers, but is named like the resource author"))
+		Expect(err.Error()).To(ContainSubstring("relationship reviews of resource books has the unknown type reviews"))
+	})
+
+	It("reports sources that only implement PaginatedFindAll", func() {
+		api.AddResource(Agent{}, paginatedOnlySource{})
+		Expect(api.Validate()).To(MatchError("invalid api configuration: source of resource agents implements PaginatedFindAll, but not FindAll"))
+	})
+})